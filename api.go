@@ -1,20 +1,77 @@
 package telegramapi
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
+	"net"
+	"reflect"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/andreyvit/telegramapi/mtproto"
 	"github.com/andreyvit/telegramapi/tl"
 )
 
+// seedDC is the DC ID requested from proxies before the seed DC reports its ID
+const seedDC = 2
+
+// ErrNotConnected is returned by requests sent before Run or after it returns.
+var ErrNotConnected = errors.New("not connected")
+
 type Options struct {
-	SeedAddr  Addr
-	PublicKey string
-	Verbose   int
+	SeedAddr        Addr
+	ProtocolVersion mtproto.ProtocolVersion
+	TCPFraming      mtproto.TCPFraming
+	Obfuscated      bool
+	Verbose         int
+
+	// PublicKeys are PEM-encoded server keys (PKCS#1 or PKCS#8), the built-in
+	// production and test keys are used when empty
+	PublicKeys string
+
+	// TempKeyTTL enables perfect forward secrecy using temporary auth keys
+	// of the given lifetime (Telegram clients typically use 24 hours)
+	TempKeyTTL time.Duration
+
+	// PingInterval enables keepalive pings; Run fails with
+	// mtproto.ErrPingTimeout when the server stops answering for PingTimeout
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	// FirstByteTimeout makes Run fail with mtproto.ErrReadTimeout when
+	// nothing arrives over TCP for this long, so it should exceed
+	// PingInterval; MsgTimeout limits receiving a single message
+	FirstByteTimeout time.Duration
+	MsgTimeout       time.Duration
+
+	// GzipThreshold compresses requests larger than this many bytes
+	GzipThreshold int
+
+	// DialTransport overrides how DC connections are established, e.g. to
+	// run against mtprototest.Server
+	DialTransport func(dc *DCState) (mtproto.Transport, error)
+
+	// Dialer establishes all DC connections, e.g. through mtproto.SOCKS5Dialer
+	Dialer mtproto.Dialer
+
+	// UseHTTP switches to the HTTP transport, which honors HTTP_PROXY
+	UseHTTP bool
+
+	// WebSocketURL switches to the WebSocket transport, e.g. wss://venus.web.telegram.org/apiws
+	WebSocketURL       string
+	WebSocketOrigin    string
+	WebSocketTLSConfig *tls.Config
+
+	// MTProxy is the host:port of an MTProto proxy to connect through,
+	// MTProxySecret is its hex secret
+	MTProxy       string
+	MTProxySecret string
 
 	APIID   int
 	APIHash string
@@ -31,37 +88,241 @@ type Conn struct {
 	stateMut sync.Mutex
 
 	session *mtproto.Session
+	sessMut sync.Mutex
+	// closed when the session gets replaced on reconnect or Run returns
+	sessionc chan struct{}
+
+	// sessions to the other DCs
+	pool *dcPool
+
+	updates *updatesEngine
 }
 
 type Delegate interface {
 	HandleConnectionReady()
 	HandleStateChanged(newState *State)
+	HandleUpdate(update *Update)
 }
 
 func New(options Options, state *State, delegate Delegate) *Conn {
 	if options.SeedAddr.IP == "" {
 		panic("configuration error: missing SeedAddr")
 	}
-	if options.PublicKey == "" {
-		panic("configuration error: missing public key")
-	}
 
-	return &Conn{
+	c := &Conn{
 		Options:  options,
 		delegate: delegate,
 		state:    state,
 		session:  nil,
+		sessionc: make(chan struct{}),
 
 		delegateQueue: make(chan func(), 1),
 	}
+	c.pool = newDCPool(c)
+	c.updates = newUpdatesEngine(c)
+	return c
+}
+
+func (c *Conn) dial(dc *DCState) (mtproto.Transport, error) {
+	if c.DialTransport != nil {
+		return c.DialTransport(dc)
+	}
+
+	if c.UseHTTP {
+		url := "http://" + net.JoinHostPort(dc.PrimaryAddr.IP, "80") + "/api"
+		return mtproto.NewHTTPTransport(url, mtproto.HTTPTransportOptions{Dialer: c.Dialer}), nil
+	}
+
+	options := mtproto.TCPTransportOptions{
+		Framing:          c.TCPFraming,
+		Obfuscated:       c.Obfuscated,
+		Dialer:           c.Dialer,
+		FirstByteTimeout: c.FirstByteTimeout,
+		MsgTimeout:       c.MsgTimeout,
+	}
+
+	if c.WebSocketURL != "" {
+		return mtproto.DialWebSocket(c.WebSocketURL, c.proxyDC(dc), mtproto.WebSocketOptions{
+			Origin:    c.WebSocketOrigin,
+			TLSConfig: c.WebSocketTLSConfig,
+			Transport: options,
+		})
+	}
+
+	if c.MTProxy != "" {
+		secret, err := mtproto.ParseProxySecret(c.MTProxySecret)
+		if err != nil {
+			return nil, err
+		}
+		dcID := c.proxyDC(dc)
+		if c.Verbose >= 2 {
+			log.Printf("Will connect to DC %v via MTProxy at %v", dcID, c.MTProxy)
+		}
+		return mtproto.DialMTProxy(c.MTProxy, dcID, secret, options)
+	}
+
+	return mtproto.DialTCP(dc.PrimaryAddr.Endpoint(), options)
+}
+
+// proxyDC returns the DC ID to announce to proxies and WebSocket endpoints.
+func (c *Conn) proxyDC(dc *DCState) int {
+	if dc.ID == 0 {
+		return seedDC
+	}
+	return dc.ID
 }
 
 func (c *Conn) Send(o tl.Object) (tl.Object, error) {
-	return c.session.Send(o)
+	return c.SendContext(context.Background(), o)
+}
+
+// SendContext is Send that gives up when ctx is done, e.g. at a deadline.
+// Requests answered with FILE_MIGRATE_X are resent to DC X, see SendToDC.
+//
+// Requests left unanswered when the connection has to be reestablished are
+// replayed on the new session. Requests the server has acknowledged may have
+// been executed already, so only those that are safe to repeat are replayed:
+// reads like messages.getHistory, and requests carrying a random_id, which
+// the server deduplicates. If the server reports RANDOM_ID_DUPLICATE for a
+// replayed request, the first attempt has succeeded: SendContext returns an
+// empty updates object, and the results arrive through updates.getDifference.
+// Other acknowledged requests fail with mtproto.ErrAnswerLost.
+func (c *Conn) SendContext(ctx context.Context, o tl.Object) (tl.Object, error) {
+	r, err := c.sendMain(ctx, o)
+	if err == nil {
+		if id := migrateDC(r); id != 0 {
+			return c.SendToDCContext(ctx, id, o)
+		}
+	}
+	return r, err
+}
+
+// sendMain sends a request over the connection established by Run,
+// replaying it after reconnects as described in SendContext.
+func (c *Conn) sendMain(ctx context.Context, o tl.Object) (tl.Object, error) {
+	replayed := false
+	for {
+		sess, replaced := c.currentSession()
+		if sess == nil {
+			return nil, ErrNotConnected
+		}
+		r, err := sess.SendContext(ctx, o)
+		if err != nil && canReplay(sess, err, o) {
+			// wait for Run to reconnect
+			select {
+			case <-replaced:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if next, _ := c.currentSession(); next == nil {
+				// Run has returned
+				if sessErr := sess.Err(); sessErr != nil {
+					return nil, sessErr
+				}
+				return nil, err
+			}
+			if c.Verbose >= 1 {
+				log.Printf("Replaying %s after reconnect", tl.Name(o))
+			}
+			replayed = true
+			continue
+		}
+
+		if replayed && isRandomIDDuplicate(r) {
+			// the first attempt went through
+			c.updates.requestSync(false)
+			return &mtproto.TLUpdates{}, nil
+		}
+		if u, ok := r.(mtproto.TLUpdatesType); ok {
+			c.updates.enqueue(u)
+		}
+		return r, err
+	}
+}
+
+// canReplay reports whether a request that failed with err can be sent
+// again on the next session, see SendContext.
+func canReplay(sess *mtproto.Session, err error, o tl.Object) bool {
+	if err == mtproto.ErrAnswerLost {
+		return isIdempotent(o) || hasRandomID(o)
+	}
+	return isStoppedSessionErr(sess, err)
+}
+
+// isIdempotent reports whether a request only reads data, going by its name:
+// messages.getHistory, contacts.search, contacts.resolveUsername and the like.
+func isIdempotent(o tl.Object) bool {
+	name := tl.Name(o)
+	// TL, then the namespace, then the method
+	if len(name) < 3 || !strings.HasPrefix(name, "TL") {
+		return false
+	}
+	i := strings.IndexFunc(name[3:], unicode.IsUpper)
+	if i < 0 {
+		return false
+	}
+	method := name[3+i:]
+	if method == "GetBotCallbackAnswer" {
+		// presses a button
+		return false
+	}
+	for _, prefix := range []string{"Get", "Search", "Check", "Resolve"} {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRandomID reports whether a request carries a random_id, like
+// messages.sendMessage, which the server uses to recognize repeated requests.
+func hasRandomID(o tl.Object) bool {
+	v := reflect.ValueOf(o)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Struct && v.FieldByName("RandomID").IsValid()
+}
+
+func isRandomIDDuplicate(r tl.Object) bool {
+	e, ok := r.(*mtproto.TLRPCError)
+	return ok && e.ErrorCode == 400 && e.ErrorMessage == "RANDOM_ID_DUPLICATE"
+}
+
+// isStoppedSessionErr reports whether a request failed only because the
+// session stopped before it could be answered.
+func isStoppedSessionErr(sess *mtproto.Session, err error) bool {
+	select {
+	case <-sess.Done():
+		return err == sess.Err() || err == mtproto.ErrSessionClosed
+	default:
+		return false
+	}
+}
+
+func (c *Conn) currentSession() (*mtproto.Session, <-chan struct{}) {
+	c.sessMut.Lock()
+	defer c.sessMut.Unlock()
+	return c.session, c.sessionc
+}
+
+// setSession installs the session of a new connection and wakes up the
+// requests waiting to be replayed. Run resets it to nil when it returns, so
+// that they give up with ErrNotConnected.
+func (c *Conn) setSession(sess *mtproto.Session) {
+	c.sessMut.Lock()
+	c.session = sess
+	old := c.sessionc
+	c.sessionc = make(chan struct{})
+	c.sessMut.Unlock()
+
+	close(old)
 }
 
 func (c *Conn) Shutdown() {
-	c.session.Shutdown()
+	if sess, _ := c.currentSession(); sess != nil {
+		sess.Shutdown()
+	}
 }
 
 func (c *Conn) dispatchDelegateCalls() {
@@ -71,28 +332,31 @@ func (c *Conn) dispatchDelegateCalls() {
 	c.delegateDone.Done()
 }
 
-func (c *Conn) runProcessing() {
-	c.session.WaitReady()
+func (c *Conn) runProcessing(sess *mtproto.Session) {
+	sess.WaitReady()
 
-	err := c.runProcessingErr()
+	err := c.runProcessingErr(sess)
 	if err == nil {
+		if c.LoginState() == LoggedIn {
+			c.updates.requestSync(false)
+		}
 		c.delegateQueue <- func() {
 			c.delegate.HandleConnectionReady()
 		}
 	} else {
-		c.session.Fail(err)
+		sess.Fail(err)
 	}
 }
 
-func (c *Conn) runProcessingErr() error {
-	r, err := c.session.Send(&mtproto.TLHelpGetConfig{})
+func (c *Conn) runProcessingErr(sess *mtproto.Session) error {
+	r, err := sess.Send(&mtproto.TLHelpGetConfig{})
 	if err != nil {
 		return err
 	}
 
 	switch r := r.(type) {
 	case *mtproto.TLConfig:
-		c.session.SetDC(r.ThisDC)
+		sess.SetDC(r.ThisDC)
 		c.updateState(func(state *State) {
 			updateDCs(state.DCs, r)
 		})
@@ -104,7 +368,9 @@ func (c *Conn) runProcessingErr() error {
 }
 
 func (c *Conn) Fail(err error) {
-	c.session.Fail(err)
+	if sess, _ := c.currentSession(); sess != nil {
+		sess.Fail(err)
+	}
 }
 
 func (c *Conn) updateState(f func(state *State)) {
@@ -146,10 +412,12 @@ func (c *Conn) HandleUnknownReply(r tl.Object) error {
 	}
 }
 
-func (c *Conn) saveSessionState() {
-	auth, fs := c.session.AuthState()
+// saveAuthState persists the auth key and framer state of a session
+// into the DCState of its DC.
+func (c *Conn) saveAuthState(sess *mtproto.Session) {
+	auth, fs := sess.AuthState()
 	c.updateState(func(state *State) {
-		id := c.session.DC()
+		id := sess.DC()
 		dc := state.DCs[id]
 		if dc != nil {
 			if auth.KeyID != 0 || dc.Auth.KeyID == 0 {
@@ -157,11 +425,19 @@ func (c *Conn) saveSessionState() {
 				dc.FramerState = fs
 			}
 		}
-		// log.Printf("saveSessionState (dc %d): %v", id, pretty.Sprint(c.state))
+		// log.Printf("saveAuthState (dc %d): %v", id, pretty.Sprint(c.state))
 	})
 }
 
 func (c *Conn) Run() error {
+	c.state.initialize()
+	c.updates.start()
+
+	// a single dispatcher for the whole lifetime keeps the delegate calls,
+	// and in particular the updates, in order across reconnects
+	c.delegateDone.Add(1)
+	go c.dispatchDelegateCalls()
+
 	for {
 		err := c.runInternal()
 		if err != mtproto.ErrReconnectRequired {
@@ -172,6 +448,9 @@ func (c *Conn) Run() error {
 }
 
 func (c *Conn) finalize() {
+	c.setSession(nil)
+	c.pool.close()
+	c.updates.stop()
 	close(c.delegateQueue)
 	c.delegateDone.Wait()
 }
@@ -200,37 +479,60 @@ func (c *Conn) runInternal() error {
 		}
 	}
 
-	pubKey, err := mtproto.ParsePublicKey(c.PublicKey)
+	pubKeys, err := c.keyRing()
 	if err != nil {
 		return err
 	}
 
-	tr, err := mtproto.DialTCP(dc.PrimaryAddr.Endpoint(), mtproto.TCPTransportOptions{})
+	if dc.ID != 0 {
+		// the pool must not keep a second session to the same DC
+		c.pool.drop(dc.ID)
+	}
+
+	tr, err := c.dial(dc)
 	if err != nil {
 		return err
 	}
 
-	c.session = mtproto.NewSession(tr, mtproto.SessionOptions{
-		PubKey:  pubKey,
-		Verbose: c.Verbose,
-	})
+	sess := c.newSession(tr, pubKeys)
+	c.setSession(sess)
 	if dc.ID != 0 {
-		c.session.SetDC(dc.ID)
+		sess.SetDC(dc.ID)
 	}
 
 	if dc.Auth.KeyID != 0 {
-		c.session.RestoreAuthState(&dc.Auth, dc.FramerState)
+		sess.RestoreAuthState(&dc.Auth, dc.FramerState)
 	} else {
 		c.state.LoginState = LoggedOut
 	}
 
-	c.session.OnStateChanged(c.saveSessionState)
+	sess.OnStateChanged(func() {
+		c.saveAuthState(sess)
+	})
+	sess.OnUpdates(c.updates.enqueue)
 
-	c.delegateDone.Add(1)
-	go c.dispatchDelegateCalls()
-	go c.runProcessing()
+	go c.runProcessing(sess)
+
+	sess.Run()
+	c.saveAuthState(sess)
+	return sess.Err()
+}
 
-	c.session.Run()
-	c.saveSessionState()
-	return c.session.Err()
+func (c *Conn) keyRing() (mtproto.KeyRing, error) {
+	if c.PublicKeys != "" {
+		return mtproto.ParseKeyRing(c.PublicKeys)
+	}
+	return mtproto.DefaultKeyRing(), nil
+}
+
+func (c *Conn) newSession(tr mtproto.Transport, pubKeys mtproto.KeyRing) *mtproto.Session {
+	return mtproto.NewSession(tr, mtproto.SessionOptions{
+		PubKeys:         pubKeys,
+		ProtocolVersion: c.ProtocolVersion,
+		Verbose:         c.Verbose,
+		TempKeyTTL:      c.TempKeyTTL,
+		PingInterval:    c.PingInterval,
+		PingTimeout:     c.PingTimeout,
+		GzipThreshold:   c.GzipThreshold,
+	})
 }
@@ -0,0 +1,516 @@
+package telegramapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/mtproto/mtprototest"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func TestDialReadTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		// accept and stay silent
+		c, err := l.Accept()
+		if err == nil {
+			defer c.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	addr := Addr{host, portNum}
+
+	c := New(Options{SeedAddr: addr, FirstByteTimeout: 100 * time.Millisecond}, &State{}, new(recordingDelegate))
+	tr, err := c.dial(&DCState{PrimaryAddr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	start := time.Now()
+	_, _, err = tr.Recv()
+	if err != mtproto.ErrReadTimeout {
+		t.Errorf("Recv failed with %v, expected %v", err, mtproto.ErrReadTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Recv took %v", d)
+	}
+}
+
+type connDelegate struct {
+	readyc chan struct{}
+}
+
+func (d *connDelegate) HandleConnectionReady() {
+	d.readyc <- struct{}{}
+}
+func (d *connDelegate) HandleStateChanged(newState *State) {}
+func (d *connDelegate) HandleUpdate(update *Update)        {}
+
+func (d *connDelegate) waitReady(t *testing.T) {
+	select {
+	case <-d.readyc:
+	case <-time.After(10 * time.Second):
+		t.Fatal("connection not ready")
+	}
+}
+
+// testDCs runs a fake server for each DC; the configs they return list all of them.
+func testDCs(ids ...int) map[int]*mtprototest.Server {
+	var options []*mtproto.TLDCOption
+	for _, id := range ids {
+		options = append(options, &mtproto.TLDCOption{ID: id, IPAddress: "127.0.0." + strconv.Itoa(id), Port: 443})
+	}
+
+	servers := make(map[int]*mtprototest.Server)
+	for _, id := range ids {
+		id := id
+		srv := mtprototest.NewServer()
+		srv.Handle(mtproto.TagHelpGetConfig, func(req tl.Object) tl.Object {
+			return &mtproto.TLConfig{ThisDC: id, DCOptions: options}
+		})
+		servers[id] = srv
+	}
+	return servers
+}
+
+// newTestConn returns a Conn whose seed DC is seedDC. beforeDial, if set,
+// is called before connecting to each DC.
+func newTestConn(servers map[int]*mtprototest.Server, seedDC int, beforeDial func(id int)) (*Conn, *connDelegate) {
+	d := &connDelegate{readyc: make(chan struct{}, 10)}
+	c := New(Options{
+		SeedAddr:   Addr{"127.0.0.1", 443},
+		PublicKeys: servers[seedDC].PublicKey(),
+		DialTransport: func(dc *DCState) (mtproto.Transport, error) {
+			id := dc.ID
+			if id == 0 {
+				id = seedDC
+			}
+			if beforeDial != nil {
+				beforeDial(id)
+			}
+			return servers[id].Dial(), nil
+		},
+	}, &State{}, d)
+	return c, d
+}
+
+// startTestConn runs a Conn connected to seedDC and waits for it to get ready.
+func startTestConn(t *testing.T, servers map[int]*mtprototest.Server, seedDC int, beforeDial func(id int)) (*Conn, *connDelegate, <-chan error) {
+	c, d := newTestConn(servers, seedDC, beforeDial)
+
+	runc := make(chan error, 1)
+	go func() {
+		runc <- c.Run()
+	}()
+	d.waitReady(t)
+	return c, d, runc
+}
+
+func stopTestConn(t *testing.T, c *Conn, runc <-chan error) {
+	c.Shutdown()
+	select {
+	case <-runc:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+// replayTest records what a request sent with migrateMidRequest went through.
+type replayTest struct {
+	r   tl.Object
+	err error
+
+	calls map[int]int // requests received by each DC
+	syncs int         // updates.getState and getDifference calls on DC 4
+}
+
+// migrateMidRequest leaves req unanswered on DC 2 and moves the connection
+// to DC 4 while it is pending; DC 4 answers it with answer.
+func migrateMidRequest(t *testing.T, ackRequests bool, req tl.Object, answer tl.Object) *replayTest {
+	servers := testDCs(2, 4)
+	servers[2].AckRequests = ackRequests
+
+	var mut sync.Mutex
+	res := &replayTest{calls: make(map[int]int)}
+	pending := make(chan struct{}, 1)
+	for id, srv := range servers {
+		id := id
+		srv.Handle(req.Cmd(), func(req tl.Object) tl.Object {
+			mut.Lock()
+			res.calls[id]++
+			mut.Unlock()
+			if id == 2 {
+				pending <- struct{}{}
+				return nil
+			}
+			return answer
+		})
+	}
+	servers[2].Handle(mtproto.TagAuthSendCode, func(req tl.Object) tl.Object {
+		return &mtproto.TLRPCError{ErrorCode: 303, ErrorMessage: "PHONE_MIGRATE_4"}
+	})
+	countSync := func(req tl.Object) tl.Object {
+		mut.Lock()
+		res.syncs++
+		mut.Unlock()
+		return &mtproto.TLUpdatesState{Pts: 1, Date: 1}
+	}
+	servers[4].Handle(mtproto.TagUpdatesGetState, countSync)
+	servers[4].Handle(mtproto.TagUpdatesGetDifference, func(req tl.Object) tl.Object {
+		countSync(req)
+		return &mtproto.TLUpdatesDifferenceEmpty{Date: 1}
+	})
+
+	c, d, runc := startTestConn(t, servers, 2, nil)
+	defer stopTestConn(t, c, runc)
+
+	type result struct {
+		r   tl.Object
+		err error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		r, err := c.Send(req)
+		resc <- result{r, err}
+	}()
+	<-pending
+
+	err := c.StartLogin("+15550000000")
+	if err != mtproto.ErrReconnectRequired {
+		t.Fatalf("StartLogin failed with %v, expected %v", err, mtproto.ErrReconnectRequired)
+	}
+	c.Fail(err)
+	d.waitReady(t)
+
+	select {
+	case r := <-resc:
+		res.r, res.err = r.r, r.err
+	case <-time.After(10 * time.Second):
+		t.Fatal("request not completed after reconnect")
+	}
+
+	// let the sync, if any, reach the server
+	if isRandomIDDuplicate(answer) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			mut.Lock()
+			syncs := res.syncs
+			mut.Unlock()
+			if syncs > 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	return res
+}
+
+func readHistory() tl.Object {
+	return &mtproto.TLMessagesReadHistory{Peer: &mtproto.TLInputPeerSelf{}, MaxID: 10}
+}
+
+func TestConnReplaysUnacknowledgedRequests(t *testing.T) {
+	res := migrateMidRequest(t, false, readHistory(), &mtproto.TLMessagesAffectedMessages{Pts: 4})
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if a, ok := res.r.(*mtproto.TLMessagesAffectedMessages); !ok || a.Pts != 4 {
+		t.Errorf("got %v, expected affectedMessages from DC 4", res.r)
+	}
+	if res.calls[2] != 1 || res.calls[4] != 1 {
+		t.Errorf("request reached DC 2 %d times and DC 4 %d times, expected once each", res.calls[2], res.calls[4])
+	}
+}
+
+func TestConnReplaysAcknowledgedReads(t *testing.T) {
+	res := migrateMidRequest(t, true, &mtproto.TLHelpGetNearestDC{}, &mtproto.TLNearestDC{ThisDC: 4})
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if dc, ok := res.r.(*mtproto.TLNearestDC); !ok || dc.ThisDC != 4 {
+		t.Errorf("got %v, expected nearestDc from DC 4", res.r)
+	}
+	if res.calls[2] != 1 || res.calls[4] != 1 {
+		t.Errorf("request reached DC 2 %d times and DC 4 %d times, expected once each", res.calls[2], res.calls[4])
+	}
+}
+
+func TestConnDoesNotReplayAcknowledgedRequests(t *testing.T) {
+	res := migrateMidRequest(t, true, readHistory(), &mtproto.TLMessagesAffectedMessages{Pts: 4})
+	if res.err != mtproto.ErrAnswerLost {
+		t.Errorf("got %v, %v, expected %v", res.r, res.err, mtproto.ErrAnswerLost)
+	}
+	if res.calls[2] != 1 || res.calls[4] != 0 {
+		t.Errorf("request reached DC 2 %d times and DC 4 %d times, expected DC 2 only", res.calls[2], res.calls[4])
+	}
+}
+
+func TestConnReplaysRandomIDRequests(t *testing.T) {
+	req := &mtproto.TLMessagesSendMessage{Peer: &mtproto.TLInputPeerSelf{}, Message: "hi", RandomID: 42}
+	res := migrateMidRequest(t, true, req, &mtproto.TLRPCError{ErrorCode: 400, ErrorMessage: "RANDOM_ID_DUPLICATE"})
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if u, ok := res.r.(*mtproto.TLUpdates); !ok || len(u.Updates) != 0 {
+		t.Errorf("got %v, expected empty updates", res.r)
+	}
+	if res.calls[2] != 1 || res.calls[4] != 1 {
+		t.Errorf("request reached DC 2 %d times and DC 4 %d times, expected once each", res.calls[2], res.calls[4])
+	}
+	if res.syncs == 0 {
+		t.Errorf("updates not synced after RANDOM_ID_DUPLICATE")
+	}
+}
+
+func TestConnPendingRequestGetsSessionError(t *testing.T) {
+	servers := testDCs(2)
+	pending := make(chan struct{}, 1)
+	servers[2].Handle(mtproto.TagMessagesReadHistory, func(req tl.Object) tl.Object {
+		pending <- struct{}{}
+		return nil
+	})
+
+	c, _, runc := startTestConn(t, servers, 2, nil)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Send(readHistory())
+		errc <- err
+	}()
+	<-pending
+
+	failure := errors.New("test failure")
+	c.Fail(failure)
+	select {
+	case err := <-runc:
+		if err != failure {
+			t.Errorf("Run returned %v, expected %v", err, failure)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	select {
+	case err := <-errc:
+		if err != failure {
+			t.Errorf("request failed with %v, expected %v", err, failure)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("request not completed")
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		o  tl.Object
+		ok bool
+	}{
+		{&mtproto.TLMessagesGetHistory{}, true},
+		{&mtproto.TLUpdatesGetDifference{}, true},
+		{&mtproto.TLContactsResolveUsername{}, true},
+		{&mtproto.TLMessagesSearch{}, true},
+		{&mtproto.TLMessagesGetBotCallbackAnswer{}, false},
+		{&mtproto.TLMessagesSendMessage{}, false},
+		{&mtproto.TLAccountUpdateStatus{}, false},
+		{&mtproto.TLInvokeWithLayer{}, false},
+	}
+	for _, tt := range tests {
+		if ok := isIdempotent(tt.o); ok != tt.ok {
+			t.Errorf("isIdempotent(%s) = %v, expected %v", tl.Name(tt.o), ok, tt.ok)
+		}
+	}
+	if !hasRandomID(&mtproto.TLMessagesForwardMessages{}) || hasRandomID(&mtproto.TLMessagesGetHistory{}) {
+		t.Errorf("hasRandomID is wrong")
+	}
+}
+
+func TestConnNotConnected(t *testing.T) {
+	servers := testDCs(2, 4)
+	c, _ := newTestConn(servers, 2, nil)
+
+	if _, err := c.Send(&mtproto.TLHelpGetNearestDC{}); err != ErrNotConnected {
+		t.Errorf("Send before Run failed with %v, expected %v", err, ErrNotConnected)
+	}
+	if _, err := c.SendToDC(4, &mtproto.TLHelpGetNearestDC{}); err != ErrNotConnected {
+		t.Errorf("SendToDC before Run failed with %v, expected %v", err, ErrNotConnected)
+	}
+
+	c, _, runc := startTestConn(t, servers, 2, nil)
+	stopTestConn(t, c, runc)
+
+	if _, err := c.Send(&mtproto.TLHelpGetNearestDC{}); err != ErrNotConnected {
+		t.Errorf("Send after Run failed with %v, expected %v", err, ErrNotConnected)
+	}
+	if _, err := c.SendToDC(4, &mtproto.TLHelpGetNearestDC{}); err != ErrNotConnected {
+		t.Errorf("SendToDC after Run failed with %v, expected %v", err, ErrNotConnected)
+	}
+}
+
+func TestConnFileMigrate(t *testing.T) {
+	servers := testDCs(2, 4)
+
+	var mut sync.Mutex
+	var events []string
+	record := func(event string) {
+		mut.Lock()
+		events = append(events, event)
+		mut.Unlock()
+	}
+	dials := make(map[int]int)
+
+	servers[2].Handle(mtproto.TagUploadGetFile, func(req tl.Object) tl.Object {
+		record("getFile 2")
+		return &mtproto.TLRPCError{ErrorCode: 303, ErrorMessage: "FILE_MIGRATE_4"}
+	})
+	servers[2].Handle(mtproto.TagAuthExportAuthorization, func(req tl.Object) tl.Object {
+		record("export " + strconv.Itoa(req.(*mtproto.TLAuthExportAuthorization).DCID))
+		return &mtproto.TLAuthExportedAuthorization{ID: 7, Bytes: []byte("auth")}
+	})
+	imported := false
+	servers[4].Handle(mtproto.TagAuthImportAuthorization, func(req tl.Object) tl.Object {
+		imp := req.(*mtproto.TLAuthImportAuthorization)
+		record("import " + strconv.Itoa(imp.ID) + " " + string(imp.Bytes))
+		mut.Lock()
+		imported = true
+		mut.Unlock()
+		return &mtproto.TLAuthAuthorization{User: &mtproto.TLUserEmpty{ID: 1}}
+	})
+	servers[4].Handle(mtproto.TagUploadGetFile, func(req tl.Object) tl.Object {
+		record("getFile 4")
+		mut.Lock()
+		defer mut.Unlock()
+		if !imported {
+			return &mtproto.TLRPCError{ErrorCode: 401, ErrorMessage: "AUTH_KEY_UNREGISTERED"}
+		}
+		return &mtproto.TLUploadFile{Type: &mtproto.TLStorageFileUnknown{}, Bytes: []byte("data")}
+	})
+
+	c, _, runc := startTestConn(t, servers, 2, func(id int) {
+		mut.Lock()
+		dials[id]++
+		mut.Unlock()
+	})
+	c.updateState(func(state *State) {
+		state.LoginState = LoggedIn
+	})
+
+	req := &mtproto.TLUploadGetFile{Location: &mtproto.TLInputFileLocation{VolumeID: 1, LocalID: 2, Secret: 3}, Limit: 1024}
+	for i := 0; i < 2; i++ {
+		r, err := c.Send(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f, ok := r.(*mtproto.TLUploadFile); !ok || string(f.Bytes) != "data" {
+			t.Fatalf("got %v, expected the file from DC 4", r)
+		}
+	}
+
+	stopTestConn(t, c, runc)
+
+	mut.Lock()
+	defer mut.Unlock()
+	expected := []string{"getFile 2", "export 4", "import 7 auth", "getFile 4", "getFile 2", "getFile 4"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("got %q, expected %q", events, expected)
+	}
+	if dials[4] != 1 {
+		t.Errorf("connected to DC 4 %d times, expected once", dials[4])
+	}
+	if c.state.DCs[4].Auth.KeyID == 0 {
+		t.Errorf("auth key of DC 4 not saved")
+	}
+}
+
+func TestConnSlowDCDoesNotBlockOthers(t *testing.T) {
+	servers := testDCs(2, 4, 5)
+	for id, srv := range servers {
+		id := id
+		srv.Handle(mtproto.TagHelpGetNearestDC, func(req tl.Object) tl.Object {
+			return &mtproto.TLNearestDC{ThisDC: id}
+		})
+	}
+
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	c, _, runc := startTestConn(t, servers, 2, func(id int) {
+		if id == 4 {
+			close(dialing)
+			<-release
+		}
+	})
+	defer stopTestConn(t, c, runc)
+
+	slowc := make(chan error, 1)
+	go func() {
+		_, err := c.SendToDC(4, &mtproto.TLHelpGetNearestDC{})
+		slowc <- err
+	}()
+	<-dialing
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := c.SendToDCContext(ctx, 5, &mtproto.TLHelpGetNearestDC{})
+	if err != nil {
+		t.Fatalf("DC 5 request failed with %v while DC 4 is connecting", err)
+	}
+	if dc, ok := r.(*mtproto.TLNearestDC); !ok || dc.ThisDC != 5 {
+		t.Errorf("got %v, expected nearestDc from DC 5", r)
+	}
+
+	close(release)
+	select {
+	case err := <-slowc:
+		if err != nil {
+			t.Errorf("DC 4 request failed with %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("DC 4 request not completed")
+	}
+}
+
+func TestDCPoolDropWaitsForSession(t *testing.T) {
+	servers := testDCs(2, 4)
+	servers[4].Handle(mtproto.TagHelpGetNearestDC, func(req tl.Object) tl.Object {
+		return &mtproto.TLNearestDC{ThisDC: 4}
+	})
+
+	c, _, runc := startTestConn(t, servers, 2, nil)
+	defer stopTestConn(t, c, runc)
+
+	if _, err := c.SendToDC(4, &mtproto.TLHelpGetNearestDC{}); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := c.pool.get(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.pool.drop(4)
+	select {
+	case <-ds.donec:
+	default:
+		t.Fatal("drop returned before the session state was saved")
+	}
+	c.stateMut.Lock()
+	keyID := c.state.DCs[4].Auth.KeyID
+	c.stateMut.Unlock()
+	if keyID == 0 {
+		t.Errorf("auth key of DC 4 not saved")
+	}
+}
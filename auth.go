@@ -93,7 +93,10 @@ func (c *Conn) completeLogin(auth *mtproto.TLAuthAuthorization) {
 			state.LastName = user.LastName
 			state.Username = user.Username
 		}
+		state.Updates = UpdatesState{}
+		state.Channels = make(map[int]*ChannelState)
 	})
+	c.updates.requestSync(true)
 }
 
 func (c *Conn) CompleteLoginWith2FAPassword(password []byte) error {
@@ -0,0 +1,224 @@
+package telegramapi
+
+import (
+	"log"
+	"sort"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+)
+
+// max number of messages to request per updates.getChannelDifference call
+const channelDifferenceLimit = 100
+
+// channelUpdates tracks the pts sequence of a single channel or supergroup.
+type channelUpdates struct {
+	ChannelState
+
+	pending  []*pendingUpdate
+	needSync bool
+}
+
+func (e *updatesEngine) trackChannel(id int) *channelUpdates {
+	ch := e.channels[id]
+	if ch == nil {
+		ch = &channelUpdates{
+			ChannelState: ChannelState{
+				ID:         id,
+				AccessHash: e.accessHashes[id],
+			},
+		}
+		e.channels[id] = ch
+		e.dirty = true
+	}
+	return ch
+}
+
+// learnChats remembers channel access hashes, which are required to call
+// updates.getChannelDifference.
+func (e *updatesEngine) learnChats(chats []mtproto.TLChatType) {
+	for _, chat := range chats {
+		switch chat := chat.(type) {
+		case *mtproto.TLChannel:
+			if chat.HasAccessHash() && !chat.Min() {
+				e.learnAccessHash(chat.ID, chat.AccessHash)
+			}
+		case *mtproto.TLChannelForbidden:
+			e.learnAccessHash(chat.ID, chat.AccessHash)
+		}
+	}
+}
+
+func (e *updatesEngine) learnAccessHash(id int, accessHash uint64) {
+	e.accessHashes[id] = accessHash
+	if ch := e.channels[id]; ch != nil && ch.AccessHash != accessHash {
+		ch.AccessHash = accessHash
+		e.dirty = true
+	}
+}
+
+func (e *updatesEngine) handleChannelPts(id, pts, ptsCount int, u *Update) {
+	ch := e.trackChannel(id)
+	if ch.Pts == 0 {
+		// first update from this channel, start following it from here
+		ch.Pts = pts
+		e.dirty = true
+		e.deliver(u)
+		return
+	}
+
+	ch.pending = e.applyPts(&ch.Pts, ch.pending, &pendingUpdate{pts, ptsCount, u})
+}
+
+func (e *updatesEngine) handleChannelTooLong(o *mtproto.TLUpdateChannelTooLong, u *Update) {
+	ch := e.trackChannel(o.ChannelID)
+	if ch.Pts == 0 && o.HasPts() {
+		ch.Pts = o.Pts
+		e.dirty = true
+	}
+	ch.needSync = true
+	e.deliver(u)
+}
+
+func (e *updatesEngine) needChannelSync() bool {
+	for _, ch := range e.channels {
+		if ch.needSync {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *updatesEngine) syncChannels() error {
+	defer e.commit()
+
+	for _, ch := range e.channels {
+		if !ch.needSync {
+			continue
+		}
+
+		err := e.syncChannel(ch)
+		if err != nil {
+			return err
+		}
+		ch.needSync = false
+
+		// replay buffered updates, the ones covered by the difference get dropped
+		pending := ch.pending
+		ch.pending = nil
+		for _, p := range pending {
+			ch.pending = e.applyPts(&ch.Pts, ch.pending, p)
+		}
+	}
+	return nil
+}
+
+func (e *updatesEngine) syncChannel(ch *channelUpdates) error {
+	if ch.Pts == 0 || ch.AccessHash == 0 {
+		log.Printf("** WARNING: cannot fetch difference of channel %d: pts or access hash unknown", ch.ID)
+		e.skipChannelGap(ch)
+		return nil
+	}
+
+	for {
+		r, err := e.send(&mtproto.TLUpdatesGetChannelDifference{
+			Channel: &mtproto.TLInputChannel{ChannelID: ch.ID, AccessHash: ch.AccessHash},
+			Filter:  &mtproto.TLChannelMessagesFilterEmpty{},
+			Pts:     ch.Pts,
+			Limit:   channelDifferenceLimit,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch r := r.(type) {
+		case *mtproto.TLUpdatesChannelDifferenceEmpty:
+			ch.Pts = r.Pts
+			e.dirty = true
+			return nil
+		case *mtproto.TLUpdatesChannelDifferenceTooLong:
+			if e.conn.Verbose >= 1 {
+				log.Printf("Channel %d difference too long, skipping to pts %d", ch.ID, r.Pts)
+			}
+			e.learnChats(r.Chats)
+			for _, msg := range r.Messages {
+				e.deliver(&Update{&mtproto.TLUpdateNewChannelMessage{Message: msg}, r.Users, r.Chats})
+			}
+			ch.Pts = r.Pts
+			e.dirty = true
+			if r.Final() {
+				return nil
+			}
+		case *mtproto.TLUpdatesChannelDifference:
+			e.learnChats(r.Chats)
+			for _, msg := range r.NewMessages {
+				e.deliver(&Update{&mtproto.TLUpdateNewChannelMessage{Message: msg}, r.Users, r.Chats})
+			}
+			for _, upd := range r.OtherUpdates {
+				e.deliver(&Update{upd, r.Users, r.Chats})
+			}
+			ch.Pts = r.Pts
+			e.dirty = true
+			if r.Final() {
+				return nil
+			}
+		default:
+			return e.conn.HandleUnknownReply(r)
+		}
+	}
+}
+
+// skipChannelGap gives up on the missing updates of a channel that cannot be
+// synced, delivering the buffered ones in pts order; otherwise they would
+// keep waiting for a gap that never gets filled.
+func (e *updatesEngine) skipChannelGap(ch *channelUpdates) {
+	sort.Slice(ch.pending, func(i, j int) bool {
+		return ch.pending[i].pts < ch.pending[j].pts
+	})
+	for _, p := range ch.pending {
+		if p.pts > ch.Pts {
+			ch.Pts = p.pts
+			e.dirty = true
+			e.deliver(p.update)
+		}
+	}
+	ch.pending = nil
+}
+
+// channelUpdatePts returns the channel ID and the channel pts carried by the
+// update, if any.
+func channelUpdatePts(u mtproto.TLUpdateType) (int, int, int, bool) {
+	var id, pts, ptsCount int
+	switch u := u.(type) {
+	case *mtproto.TLUpdateNewChannelMessage:
+		id, pts, ptsCount = messageChannelID(u.Message), u.Pts, u.PtsCount
+	case *mtproto.TLUpdateEditChannelMessage:
+		id, pts, ptsCount = messageChannelID(u.Message), u.Pts, u.PtsCount
+	case *mtproto.TLUpdateDeleteChannelMessages:
+		id, pts, ptsCount = u.ChannelID, u.Pts, u.PtsCount
+	case *mtproto.TLUpdateChannelWebPage:
+		id, pts, ptsCount = u.ChannelID, u.Pts, u.PtsCount
+	}
+	return id, pts, ptsCount, id != 0
+}
+
+func isChannelUpdate(u mtproto.TLUpdateType) bool {
+	if _, _, _, ok := channelUpdatePts(u); ok {
+		return true
+	}
+	_, ok := u.(*mtproto.TLUpdateChannelTooLong)
+	return ok
+}
+
+func messageChannelID(msg mtproto.TLMessageType) int {
+	var peer mtproto.TLPeerType
+	switch msg := msg.(type) {
+	case *mtproto.TLMessage:
+		peer = msg.ToID
+	case *mtproto.TLMessageService:
+		peer = msg.ToID
+	}
+	if peer, ok := peer.(*mtproto.TLPeerChannel); ok {
+		return peer.ChannelID
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package telegramapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func newChannelMsg(channelID, id, pts, ptsCount int) mtproto.TLUpdateType {
+	return &mtproto.TLUpdateNewChannelMessage{
+		Message:  &mtproto.TLMessage{ID: id, ToID: &mtproto.TLPeerChannel{ChannelID: channelID}},
+		Pts:      pts,
+		PtsCount: ptsCount,
+	}
+}
+
+func newChannel(id int, accessHash uint64) *mtproto.TLChannel {
+	ch := &mtproto.TLChannel{ID: id, AccessHash: accessHash}
+	ch.SetHasAccessHash(true)
+	return ch
+}
+
+func TestChannelPtsOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		updates   []mtproto.TLUpdateType
+		delivered []string
+		pts       int
+		pending   int
+	}{
+		{
+			"first update",
+			[]mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1)},
+			[]string{"chmsg 1"},
+			20, 0,
+		},
+		{
+			"out of order",
+			[]mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1), newChannelMsg(5, 3, 22, 1), newChannelMsg(5, 2, 21, 1)},
+			[]string{"chmsg 1", "chmsg 2", "chmsg 3"},
+			22, 0,
+		},
+		{
+			"gap",
+			[]mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1), newChannelMsg(5, 3, 22, 1)},
+			[]string{"chmsg 1"},
+			20, 1,
+		},
+		{
+			"duplicate",
+			[]mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1), newChannelMsg(5, 1, 20, 1)},
+			[]string{"chmsg 1"},
+			20, 0,
+		},
+	}
+
+	for _, tt := range tests {
+		e, d, _ := newTestEngine(10)
+		for _, u := range tt.updates {
+			e.handleUpdates(&mtproto.TLUpdateShort{Update: u})
+		}
+		runDelegateCalls(e)
+
+		if actual := d.delivered(); !reflect.DeepEqual(actual, tt.delivered) {
+			t.Errorf("%s: delivered %v, expected %v", tt.name, actual, tt.delivered)
+		}
+		ch := e.channels[5]
+		if ch.Pts != tt.pts {
+			t.Errorf("%s: channel pts == %d, expected %d", tt.name, ch.Pts, tt.pts)
+		}
+		if len(ch.pending) != tt.pending {
+			t.Errorf("%s: %d pending, expected %d", tt.name, len(ch.pending), tt.pending)
+		}
+		if e.state.Pts != 10 {
+			t.Errorf("%s: common pts changed to %d", tt.name, e.state.Pts)
+		}
+	}
+}
+
+func TestChannelGapRecovery(t *testing.T) {
+	diff := &mtproto.TLUpdatesChannelDifference{
+		Pts:         22,
+		NewMessages: []mtproto.TLMessageType{&mtproto.TLMessage{ID: 2, ToID: &mtproto.TLPeerChannel{ChannelID: 5}}},
+	}
+	diff.SetFinal(true)
+	e, d, f := newTestEngine(10, diff)
+
+	// the access hash comes along with the updates
+	e.handleUpdates(&mtproto.TLUpdates{
+		Updates: []mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1), newChannelMsg(5, 3, 23, 1)},
+		Chats:   []mtproto.TLChatType{newChannel(5, 99)},
+	})
+	e.expireGaps()
+	if !e.needChannelSync() {
+		t.Fatalf("expireGaps did not request a channel sync")
+	}
+	if err := e.syncChannels(); err != nil {
+		t.Fatal(err)
+	}
+	runDelegateCalls(e)
+
+	expected := []string{"chmsg 1", "chmsg 2", "chmsg 3"}
+	if actual := d.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("delivered %v, expected %v", actual, expected)
+	}
+	if len(f.requests) != 1 {
+		t.Fatalf("%d requests, expected 1", len(f.requests))
+	}
+	req, ok := f.requests[0].(*mtproto.TLUpdatesGetChannelDifference)
+	if !ok {
+		t.Fatalf("requested %s, expected updates.getChannelDifference", tl.Name(f.requests[0]))
+	}
+	if in := req.Channel.(*mtproto.TLInputChannel); in.ChannelID != 5 || in.AccessHash != 99 || req.Pts != 20 {
+		t.Errorf("requested difference of channel %d (access hash %d) from pts %d, expected channel 5 (99) from pts 20", in.ChannelID, in.AccessHash, req.Pts)
+	}
+	if ch := e.channels[5]; ch.Pts != 23 || len(ch.pending) != 0 || ch.needSync {
+		t.Errorf("channel pts == %d, %d pending, needSync %v; expected 23, none pending, synced", ch.Pts, len(ch.pending), ch.needSync)
+	}
+}
+
+// Without an access hash getChannelDifference cannot be called, so the
+// buffered updates are delivered as they are instead of waiting forever.
+func TestChannelGapWithoutAccessHash(t *testing.T) {
+	e, d, f := newTestEngine(10)
+
+	for _, u := range []mtproto.TLUpdateType{newChannelMsg(5, 1, 20, 1), newChannelMsg(5, 4, 24, 1), newChannelMsg(5, 3, 22, 1)} {
+		e.handleUpdates(&mtproto.TLUpdateShort{Update: u})
+	}
+	e.expireGaps()
+	if err := e.syncChannels(); err != nil {
+		t.Fatal(err)
+	}
+	runDelegateCalls(e)
+
+	expected := []string{"chmsg 1", "chmsg 3", "chmsg 4"}
+	if actual := d.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("delivered %v, expected %v", actual, expected)
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("%d requests, expected none", len(f.requests))
+	}
+	if e.needChannelSync() || e.hasPending() {
+		t.Errorf("channel still waiting for a sync")
+	}
+	if ch := e.channels[5]; ch.Pts != 24 {
+		t.Errorf("channel pts == %d, expected 24", ch.Pts)
+	}
+}
@@ -0,0 +1,345 @@
+package telegramapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// max number of *_MIGRATE_X redirects followed by a single request
+const maxMigrations = 3
+
+var ErrPoolClosed = errors.New("DC pool is closed")
+
+// dcPool keeps sessions to DCs other than the one Run is connected to,
+// opened on demand, e.g. to download files stored elsewhere. Each session
+// has its own auth key, persisted in State.DCs like the main one, and is
+// logged in by importing the authorization exported by the main DC.
+type dcPool struct {
+	conn *Conn
+
+	mut      sync.Mutex
+	sessions map[int]*dcSession
+	closed   bool
+	running  sync.WaitGroup
+}
+
+type dcSession struct {
+	id int
+
+	// closed once connected, sess or err is set by then
+	readyc chan struct{}
+	sess   *mtproto.Session
+	err    error
+	// the session has generated a new auth key, which is not logged in
+	newKey bool
+	// closed once run returns, after saving the session state
+	donec chan struct{}
+
+	authMut  sync.Mutex
+	imported bool
+}
+
+func newDCPool(c *Conn) *dcPool {
+	return &dcPool{
+		conn:     c,
+		sessions: make(map[int]*dcSession),
+	}
+}
+
+// get returns the session to the given DC, connecting if necessary. The
+// connection is made without holding the lock, so that a slow DC does not
+// hold up requests to the others; concurrent callers wait for the same one.
+func (p *dcPool) get(ctx context.Context, id int) (*dcSession, error) {
+	p.mut.Lock()
+	if p.closed {
+		p.mut.Unlock()
+		return nil, ErrPoolClosed
+	}
+	ds := p.sessions[id]
+	if ds == nil {
+		ds = &dcSession{id: id, readyc: make(chan struct{}), donec: make(chan struct{})}
+		p.sessions[id] = ds
+		p.running.Add(1)
+		go p.run(ds)
+	}
+	p.mut.Unlock()
+
+	select {
+	case <-ds.readyc:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if ds.err != nil {
+		return nil, ds.err
+	}
+	return ds, nil
+}
+
+// run connects to the DC and runs the session until it stops.
+func (p *dcPool) run(ds *dcSession) {
+	defer p.running.Done()
+	defer close(ds.donec)
+	c := p.conn
+
+	sess, newKey, err := p.open(ds.id)
+
+	p.mut.Lock()
+	ds.sess, ds.newKey, ds.err = sess, newKey, err
+	// dropped or closed while connecting
+	stale := p.sessions[ds.id] != ds
+	if err != nil && !stale {
+		delete(p.sessions, ds.id)
+	}
+	close(ds.readyc)
+	p.mut.Unlock()
+
+	if err != nil {
+		if c.Verbose >= 1 {
+			log.Printf("Failed to connect to DC %v: %v", ds.id, err)
+		}
+		return
+	}
+	if stale {
+		go sess.Shutdown()
+	}
+
+	sess.Run()
+	c.saveAuthState(sess)
+	if c.Verbose >= 1 {
+		log.Printf("Disconnected from DC %v: %v", ds.id, sess.Err())
+	}
+	p.remove(ds)
+}
+
+func (p *dcPool) open(id int) (*mtproto.Session, bool, error) {
+	c := p.conn
+
+	c.stateMut.Lock()
+	dc := c.state.DCs[id]
+	if dc != nil {
+		dc = dc.Clone()
+	}
+	c.stateMut.Unlock()
+	if dc == nil {
+		return nil, false, fmt.Errorf("unknown DC %d", id)
+	}
+
+	if c.Verbose >= 1 {
+		log.Printf("Connecting to DC %v at %v", dc.ID, dc.PrimaryAddr.Endpoint())
+	}
+
+	pubKeys, err := c.keyRing()
+	if err != nil {
+		return nil, false, err
+	}
+	tr, err := c.dial(dc)
+	if err != nil {
+		return nil, false, err
+	}
+	sess := c.newSession(tr, pubKeys)
+	sess.SetDC(dc.ID)
+	if dc.Auth.KeyID != 0 {
+		sess.RestoreAuthState(&dc.Auth, dc.FramerState)
+	}
+	sess.OnStateChanged(func() {
+		c.saveAuthState(sess)
+	})
+	return sess, dc.Auth.KeyID == 0, nil
+}
+
+func (p *dcPool) remove(ds *dcSession) {
+	p.mut.Lock()
+	if p.sessions[ds.id] == ds {
+		delete(p.sessions, ds.id)
+	}
+	p.mut.Unlock()
+}
+
+// drop disconnects from the given DC, e.g. once Run connects to it, so that
+// two sessions do not save their state into the same DCState. It returns
+// once the state of the dropped session has been saved.
+func (p *dcPool) drop(id int) {
+	p.mut.Lock()
+	ds := p.sessions[id]
+	delete(p.sessions, id)
+	sess := readySession(ds)
+	p.mut.Unlock()
+
+	if ds == nil {
+		return
+	}
+	if sess != nil {
+		sess.Shutdown()
+	}
+	<-ds.donec
+}
+
+// close disconnects from all DCs and waits for their state to be saved.
+func (p *dcPool) close() {
+	p.mut.Lock()
+	p.closed = true
+	var sessions []*mtproto.Session
+	for _, ds := range p.sessions {
+		if sess := readySession(ds); sess != nil {
+			sessions = append(sessions, sess)
+		}
+	}
+	p.sessions = make(map[int]*dcSession)
+	p.mut.Unlock()
+
+	for _, sess := range sessions {
+		sess.Shutdown()
+	}
+	p.running.Wait()
+}
+
+// readySession returns the session of ds if it has connected. The sessions
+// still connecting shut themselves down once they notice they are gone from
+// the pool. Must be called under p.mut.
+func readySession(ds *dcSession) *mtproto.Session {
+	if ds == nil {
+		return nil
+	}
+	select {
+	case <-ds.readyc:
+		return ds.sess
+	default:
+		return nil
+	}
+}
+
+// importAuthorization logs the session in using the authorization of the
+// main DC. It does nothing if another request has done it already.
+func (c *Conn) importAuthorization(ctx context.Context, ds *dcSession) error {
+	ds.authMut.Lock()
+	defer ds.authMut.Unlock()
+
+	if ds.imported {
+		return nil
+	}
+
+	if c.Verbose >= 1 {
+		log.Printf("Importing authorization into DC %v", ds.id)
+	}
+
+	r, err := c.SendContext(ctx, &mtproto.TLAuthExportAuthorization{DCID: ds.id})
+	if err != nil {
+		return err
+	}
+	exported, ok := r.(*mtproto.TLAuthExportedAuthorization)
+	if !ok {
+		return c.HandleUnknownReply(r)
+	}
+
+	r, err = ds.sess.SendContext(ctx, &mtproto.TLAuthImportAuthorization{
+		ID:    exported.ID,
+		Bytes: exported.Bytes,
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := r.(*mtproto.TLAuthAuthorization); !ok {
+		return c.HandleUnknownReply(r)
+	}
+
+	ds.imported = true
+	return nil
+}
+
+func (c *Conn) SendToDC(dcID int, o tl.Object) (tl.Object, error) {
+	return c.SendToDCContext(context.Background(), dcID, o)
+}
+
+// SendToDCContext sends a request to the given DC, connecting to it and
+// importing the authorization of the main DC if necessary. Requests for the
+// DC Run is connected to go through SendContext.
+//
+// Like SendContext, it follows FILE_MIGRATE_X errors to the DC that stores
+// the file.
+func (c *Conn) SendToDCContext(ctx context.Context, dcID int, o tl.Object) (tl.Object, error) {
+	for i := 0; ; i++ {
+		r, err := c.sendToDC(ctx, dcID, o)
+		if err != nil {
+			return nil, err
+		}
+		if id := migrateDC(r); id != 0 && i < maxMigrations {
+			if c.Verbose >= 1 {
+				log.Printf("Redirecting %s to DC %v", tl.Name(o), id)
+			}
+			dcID = id
+			continue
+		}
+		return r, nil
+	}
+}
+
+func (c *Conn) sendToDC(ctx context.Context, dcID int, o tl.Object) (tl.Object, error) {
+	sess, _ := c.currentSession()
+	if sess == nil {
+		return nil, ErrNotConnected
+	}
+	if sess.DC() == dcID {
+		return c.sendMain(ctx, o)
+	}
+
+	reconnected, imported := false, false
+	for {
+		ds, err := c.pool.get(ctx, dcID)
+		if err != nil {
+			return nil, err
+		}
+
+		// a new key is not logged in, so log in before the first request
+		// rather than waiting for it to fail with AUTH_KEY_UNREGISTERED
+		if ds.newKey && c.LoginState() == LoggedIn {
+			if err := c.importAuthorization(ctx, ds); err != nil {
+				return nil, err
+			}
+		}
+
+		r, err := ds.sess.SendContext(ctx, o)
+		if err != nil && canReplay(ds.sess, err, o) && !reconnected {
+			c.pool.remove(ds)
+			reconnected = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a persisted key may have been logged out since
+		if e, ok := r.(*mtproto.TLRPCError); ok && e.ErrorCode == 401 && e.ErrorMessage == "AUTH_KEY_UNREGISTERED" && !imported && c.LoginState() == LoggedIn {
+			if err := c.importAuthorization(ctx, ds); err != nil {
+				return nil, err
+			}
+			imported = true
+			continue
+		}
+		return r, nil
+	}
+}
+
+// migrateDC returns the DC to resend a request to, or 0. PHONE_MIGRATE_X and
+// friends that move the whole connection are left to HandleUnknownReply.
+func migrateDC(r tl.Object) int {
+	e, ok := r.(*mtproto.TLRPCError)
+	if !ok || e.ErrorCode != 303 {
+		return 0
+	}
+	nstr := stripPrefix(e.ErrorMessage, "FILE_MIGRATE_")
+	if nstr == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(nstr)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package mtproto
+
+import (
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// TLBool is a boxed Bool returned as an RPC result, e.g. by auth.bindTempAuthKey.
+// Generated structs represent Bool fields as plain bools, so the schema
+// factory does not know about boolTrue and boolFalse on its own.
+type TLBool struct {
+	Value bool
+}
+
+func (o *TLBool) Cmd() uint32 {
+	if o.Value {
+		return TagBoolTrue
+	} else {
+		return TagBoolFalse
+	}
+}
+
+func (o *TLBool) ReadBareFrom(r *tl.Reader) {
+}
+
+func (o *TLBool) WriteBareTo(w *tl.Writer) {
+}
+
+func (o *TLBool) String() string {
+	return tl.Pretty(o)
+}
+
+func init() {
+	factory := Schema.Factory
+	Schema.Factory = func(cmd uint32) tl.Object {
+		switch cmd {
+		case TagBoolTrue:
+			return &TLBool{Value: true}
+		case TagBoolFalse:
+			return &TLBool{Value: false}
+		default:
+			return factory(cmd)
+		}
+	}
+}
@@ -29,3 +29,11 @@ func EncryptRSAWithHash(unencrypted []byte, paddingSource []byte, pubKey *rsa.Pu
 
 	return encrypted
 }
+
+// DecryptRSAWithHash reverses EncryptRSAWithHash, returning the data (followed
+// by the padding) and its SHA1 hash; the caller must verify the hash once the
+// length of the data is known.
+func DecryptRSAWithHash(encrypted []byte, privKey *rsa.PrivateKey) ([]byte, []byte) {
+	decrypted := DecryptRSA(encrypted, privKey)
+	return decrypted[sha1.Size:], decrypted[:sha1.Size]
+}
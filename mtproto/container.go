@@ -0,0 +1,165 @@
+package mtproto
+
+import (
+	"log"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+const (
+	// how long outgoing messages wait to be packed together
+	flushDelay = 5 * time.Millisecond
+
+	// limits on what goes into a single msg_container
+	maxContainerMsgs = 1020
+	maxContainerSize = 1 << 15
+
+	maxAcksPerMsg = 8192
+
+	// msg_id, seqno and bytes of each message in a container
+	containerMsgOverhead = 16
+)
+
+// queuedMsg is an outgoing message waiting for the next flush.
+type queuedMsg struct {
+	Obj  tl.Object
+	Msg  Msg
+	Infl *rpcInFlight
+}
+
+// enqueue schedules o to be sent with the next flush, possibly in a container.
+func (sess *Session) enqueue(o tl.Object, infl *rpcInFlight) {
+	msg := compressMsg(MsgFromObj(o), sess.options.GzipThreshold)
+	sess.queue = append(sess.queue, queuedMsg{o, msg, infl})
+	if len(sess.queue) >= maxContainerMsgs {
+		sess.flush()
+	} else {
+		sess.scheduleFlush()
+	}
+}
+
+func (sess *Session) ack(msgID uint64) {
+	sess.pendingAcks = append(sess.pendingAcks, msgID)
+	sess.scheduleFlush()
+}
+
+func (sess *Session) scheduleFlush() {
+	if sess.flushc == nil {
+		sess.flushc = time.After(flushDelay)
+	}
+}
+
+// flush sends the queued messages and acks, packing them into containers.
+func (sess *Session) flush() {
+	sess.flushc = nil
+	if sess.err != nil {
+		return
+	}
+	sess.forgetOldMsgs()
+
+	var queue []queuedMsg
+	for acks := sess.pendingAcks; len(acks) > 0; {
+		n := len(acks)
+		if n > maxAcksPerMsg {
+			n = maxAcksPerMsg
+		}
+		o := &TLMsgsAck{MsgIDs: acks[:n]}
+		queue = append(queue, queuedMsg{o, MsgFromObj(o), nil})
+		acks = acks[n:]
+	}
+	sess.markAckSent(sess.pendingAcks)
+	sess.pendingAcks = nil
+	queue = append(queue, sess.queue...)
+	sess.queue = nil
+
+	for len(queue) > 0 {
+		n, size := 0, 0
+		for n < len(queue) && n < maxContainerMsgs {
+			l := len(queue[n].Msg.Payload) + containerMsgOverhead
+			if n > 0 && size+l > maxContainerSize {
+				break
+			}
+			size += l
+			n++
+		}
+
+		if n == 1 {
+			sess.sendMsg(queue[0].Obj, queue[0].Msg, false, queue[0].Infl)
+		} else {
+			sess.sendContainer(queue[:n])
+		}
+		queue = queue[n:]
+	}
+}
+
+func (sess *Session) sendContainer(batch []queuedMsg) {
+	msgIDs := make([]uint64, len(batch))
+
+	// msg_container, written by hand to reuse the (possibly compressed) payloads
+	w := tl.NewWriterCmd(TagMsgContainer)
+	w.WriteInt(len(batch))
+
+	sess.stateMut.Lock()
+	for i, q := range batch {
+		msgID, seqNo := sess.framer.NextInnerMsg(q.Msg.Type)
+		msgIDs[i] = msgID
+		w.WriteUint64(msgID)
+		w.WriteUint32(seqNo)
+		w.WriteInt(len(q.Msg.Payload))
+		w.Write(q.Msg.Payload)
+	}
+	raw, containerID, err := sess.framer.Format(Msg{w.Bytes(), ServiceMsg, 0, 0})
+	sess.stateMut.Unlock()
+	if err != nil {
+		sess.failInternal(err)
+		return
+	}
+
+	var reqIDs []uint64
+	for i, q := range batch {
+		msgID := msgIDs[i]
+		if sess.options.Verbose >= 2 {
+			log.Printf("mtproto.Session sending %s (%v bytes, %v, msgID %08x in container %08x)", q.Obj, len(q.Msg.Payload), q.Msg.Type, msgID, containerID)
+		} else if sess.options.Verbose >= 1 {
+			log.Printf("mtproto.Session sending %s (%v bytes, %v, msgID %08x in container %08x)", tl.Name(q.Obj), len(q.Msg.Payload), q.Msg.Type, msgID, containerID)
+		}
+
+		if infl := q.Infl; infl != nil {
+			infl.MsgID = msgID
+			infl.Obj = q.Obj
+			infl.ContainerID = containerID
+			sess.startPendingRPC(infl)
+			reqIDs = append(reqIDs, msgID)
+		}
+		sess.trackSent(msgID, q)
+	}
+	if len(reqIDs) > 0 {
+		sess.containers[containerID] = reqIDs
+	}
+
+	err = sess.transport.Send(raw)
+	if err != nil {
+		sess.failInternal(err)
+		return
+	}
+}
+
+// expandContainer maps the msg_id of a container to the requests sent in it;
+// other msg_ids are returned as is.
+func (sess *Session) expandContainer(msgID uint64) []uint64 {
+	if ids, ok := sess.containers[msgID]; ok {
+		return ids
+	}
+	return []uint64{msgID}
+}
+
+// releaseContainer forgets a container once none of its requests are in flight.
+func (sess *Session) releaseContainer(containerID uint64) {
+	for _, id := range sess.containers[containerID] {
+		if infl := sess.inFlight[id]; infl != nil && infl.ContainerID == containerID {
+			return
+		}
+	}
+	delete(sess.containers, containerID)
+}
@@ -0,0 +1,125 @@
+package mtproto
+
+import (
+	"math/big"
+	"sync"
+	"time"
+)
+
+// KeyExSecurityError is returned when a key exchange message fails one of the
+// checks required by the protocol, which may indicate a man-in-the-middle attack.
+type KeyExSecurityError struct {
+	Check string
+}
+
+func (e *KeyExSecurityError) Error() string {
+	return "key exchange security check failed: " + e.Check
+}
+
+var (
+	ErrBadNonce           = &KeyExSecurityError{"nonce mismatch"}
+	ErrBadDHPrime         = &KeyExSecurityError{"dh_prime is not a 2048-bit safe prime"}
+	ErrBadDHGenerator     = &KeyExSecurityError{"g does not generate the required subgroup"}
+	ErrBadGA              = &KeyExSecurityError{"g_a out of range"}
+	ErrBadGB              = &KeyExSecurityError{"g_b out of range"}
+	ErrBadNewNonceHash    = &KeyExSecurityError{"new_nonce_hash mismatch"}
+	ErrBadServerTime      = &KeyExSecurityError{"server_time too far off"}
+	ErrBadEncryptedAnswer = &KeyExSecurityError{"server_DH_inner_data hash mismatch"}
+)
+
+const dhPrimeBits = 2048
+
+// g_a and g_b must lie in (2^(2048-64), dh_prime - 2^(2048-64))
+var dhPublicMargin = new(big.Int).Lsh(big.NewInt(1), dhPrimeBits-64)
+
+// server_time may differ from the local clock by this much: a clock set in
+// the wrong time zone is off by up to 14 hours, larger offsets suggest a
+// replayed answer rather than a misconfigured clock
+const maxServerTimeSkew = 24 * time.Hour
+
+type dhParams struct {
+	prime *big.Int
+	g     int
+}
+
+// safe primes that passed the checks before, so that the expensive primality
+// test runs once per prime
+var knownDHPrimes = struct {
+	sync.Mutex
+	m map[string]bool
+}{
+	m: map[string]bool{
+		string(defaultDHPrime.Bytes()): true,
+	},
+}
+
+func checkDHPrime(p *big.Int) error {
+	if p.BitLen() != dhPrimeBits {
+		return ErrBadDHPrime
+	}
+
+	key := string(p.Bytes())
+	knownDHPrimes.Lock()
+	known := knownDHPrimes.m[key]
+	knownDHPrimes.Unlock()
+	if known {
+		return nil
+	}
+
+	// both p and (p-1)/2 must be prime
+	q := new(big.Int).Rsh(p, 1)
+	if !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		return ErrBadDHPrime
+	}
+
+	knownDHPrimes.Lock()
+	knownDHPrimes.m[key] = true
+	knownDHPrimes.Unlock()
+	return nil
+}
+
+// checkDHGenerator verifies that g generates a cyclic subgroup of prime order (p-1)/2.
+func checkDHGenerator(p *big.Int, g int) error {
+	mod := func(m int64) int64 {
+		return new(big.Int).Mod(p, big.NewInt(m)).Int64()
+	}
+
+	var ok bool
+	switch g {
+	case 2:
+		ok = (mod(8) == 7)
+	case 3:
+		ok = (mod(3) == 2)
+	case 4:
+		ok = true
+	case 5:
+		r := mod(5)
+		ok = (r == 1 || r == 4)
+	case 6:
+		r := mod(24)
+		ok = (r == 19 || r == 23)
+	case 7:
+		r := mod(7)
+		ok = (r == 3 || r == 5 || r == 6)
+	}
+	if !ok {
+		return ErrBadDHGenerator
+	}
+	return nil
+}
+
+func isValidDHPublic(x, p *big.Int) bool {
+	if x.Cmp(dhPublicMargin) <= 0 {
+		return false
+	}
+	upper := new(big.Int).Sub(p, dhPublicMargin)
+	return x.Cmp(upper) < 0
+}
+
+func checkServerTime(serverTime, now time.Time) error {
+	d := serverTime.Sub(now)
+	if d > maxServerTimeSkew || d < -maxServerTimeSkew {
+		return ErrBadServerTime
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func TestCheckDHPrime(t *testing.T) {
+	if err := checkDHPrime(defaultDHPrime); err != nil {
+		t.Errorf("default prime: got %v, expected no error", err)
+	}
+
+	notPrime := new(big.Int).Add(defaultDHPrime, big.NewInt(2))
+	if err := checkDHPrime(notPrime); err != ErrBadDHPrime {
+		t.Errorf("non-prime: got %v, expected %v", err, ErrBadDHPrime)
+	}
+
+	short := new(big.Int).Rsh(defaultDHPrime, 1)
+	if err := checkDHPrime(short); err != ErrBadDHPrime {
+		t.Errorf("2047-bit prime: got %v, expected %v", err, ErrBadDHPrime)
+	}
+}
+
+func TestCheckDHGenerator(t *testing.T) {
+	tests := []struct {
+		g  int
+		ok bool
+	}{
+		{1, false},
+		{2, false}, // defaultDHPrime is 3 mod 8
+		{3, true},
+		{4, true},
+		{5, false},
+		{6, false},
+		{7, true},
+		{8, false},
+	}
+	for _, tt := range tests {
+		err := checkDHGenerator(defaultDHPrime, tt.g)
+		if (err == nil) != tt.ok {
+			t.Errorf("g=%d: got %v, expected ok=%v", tt.g, err, tt.ok)
+		}
+	}
+}
+
+func TestIsValidDHPublic(t *testing.T) {
+	one := big.NewInt(1)
+	upper := new(big.Int).Sub(defaultDHPrime, dhPublicMargin)
+	tests := []struct {
+		x  *big.Int
+		ok bool
+	}{
+		{big.NewInt(2), false},
+		{dhPublicMargin, false},
+		{new(big.Int).Add(dhPublicMargin, one), true},
+		{new(big.Int).Sub(upper, one), true},
+		{upper, false},
+		{new(big.Int).Sub(defaultDHPrime, one), false},
+	}
+	for i, tt := range tests {
+		if ok := isValidDHPublic(tt.x, defaultDHPrime); ok != tt.ok {
+			t.Errorf("#%d: got %v, expected %v", i, ok, tt.ok)
+		}
+	}
+}
+
+func TestCheckServerTime(t *testing.T) {
+	now := time.Now()
+	if err := checkServerTime(now.Add(time.Hour), now); err != nil {
+		t.Errorf("got %v for a 1 hour skew, expected no error", err)
+	}
+	if err := checkServerTime(now.Add(-14*time.Hour), now); err != nil {
+		t.Errorf("got %v for a 14 hour skew, expected no error", err)
+	}
+	if err := checkServerTime(now.Add(48*time.Hour), now); err != ErrBadServerTime {
+		t.Errorf("got %v for a 2 day skew, expected %v", err, ErrBadServerTime)
+	}
+	if err := checkServerTime(now.Add(-365*24*time.Hour), now); err != ErrBadServerTime {
+		t.Errorf("got %v for a 1 year skew, expected %v", err, ErrBadServerTime)
+	}
+}
+
+func TestKeyExRejectsBadGenerator(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &KeyEx{PubKeys: KeyRing{&privKey.PublicKey}}
+	server := &ServerKeyEx{PrivKey: privKey, G: 2}
+	o := client.Start()
+	for i := 0; i < 2; i++ {
+		reply, err := server.Handle(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err = client.Handle(reply)
+		if i == 1 && err != ErrBadDHGenerator {
+			t.Errorf("got %v, expected %v", err, ErrBadDHGenerator)
+		}
+	}
+}
+
+func TestKeyExRejectsBadNewNonceHash(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &KeyEx{PubKeys: KeyRing{&privKey.PublicKey}}
+	server := &ServerKeyEx{PrivKey: privKey}
+	o := client.Start()
+	for !client.IsFinished() {
+		reply, err := server.Handle(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, isOK := reply.(*TLDHGenOK); isOK {
+			ok.NewNonceHash1[0] ^= 1
+		}
+		o, _ = client.Handle(Schema.MustReadBoxedObject(tl.Bytes(reply)))
+	}
+
+	_, err = client.Result()
+	if err != ErrBadNewNonceHash {
+		t.Errorf("got %v, expected %v", err, ErrBadNewNonceHash)
+	}
+	if _, ok := err.(*KeyExSecurityError); !ok {
+		t.Errorf("got %T, expected *KeyExSecurityError", err)
+	}
+}
@@ -0,0 +1,223 @@
+package mtproto
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Dialer establishes connections to DCs, proxies and WebSocket endpoints.
+// It is satisfied by *net.Dialer and golang.org/x/net/proxy dialers.
+type Dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+var defaultDialer Dialer = &net.Dialer{}
+
+var ErrSOCKS5Failed = errors.New("SOCKS5 proxy refused the connection")
+
+var ErrSOCKS5AuthFailed = errors.New("SOCKS5 proxy authentication failed")
+
+const (
+	socks5Version      = 0x05
+	socks5NoAuth       = 0x00
+	socks5UserPassAuth = 0x02
+	socks5NoAcceptable = 0xFF
+	socks5Connect      = 0x01
+	socks5IPv4         = 0x01
+	socks5Domain       = 0x03
+	socks5IPv6         = 0x04
+)
+
+// SOCKS5Dialer connects through a SOCKS5 proxy, optionally authenticating
+// with a username and password (RFC 1929).
+type SOCKS5Dialer struct {
+	Addr     string
+	Username string
+	Password string
+
+	// used to reach the proxy, defaults to a plain net.Dialer
+	Forward Dialer
+}
+
+func (d *SOCKS5Dialer) Dial(network, addr string) (net.Conn, error) {
+	c, err := forwardDialer(d.Forward).Dial(network, d.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.connect(c, addr)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (d *SOCKS5Dialer) connect(c net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	method := byte(socks5NoAuth)
+	if d.Username != "" {
+		method = socks5UserPassAuth
+	}
+	_, err = c.Write([]byte{socks5Version, 1, method})
+	if err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	_, err = io.ReadFull(c, resp)
+	if err != nil {
+		return err
+	}
+	if resp[0] != socks5Version || resp[1] == socks5NoAcceptable || resp[1] != method {
+		return ErrSOCKS5AuthFailed
+	}
+
+	if method == socks5UserPassAuth {
+		if len(d.Username) > 255 || len(d.Password) > 255 {
+			return ErrSOCKS5AuthFailed
+		}
+		req := []byte{0x01, byte(len(d.Username))}
+		req = append(req, d.Username...)
+		req = append(req, byte(len(d.Password)))
+		req = append(req, d.Password...)
+		_, err = c.Write(req)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.ReadFull(c, resp)
+		if err != nil {
+			return err
+		}
+		if resp[1] != 0 {
+			return ErrSOCKS5AuthFailed
+		}
+	}
+
+	req := []byte{socks5Version, socks5Connect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5IPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5IPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %v", host)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	_, err = c.Write(req)
+	if err != nil {
+		return err
+	}
+
+	// VER REP RSV ATYP, then the bound address we don't care about
+	header := make([]byte, 4)
+	_, err = io.ReadFull(c, header)
+	if err != nil {
+		return err
+	}
+	if header[0] != socks5Version || header[1] != 0 {
+		return ErrSOCKS5Failed
+	}
+
+	var n int
+	switch header[3] {
+	case socks5IPv4:
+		n = net.IPv4len
+	case socks5IPv6:
+		n = net.IPv6len
+	case socks5Domain:
+		_, err = io.ReadFull(c, resp[:1])
+		if err != nil {
+			return err
+		}
+		n = int(resp[0])
+	default:
+		return ErrSOCKS5Failed
+	}
+	_, err = io.ReadFull(c, make([]byte, n+2))
+	return err
+}
+
+// HTTPConnectDialer connects through an HTTP proxy using the CONNECT method,
+// optionally with basic authentication.
+type HTTPConnectDialer struct {
+	Addr     string
+	Username string
+	Password string
+
+	// used to reach the proxy, defaults to a plain net.Dialer
+	Forward Dialer
+}
+
+func (d *HTTPConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	c, err := forwardDialer(d.Forward).Dial(network, d.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if d.Username != "" {
+		cred := base64.StdEncoding.EncodeToString([]byte(d.Username + ":" + d.Password))
+		req += "Proxy-Authorization: Basic " + cred + "\r\n"
+	}
+	req += "\r\n"
+	_, err = io.WriteString(c, req)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	// read byte by byte so that nothing past the response gets buffered
+	resp, err := http.ReadResponse(bufio.NewReaderSize(byteReader{c}, 16), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	// the body of a successful response is the tunnel itself, so don't touch it
+	if resp.StatusCode != http.StatusOK {
+		c.Close()
+		return nil, fmt.Errorf("HTTP proxy CONNECT failed: %v", resp.Status)
+	}
+	return c, nil
+}
+
+// byteReader limits reads to a single byte.
+type byteReader struct {
+	r io.Reader
+}
+
+func (r byteReader) Read(b []byte) (int, error) {
+	if len(b) > 1 {
+		b = b[:1]
+	}
+	return r.r.Read(b)
+}
+
+func forwardDialer(d Dialer) Dialer {
+	if d == nil {
+		return defaultDialer
+	}
+	return d
+}
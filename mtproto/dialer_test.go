@@ -0,0 +1,194 @@
+package mtproto
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// startTestSOCKS5 is a stand-in SOCKS5 proxy requiring the given credentials.
+func startTestSOCKS5(t *testing.T, username, password string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+
+				buf := make([]byte, 2)
+				if _, err := io.ReadFull(c, buf); err != nil {
+					return
+				}
+				methods := make([]byte, buf[1])
+				if _, err := io.ReadFull(c, methods); err != nil {
+					return
+				}
+				if len(methods) != 1 || methods[0] != socks5UserPassAuth {
+					c.Write([]byte{socks5Version, socks5NoAcceptable})
+					return
+				}
+				c.Write([]byte{socks5Version, socks5UserPassAuth})
+
+				user := readTestSOCKS5String(c, 1)
+				pass := readTestSOCKS5String(c, 0)
+				if user != username || pass != password {
+					c.Write([]byte{0x01, 0x01})
+					return
+				}
+				c.Write([]byte{0x01, 0x00})
+
+				header := make([]byte, 4)
+				if _, err := io.ReadFull(c, header); err != nil {
+					return
+				}
+				var host string
+				switch header[3] {
+				case socks5IPv4:
+					ip := make([]byte, net.IPv4len)
+					io.ReadFull(c, ip)
+					host = net.IP(ip).String()
+				case socks5Domain:
+					host = readTestSOCKS5String(c, 0)
+				default:
+					t.Errorf("unexpected address type %v", header[3])
+					return
+				}
+				port := make([]byte, 2)
+				io.ReadFull(c, port)
+
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))))
+				if err != nil {
+					c.Write([]byte{socks5Version, 0x05, 0, socks5IPv4, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				c.Write([]byte{socks5Version, 0, 0, socks5IPv4, 127, 0, 0, 1, 0, 0})
+
+				go io.Copy(target, c)
+				io.Copy(c, target)
+			}()
+		}
+	}()
+	return l
+}
+
+func readTestSOCKS5String(r io.Reader, skip int) string {
+	buf := make([]byte, skip+1)
+	io.ReadFull(r, buf)
+	s := make([]byte, buf[skip])
+	io.ReadFull(r, s)
+	return string(s)
+}
+
+// startTestHTTPProxy is a stand-in HTTP CONNECT proxy requiring the given credentials.
+func startTestHTTPProxy(t *testing.T, username, password string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+
+				br := bufio.NewReader(c)
+				req, err := http.ReadRequest(br)
+				if err != nil {
+					return
+				}
+				if req.Method != "CONNECT" {
+					t.Errorf("unexpected method %v", req.Method)
+					return
+				}
+
+				probe := &http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}
+				user, pass, ok := probe.BasicAuth()
+				if !ok || user != username || pass != password {
+					io.WriteString(c, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+					return
+				}
+
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+					return
+				}
+				defer target.Close()
+				io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+				go io.Copy(target, br)
+				io.Copy(c, target)
+			}()
+		}
+	}()
+	return l
+}
+
+func TestProxyDialers(t *testing.T) {
+	socks := startTestSOCKS5(t, "alice", "secret")
+	defer socks.Close()
+	httpProxy := startTestHTTPProxy(t, "alice", "secret")
+	defer httpProxy.Close()
+	dc := startEchoDC(t)
+	defer dc.Close()
+
+	dialers := []Dialer{
+		&SOCKS5Dialer{Addr: socks.Addr().String(), Username: "alice", Password: "secret"},
+		&HTTPConnectDialer{Addr: httpProxy.Addr().String(), Username: "alice", Password: "secret"},
+	}
+	for _, d := range dialers {
+		tr, err := DialTCP(dc.Addr().String(), TCPTransportOptions{
+			Framing: IntermediateFraming,
+			Dialer:  d,
+		})
+		if err != nil {
+			t.Fatalf("%T: %v", d, err)
+		}
+
+		packet := fromHex("0000000000000000 0102030405060708 04000000 aabbccdd")
+		err = tr.Send(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _, err := tr.Recv()
+		if err != nil {
+			t.Fatalf("%T: %v", d, err)
+		}
+		if a, e := hex.EncodeToString(raw), hex.EncodeToString(packet); a != e {
+			t.Errorf("%T: received %v, expected %v", d, a, e)
+		}
+
+		tr.Close()
+	}
+}
+
+func TestProxyDialersWrongCredentials(t *testing.T) {
+	socks := startTestSOCKS5(t, "alice", "secret")
+	defer socks.Close()
+	httpProxy := startTestHTTPProxy(t, "alice", "secret")
+	defer httpProxy.Close()
+
+	_, err := (&SOCKS5Dialer{Addr: socks.Addr().String(), Username: "alice", Password: "wrong"}).Dial("tcp", "127.0.0.1:1")
+	if err != ErrSOCKS5AuthFailed {
+		t.Errorf("SOCKS5: got %v, expected %v", err, ErrSOCKS5AuthFailed)
+	}
+
+	_, err = (&HTTPConnectDialer{Addr: httpProxy.Addr().String(), Username: "alice", Password: "wrong"}).Dial("tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Errorf("HTTP CONNECT: expected an error")
+	}
+}
@@ -0,0 +1,248 @@
+package mtproto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	tlsRecordHandshake        = 0x16
+	tlsRecordChangeCipherSpec = 0x14
+	tlsRecordApplicationData  = 0x17
+
+	tlsRecordHeaderLen = 5
+	// max payload of a single application data record
+	tlsMaxRecordLen = 16384
+
+	// size of the ClientHello handshake message, matching popular browsers
+	fakeTLSHelloLen = 512
+
+	// offset of the 32-byte random field in the first handshake record
+	fakeTLSRandomOffset = 11
+)
+
+var ErrFakeTLSHandshake = errors.New("fake TLS handshake failed")
+
+var tlsChangeCipherSpec = []byte{tlsRecordChangeCipherSpec, 0x03, 0x03, 0x00, 0x01, 0x01}
+
+// fakeTLSConn disguises the traffic as a TLS 1.3 connection by wrapping it into
+// application data records, as expected by MTProxy with an ee-prefixed secret.
+type fakeTLSConn struct {
+	net.Conn
+
+	rbuf       []byte
+	headerSent bool
+}
+
+// fakeTLSHandshake sends a ClientHello signed with the proxy secret and checks
+// the signature of the server response.
+func fakeTLSHandshake(c net.Conn, secret *ProxySecret, random io.Reader, now time.Time) (*fakeTLSConn, error) {
+	hello, err := buildFakeTLSClientHello(secret.Domain, random)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret.Key)
+	mac.Write(hello)
+	digest := mac.Sum(nil)
+	ts := binary.LittleEndian.Uint32(digest[28:32]) ^ uint32(now.Unix())
+	binary.LittleEndian.PutUint32(digest[28:32], ts)
+	copy(hello[fakeTLSRandomOffset:], digest)
+
+	_, err = c.Write(hello)
+	if err != nil {
+		return nil, err
+	}
+
+	// ServerHello, ChangeCipherSpec and the first application data record
+	var resp []byte
+	for _, typ := range []byte{tlsRecordHandshake, tlsRecordChangeCipherSpec, tlsRecordApplicationData} {
+		record, err := readTLSRecord(c)
+		if err != nil {
+			return nil, err
+		}
+		if record[0] != typ {
+			return nil, ErrFakeTLSHandshake
+		}
+		resp = append(resp, record...)
+	}
+
+	if len(resp) < fakeTLSRandomOffset+32 {
+		return nil, ErrFakeTLSHandshake
+	}
+	serverRandom := make([]byte, 32)
+	copy(serverRandom, resp[fakeTLSRandomOffset:])
+	copy(resp[fakeTLSRandomOffset:fakeTLSRandomOffset+32], make([]byte, 32))
+
+	mac.Reset()
+	mac.Write(hello[fakeTLSRandomOffset : fakeTLSRandomOffset+32])
+	mac.Write(resp)
+	if subtle.ConstantTimeCompare(mac.Sum(nil), serverRandom) != 1 {
+		return nil, ErrFakeTLSHandshake
+	}
+
+	return &fakeTLSConn{Conn: c}, nil
+}
+
+func (c *fakeTLSConn) Read(b []byte) (int, error) {
+	for len(c.rbuf) == 0 {
+		record, err := readTLSRecord(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+		switch record[0] {
+		case tlsRecordApplicationData:
+			c.rbuf = record[tlsRecordHeaderLen:]
+		case tlsRecordChangeCipherSpec:
+			// sent once by the client before any data
+		default:
+			return 0, ErrFakeTLSHandshake
+		}
+	}
+
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+func (c *fakeTLSConn) Write(b []byte) (int, error) {
+	var buf []byte
+	if !c.headerSent {
+		buf = append(buf, tlsChangeCipherSpec...)
+		c.headerSent = true
+	}
+	for rem := b; len(rem) > 0; {
+		n := len(rem)
+		if n > tlsMaxRecordLen {
+			n = tlsMaxRecordLen
+		}
+		buf = append(buf, tlsRecordApplicationData, 0x03, 0x03, byte(n>>8), byte(n))
+		buf = append(buf, rem[:n]...)
+		rem = rem[n:]
+	}
+
+	_, err := c.Conn.Write(buf)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// readTLSRecord returns a whole TLS record including its header.
+func readTLSRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, tlsRecordHeaderLen)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	if header[1] != 0x03 {
+		return nil, ErrFakeTLSHandshake
+	}
+
+	n := int(binary.BigEndian.Uint16(header[3:5]))
+	if n > tlsMaxRecordLen+256 {
+		return nil, ErrFakeTLSHandshake
+	}
+	record := make([]byte, tlsRecordHeaderLen+n)
+	copy(record, header)
+	_, err = io.ReadFull(r, record[tlsRecordHeaderLen:])
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// buildFakeTLSClientHello returns a ClientHello record with a zero random field.
+func buildFakeTLSClientHello(domain string, random io.Reader) ([]byte, error) {
+	sessionID := make([]byte, 32)
+	keyShare := make([]byte, 32)
+	for _, b := range [][]byte{sessionID, keyShare} {
+		_, err := io.ReadFull(random, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ext bytes.Buffer
+	writeTLSExtension(&ext, 0x0000, func(b *bytes.Buffer) {
+		// server_name
+		writeUint16(b, len(domain)+3)
+		b.WriteByte(0x00)
+		writeUint16(b, len(domain))
+		b.WriteString(domain)
+	})
+	writeTLSExtension(&ext, 0x0017, nil)                                      // extended_master_secret
+	writeTLSExtension(&ext, 0xff01, func(b *bytes.Buffer) { b.WriteByte(0) }) // renegotiation_info
+	writeTLSExtension(&ext, 0x000a, func(b *bytes.Buffer) {
+		// supported_groups: x25519, secp256r1, secp384r1
+		b.Write([]byte{0x00, 0x06, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18})
+	})
+	writeTLSExtension(&ext, 0x000b, func(b *bytes.Buffer) {
+		// ec_point_formats: uncompressed
+		b.Write([]byte{0x01, 0x00})
+	})
+	writeTLSExtension(&ext, 0x000d, func(b *bytes.Buffer) {
+		// signature_algorithms
+		b.Write([]byte{0x00, 0x08, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03})
+	})
+	writeTLSExtension(&ext, 0x0033, func(b *bytes.Buffer) {
+		// key_share: x25519
+		writeUint16(b, len(keyShare)+4)
+		b.Write([]byte{0x00, 0x1d})
+		writeUint16(b, len(keyShare))
+		b.Write(keyShare)
+	})
+	writeTLSExtension(&ext, 0x002b, func(b *bytes.Buffer) {
+		// supported_versions: TLS 1.3, TLS 1.2
+		b.Write([]byte{0x04, 0x03, 0x04, 0x03, 0x03})
+	})
+
+	var body bytes.Buffer
+	body.Write([]byte{0x03, 0x03})
+	body.Write(make([]byte, 32)) // random, filled in later
+	body.WriteByte(byte(len(sessionID)))
+	body.Write(sessionID)
+	// cipher suites
+	body.Write([]byte{0x00, 0x06, 0x13, 0x01, 0x13, 0x02, 0x13, 0x03})
+	// compression methods: null
+	body.Write([]byte{0x01, 0x00})
+
+	// pad the hello to the usual size
+	const handshakeHeaderLen = 4
+	padding := fakeTLSHelloLen - handshakeHeaderLen - body.Len() - 2 - ext.Len() - 4
+	if padding >= 0 {
+		writeTLSExtension(&ext, 0x0015, func(b *bytes.Buffer) { b.Write(make([]byte, padding)) })
+	}
+	writeUint16(&body, ext.Len())
+	body.Write(ext.Bytes())
+
+	var hello bytes.Buffer
+	hello.Write([]byte{tlsRecordHandshake, 0x03, 0x01})
+	writeUint16(&hello, body.Len()+handshakeHeaderLen)
+	hello.WriteByte(0x01) // ClientHello
+	hello.Write([]byte{byte(body.Len() >> 16), byte(body.Len() >> 8), byte(body.Len())})
+	hello.Write(body.Bytes())
+	return hello.Bytes(), nil
+}
+
+func writeTLSExtension(b *bytes.Buffer, typ int, f func(b *bytes.Buffer)) {
+	var data bytes.Buffer
+	if f != nil {
+		f(&data)
+	}
+	writeUint16(b, typ)
+	writeUint16(b, data.Len())
+	b.Write(data.Bytes())
+}
+
+func writeUint16(b *bytes.Buffer, v int) {
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
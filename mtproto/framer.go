@@ -3,14 +3,39 @@ package mtproto
 import (
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
+	"github.com/andreyvit/telegramapi/binints"
 	"github.com/andreyvit/telegramapi/tl"
 	"io"
 	"log"
+	"time"
 )
 
 var ErrUnknownKeyID = errors.New("unknown auth key ID")
 
+var ErrMsgKeyMismatch = errors.New("msg_key does not match decrypted data")
+
+var ErrInvalidPadding = errors.New("invalid padding length")
+
+var ErrSessionIDMismatch = errors.New("session_id does not match")
+
+// ProtocolVersion selects how encrypted messages are framed.
+type ProtocolVersion int
+
+const (
+	// MTProto 1.0: SHA-1 msg_key, SHA-1 based KDF, 0-15 bytes of padding
+	MTProto1 ProtocolVersion = iota
+	// MTProto 2.0: SHA-256 msg_key covering part of the auth key, SHA-256 based KDF, 12-1024 bytes of padding
+	MTProto2
+)
+
+const (
+	minPadding2 = 12
+	maxPadding2 = 1024
+)
+
 type FramerState struct {
 	SeqNo uint32
 }
@@ -18,10 +43,21 @@ type FramerState struct {
 type Framer struct {
 	MsgIDOverride uint64
 	RandomReader  io.Reader
+	Version       ProtocolVersion
+
+	// PaddingOverride fixes the MTProto 2.0 padding length, for test vectors
+	PaddingOverride int
+
+	// Server makes the framer parse client-encrypted messages and emit
+	// server-encrypted ones; it also adopts the session ID and salt of incoming messages.
+	Server bool
 
 	gen  MsgIDGen
 	auth *AuthResult
 
+	// previous key, still accepted for incoming messages after switching keys
+	prevAuth *AuthResult
+
 	FramerState
 }
 
@@ -35,20 +71,77 @@ func (fr *Framer) Restore(state FramerState) {
 }
 
 func (fr *Framer) SetAuth(auth *AuthResult) {
+	if fr.auth != nil && auth != nil && fr.auth.KeyID != auth.KeyID {
+		fr.prevAuth = fr.auth
+	}
 	fr.auth = auth
 	if fr.RandomReader == nil {
 		fr.RandomReader = rand.Reader
 	}
 }
 
-func (fr *Framer) Format(msg Msg) ([]byte, uint64, error) {
+// ReserveMsgID returns the msg_id that the next formatted message will get.
+func (fr *Framer) ReserveMsgID() uint64 {
+	fr.MsgIDOverride = fr.nextMsgID()
+	return fr.MsgIDOverride
+}
+
+func (fr *Framer) nextMsgID() uint64 {
 	var msgID uint64
 	if fr.MsgIDOverride != 0 {
 		msgID = fr.MsgIDOverride
 		fr.MsgIDOverride = 0
 	} else {
-		msgID = fr.gen.Generate()
+		now := time.Now()
+		if fr.auth != nil {
+			// msg_id must follow the server clock
+			now = now.Add(time.Duration(fr.auth.TimeOffset) * time.Second)
+		}
+		msgID = fr.gen.GenerateAt(now)
 	}
+	if fr.Server {
+		// server message IDs are odd
+		msgID |= 1
+	}
+	return msgID
+}
+
+func (fr *Framer) nextSeqNo(typ MsgType) uint32 {
+	if typ == ContentMsg {
+		seqNo := fr.SeqNo + 1
+		fr.SeqNo += 2
+		return seqNo
+	} else {
+		return fr.SeqNo
+	}
+}
+
+// NextInnerMsg assigns msg_id and seq_no to a message that goes into a
+// container. The container itself must be formatted afterwards, so that its
+// msg_id is greater than those of the messages inside.
+func (fr *Framer) NextInnerMsg(typ MsgType) (uint64, uint32) {
+	return fr.nextMsgID(), fr.nextSeqNo(typ)
+}
+
+// FormatPlain formats an unencrypted message, which is how key exchange
+// messages are sent even when an auth key is already in use.
+func (fr *Framer) FormatPlain(msg Msg) ([]byte, uint64, error) {
+	msgID := fr.nextMsgID()
+
+	w := tl.NewWriter()
+	writePlainMsg(w, msgID, msg)
+	return w.Bytes(), msgID, nil
+}
+
+func writePlainMsg(w *tl.Writer, msgID uint64, msg Msg) {
+	w.WriteUint64(0)
+	w.WriteUint64(msgID)
+	w.WriteInt(len(msg.Payload))
+	w.Write(msg.Payload)
+}
+
+func (fr *Framer) Format(msg Msg) ([]byte, uint64, error) {
+	msgID := fr.nextMsgID()
 
 	w := tl.NewWriter()
 	if fr.auth == nil {
@@ -56,18 +149,9 @@ func (fr *Framer) Format(msg Msg) ([]byte, uint64, error) {
 			panic("cannot send encrypted messages before key exchange is finished")
 		}
 
-		w.WriteUint64(0)
-		w.WriteUint64(msgID)
-		w.WriteInt(len(msg.Payload))
-		w.Write(msg.Payload)
+		writePlainMsg(w, msgID, msg)
 	} else {
-		var seqNo uint32
-		if msg.Type == ContentMsg {
-			seqNo = fr.SeqNo + 1
-			fr.SeqNo += 2
-		} else {
-			seqNo = fr.SeqNo
-		}
+		seqNo := fr.nextSeqNo(msg.Type)
 
 		w.Write(fr.auth.ServerSalt[:])
 		w.Write(fr.auth.SessionID[:])
@@ -75,32 +159,40 @@ func (fr *Framer) Format(msg Msg) ([]byte, uint64, error) {
 		w.WriteUint32(seqNo)
 		w.WriteInt(len(msg.Payload))
 		w.Write(msg.Payload)
-		hash := sha1.Sum(w.Bytes())
 
 		var msgKey [16]byte
-		copy(msgKey[:], hash[4:20])
+		var key, iv [32]byte
+		if fr.Version == MTProto2 {
+			pad, err := fr.padding2(len(w.Bytes()))
+			if err != nil {
+				return nil, 0, err
+			}
+			err = fr.writePadding(w, pad)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			computeMsgKey2(fr.auth.Key, w.Bytes(), msgKey[:], !fr.Server)
+			deriveAESKey2(fr.auth.Key, msgKey[:], key[:], iv[:], !fr.Server)
+		} else {
+			hash := sha1.Sum(w.Bytes())
+			copy(msgKey[:], hash[4:20])
 
-		pad := w.PaddingTo(16)
-		if pad > 0 {
-			var padding [16]byte
-			_, err := io.ReadFull(fr.RandomReader, padding[:pad])
+			err := fr.writePadding(w, w.PaddingTo(16))
 			if err != nil {
-				log.Printf("failed to read padding (%d): %v", pad, err)
 				return nil, 0, err
 			}
-			w.Write(padding[:pad])
+
+			deriveAESKey(fr.auth.Key, msgKey[:], key[:], iv[:], !fr.Server)
 		}
 		data := w.Bytes()
 
-		var key, iv [32]byte
-		deriveAESKey(fr.auth.Key, msgKey[:], key[:], iv[:], true)
-
 		// log.Printf("AES key: %x", key)
 		// log.Printf("AES iv: %x", key)
 
 		encrypted, err := AESIGEPadEncrypt(nil, data, key[:], iv[:], nil)
 		if err != nil {
-			log.Printf("encryption failed: %v", pad, err)
+			log.Printf("encryption failed: %v", err)
 			return nil, 0, err
 		}
 
@@ -113,6 +205,36 @@ func (fr *Framer) Format(msg Msg) ([]byte, uint64, error) {
 	return w.Bytes(), msgID, nil
 }
 
+// padding2 picks a random MTProto 2.0 padding length for a message of n
+// bytes, so that the length of the message is not revealed.
+func (fr *Framer) padding2(n int) (int, error) {
+	if fr.PaddingOverride != 0 {
+		return fr.PaddingOverride, nil
+	}
+
+	min := minPadding2 + paddingTo(n+minPadding2, 16)
+	r, err := binints.ReadUint24LE(fr.RandomReader)
+	if err != nil {
+		return 0, err
+	}
+	blocks := (maxPadding2 - min) / 16
+	return min + 16*(int(r)%(blocks+1)), nil
+}
+
+func (fr *Framer) writePadding(w *tl.Writer, pad int) error {
+	if pad == 0 {
+		return nil
+	}
+	padding := make([]byte, pad)
+	_, err := io.ReadFull(fr.RandomReader, padding)
+	if err != nil {
+		log.Printf("failed to read padding (%d): %v", pad, err)
+		return err
+	}
+	w.Write(padding)
+	return nil
+}
+
 func (fr *Framer) Parse(raw []byte) (Msg, error) {
 	var r tl.Reader
 	r.Reset(raw)
@@ -128,9 +250,13 @@ func (fr *Framer) Parse(raw []byte) (Msg, error) {
 		payload := r.ReadN(msgLen)
 		r.ExpectEOF()
 
-		return Msg{payload, KeyExMsg, msgID}, r.Err()
+		return Msg{payload, KeyExMsg, msgID, 0}, r.Err()
 	} else {
-		if authKeyID != fr.auth.KeyID {
+		auth := fr.auth
+		if auth != nil && authKeyID != auth.KeyID {
+			auth = fr.prevAuth
+		}
+		if auth == nil || authKeyID != auth.KeyID {
 			return Msg{}, ErrUnknownKeyID
 		}
 
@@ -144,7 +270,11 @@ func (fr *Framer) Parse(raw []byte) (Msg, error) {
 		// log.Printf("Received encrypted: authKeyID=%x data=(%d) %x", authKeyID, len(enc), enc)
 
 		var key, iv [32]byte
-		deriveAESKey(fr.auth.Key, msgKey[:], key[:], iv[:], false)
+		if fr.Version == MTProto2 {
+			deriveAESKey2(auth.Key, msgKey[:], key[:], iv[:], fr.Server)
+		} else {
+			deriveAESKey(auth.Key, msgKey[:], key[:], iv[:], fr.Server)
+		}
 		// log.Printf("AES key: %x", key)
 		// log.Printf("AES iv: %x", key)
 
@@ -153,6 +283,14 @@ func (fr *Framer) Parse(raw []byte) (Msg, error) {
 			return Msg{}, err
 		}
 
+		if fr.Version == MTProto2 {
+			var expectedMsgKey [16]byte
+			computeMsgKey2(auth.Key, decrypted, expectedMsgKey[:], fr.Server)
+			if 1 != subtle.ConstantTimeCompare(msgKey[:], expectedMsgKey[:]) {
+				return Msg{}, ErrMsgKeyMismatch
+			}
+		}
+
 		r.Reset(decrypted)
 		var salt [8]byte
 		var sessid [8]byte
@@ -166,6 +304,20 @@ func (fr *Framer) Parse(raw []byte) (Msg, error) {
 			return Msg{}, r.Err()
 		}
 
+		if fr.Version == MTProto2 {
+			pad := len(decrypted) - 32 - msgLen
+			if pad < minPadding2 || pad > maxPadding2 {
+				return Msg{}, ErrInvalidPadding
+			}
+		}
+
+		if fr.Server {
+			auth.SessionID = sessid
+			auth.ServerSalt = salt
+		} else if sessid != auth.SessionID {
+			return Msg{}, ErrSessionIDMismatch
+		}
+
 		// log.Printf("Received: authKeyID=%x msgID=%v seqNo=%v payload=(%d) %x", authKeyID, msgID, seqNo, len(payload), payload)
 
 		var typ MsgType
@@ -175,7 +327,7 @@ func (fr *Framer) Parse(raw []byte) (Msg, error) {
 			typ = ContentMsg
 		}
 
-		return Msg{payload, typ, msgID}, nil
+		return Msg{payload, typ, msgID, seqNo}, nil
 	}
 }
 
@@ -227,3 +379,63 @@ func deriveAESKey(authKey, msgKey []byte, key, iv []byte, isClient bool) {
 	copy(iv[20:24], c[16:16+4])
 	copy(iv[24:32], d[0:8])
 }
+
+// computeMsgKey2 computes MTProto 2.0 msg_key of the given plaintext (including padding).
+func computeMsgKey2(authKey, plaintext []byte, msgKey []byte, isClient bool) {
+	if len(authKey) != 256 {
+		panic("invalid auth key len")
+	}
+
+	var x int
+	if isClient {
+		x = 0
+	} else {
+		x = 8
+	}
+
+	// msg_key_large = SHA256(substr(auth_key, 88+x, 32) + plaintext + random_padding)
+	h := sha256.New()
+	h.Write(authKey[88+x : 88+x+32])
+	h.Write(plaintext)
+	large := h.Sum(nil)
+
+	// msg_key = substr(msg_key_large, 8, 16)
+	copy(msgKey, large[8:24])
+}
+
+func deriveAESKey2(authKey, msgKey []byte, key, iv []byte, isClient bool) {
+	if len(authKey) != 256 {
+		panic("invalid auth key len")
+	}
+	if len(msgKey) != 16 {
+		panic("invalid msg key len")
+	}
+
+	var x int
+	if isClient {
+		x = 0
+	} else {
+		x = 8
+	}
+	var src [52]byte
+
+	// sha256_a = SHA256(msg_key + substr(auth_key, x, 36))
+	copy(src[0:16], msgKey)
+	copy(src[16:52], authKey[x:x+36])
+	a := sha256.Sum256(src[:])
+
+	// sha256_b = SHA256(substr(auth_key, 40+x, 36) + msg_key)
+	copy(src[0:36], authKey[40+x:40+x+36])
+	copy(src[36:52], msgKey)
+	b := sha256.Sum256(src[:])
+
+	// aes_key = substr(sha256_a, 0, 8) + substr(sha256_b, 8, 16) + substr(sha256_a, 24, 8)
+	copy(key[0:8], a[0:8])
+	copy(key[8:24], b[8:24])
+	copy(key[24:32], a[24:32])
+
+	// aes_iv = substr(sha256_b, 0, 8) + substr(sha256_a, 8, 16) + substr(sha256_b, 24, 8)
+	copy(iv[0:8], b[0:8])
+	copy(iv[8:24], a[8:24])
+	copy(iv[24:32], b[24:32])
+}
@@ -0,0 +1,96 @@
+package mtproto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+)
+
+// length, seqno and CRC32 fields of the full TCP framing
+const fullFramingOverhead = 4 + 4 + 4
+
+// IntegrityError is returned when a packet received over the full TCP framing
+// has a wrong checksum or sequence number.
+type IntegrityError struct {
+	Field    string
+	Expected uint32
+	Actual   uint32
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("corrupted TCP packet: %s is %08x, expected %08x", e.Field, e.Actual, e.Expected)
+}
+
+func readFullTCPMessageLen(r io.Reader) (int, error) {
+	n, err := binints.ReadUint32LE(r)
+	if err != nil {
+		return -1, err
+	}
+	if n < fullFramingOverhead || n%4 != 0 || n > 1<<31 {
+		return -1, errors.New("invalid full TCP message length")
+	}
+	// the length field covers itself
+	return int(n) - 4, nil
+}
+
+func formatFullTCPMessage(data []byte, seqNo uint32) []byte {
+	var buf bytes.Buffer
+
+	if len(data) == 0 {
+		panic("Cannot send empty message")
+	}
+
+	binints.WriteUint32LE(&buf, uint32(len(data)+fullFramingOverhead))
+	binints.WriteUint32LE(&buf, seqNo)
+	buf.Write(data)
+	binints.WriteUint32LE(&buf, crc32.ChecksumIEEE(buf.Bytes()))
+
+	return buf.Bytes()
+}
+
+type fullCodec struct {
+	sendSeqNo uint32
+	recvSeqNo uint32
+}
+
+func (c *fullCodec) Tag() []byte {
+	return nil
+}
+
+func (c *fullCodec) ObfuscatedTag() []byte {
+	return nil
+}
+
+func (c *fullCodec) Format(data []byte) ([]byte, error) {
+	msg := formatFullTCPMessage(data, c.sendSeqNo)
+	c.sendSeqNo++
+	return msg, nil
+}
+
+func (c *fullCodec) Read(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
+	body, err := readTCPMessage(r, readFullTCPMessageLen, maxMsgLen+fullFramingOverhead-4, firstByteTimeout, msgTimeout)
+	if err != nil || body == nil {
+		return body, err
+	}
+
+	n := len(body) - 4
+	var lenbuf [4]byte
+	binints.EncodeUint32LE(uint32(len(body)+4), lenbuf[:])
+	crc := crc32.Update(crc32.ChecksumIEEE(lenbuf[:]), crc32.IEEETable, body[:n])
+	if expected := binints.DecodeUint32LE(body[n:]); crc != expected {
+		return nil, &IntegrityError{"crc32", expected, crc}
+	}
+
+	seqNo := binints.DecodeUint32LE(body[0:4])
+	if seqNo != c.recvSeqNo {
+		return nil, &IntegrityError{"seqno", c.recvSeqNo, seqNo}
+	}
+	c.recvSeqNo++
+
+	return body[4:n], nil
+}
@@ -12,6 +12,7 @@ import (
 const (
 	TagResPQ                   uint32 = 0x05162463
 	TagPQInnerData                    = 0x83c95aec
+	TagPQInnerDataTemp                = 0x3c6a84d4
 	TagServerDHParamsFail             = 0x79cb045d
 	TagServerDHParamsOK               = 0xd0e8075c
 	TagServerDHInnerData              = 0xb5890dba
@@ -35,6 +36,7 @@ const (
 	TagMsgCopy                        = 0xe06046b2
 	TagGzipPacked                     = 0x3072cfa1
 	TagMsgsAck                        = 0x62d6b459
+	TagBindAuthKeyInner               = 0x75a3f765
 	TagBadMsgNotification             = 0xa7eff811
 	TagBadServerSalt                  = 0xedab447b
 	TagMsgResendReq                   = 0x7d861a08
@@ -831,6 +833,7 @@ const (
 var combOrigins = map[uint32]SchemaOrigin{
 	TagResPQ:                                  SchemaOriginMTProto,
 	TagPQInnerData:                            SchemaOriginMTProto,
+	TagPQInnerDataTemp:                        SchemaOriginMTProto,
 	TagServerDHParamsFail:                     SchemaOriginMTProto,
 	TagServerDHParamsOK:                       SchemaOriginMTProto,
 	TagServerDHInnerData:                      SchemaOriginMTProto,
@@ -854,6 +857,7 @@ var combOrigins = map[uint32]SchemaOrigin{
 	TagMsgCopy:                                SchemaOriginMTProto,
 	TagGzipPacked:                             SchemaOriginMTProto,
 	TagMsgsAck:                                SchemaOriginMTProto,
+	TagBindAuthKeyInner:                       SchemaOriginMTProto,
 	TagBadMsgNotification:                     SchemaOriginMTProto,
 	TagBadServerSalt:                          SchemaOriginMTProto,
 	TagMsgResendReq:                           SchemaOriginMTProto,
@@ -1333,302 +1337,302 @@ var combOrigins = map[uint32]SchemaOrigin{
 	TagMaskCoords:                             SchemaOriginTelegram,
 	TagInputStickeredMediaPhoto:               SchemaOriginTelegram,
 	TagInputStickeredMediaDocument:            SchemaOriginTelegram,
-	TagGame:                             SchemaOriginTelegram,
-	TagInputGameID:                      SchemaOriginTelegram,
-	TagInputGameShortName:               SchemaOriginTelegram,
-	TagHighScore:                        SchemaOriginTelegram,
-	TagMessagesHighScores:               SchemaOriginTelegram,
-	TagTextEmpty:                        SchemaOriginTelegram,
-	TagTextPlain:                        SchemaOriginTelegram,
-	TagTextBold:                         SchemaOriginTelegram,
-	TagTextItalic:                       SchemaOriginTelegram,
-	TagTextUnderline:                    SchemaOriginTelegram,
-	TagTextStrike:                       SchemaOriginTelegram,
-	TagTextFixed:                        SchemaOriginTelegram,
-	TagTextURL:                          SchemaOriginTelegram,
-	TagTextEmail:                        SchemaOriginTelegram,
-	TagTextConcat:                       SchemaOriginTelegram,
-	TagPageBlockUnsupported:             SchemaOriginTelegram,
-	TagPageBlockTitle:                   SchemaOriginTelegram,
-	TagPageBlockSubtitle:                SchemaOriginTelegram,
-	TagPageBlockAuthorDate:              SchemaOriginTelegram,
-	TagPageBlockHeader:                  SchemaOriginTelegram,
-	TagPageBlockSubheader:               SchemaOriginTelegram,
-	TagPageBlockParagraph:               SchemaOriginTelegram,
-	TagPageBlockPreformatted:            SchemaOriginTelegram,
-	TagPageBlockFooter:                  SchemaOriginTelegram,
-	TagPageBlockDivider:                 SchemaOriginTelegram,
-	TagPageBlockAnchor:                  SchemaOriginTelegram,
-	TagPageBlockList:                    SchemaOriginTelegram,
-	TagPageBlockBlockquote:              SchemaOriginTelegram,
-	TagPageBlockPullquote:               SchemaOriginTelegram,
-	TagPageBlockPhoto:                   SchemaOriginTelegram,
-	TagPageBlockVideo:                   SchemaOriginTelegram,
-	TagPageBlockCover:                   SchemaOriginTelegram,
-	TagPageBlockEmbed:                   SchemaOriginTelegram,
-	TagPageBlockEmbedPost:               SchemaOriginTelegram,
-	TagPageBlockCollage:                 SchemaOriginTelegram,
-	TagPageBlockSlideshow:               SchemaOriginTelegram,
-	TagPagePart:                         SchemaOriginTelegram,
-	TagPageFull:                         SchemaOriginTelegram,
-	TagPhoneCallDiscardReasonMissed:     SchemaOriginTelegram,
-	TagPhoneCallDiscardReasonDisconnect: SchemaOriginTelegram,
-	TagPhoneCallDiscardReasonHangup:     SchemaOriginTelegram,
-	TagPhoneCallDiscardReasonBusy:       SchemaOriginTelegram,
-	TagDataJSON:                         SchemaOriginTelegram,
-	TagLabeledPrice:                     SchemaOriginTelegram,
-	TagInvoice:                          SchemaOriginTelegram,
-	TagPaymentCharge:                    SchemaOriginTelegram,
-	TagPostAddress:                      SchemaOriginTelegram,
-	TagPaymentRequestedInfo:             SchemaOriginTelegram,
-	TagPaymentSavedCredentialsCard:      SchemaOriginTelegram,
-	TagWebDocument:                      SchemaOriginTelegram,
-	TagInputWebDocument:                 SchemaOriginTelegram,
-	TagInputWebFileLocation:             SchemaOriginTelegram,
-	TagUploadWebFile:                    SchemaOriginTelegram,
-	TagPaymentsPaymentForm:              SchemaOriginTelegram,
-	TagPaymentsValidatedRequestedInfo:   SchemaOriginTelegram,
-	TagPaymentsPaymentResult:            SchemaOriginTelegram,
-	TagPaymentsPaymentVerficationNeeded: SchemaOriginTelegram,
-	TagPaymentsPaymentReceipt:           SchemaOriginTelegram,
-	TagPaymentsSavedInfo:                SchemaOriginTelegram,
-	TagInputPaymentCredentialsSaved:     SchemaOriginTelegram,
-	TagInputPaymentCredentials:          SchemaOriginTelegram,
-	TagAccountTmpPassword:               SchemaOriginTelegram,
-	TagShippingOption:                   SchemaOriginTelegram,
-	TagInputPhoneCall:                   SchemaOriginTelegram,
-	TagPhoneCallEmpty:                   SchemaOriginTelegram,
-	TagPhoneCallWaiting:                 SchemaOriginTelegram,
-	TagPhoneCallRequested:               SchemaOriginTelegram,
-	TagPhoneCallAccepted:                SchemaOriginTelegram,
-	TagPhoneCall:                        SchemaOriginTelegram,
-	TagPhoneCallDiscarded:               SchemaOriginTelegram,
-	TagPhoneConnection:                  SchemaOriginTelegram,
-	TagPhoneCallProtocol:                SchemaOriginTelegram,
-	TagPhonePhoneCall:                   SchemaOriginTelegram,
-	TagInvokeAfterMsg:                   SchemaOriginTelegram,
-	TagInvokeAfterMsgs:                  SchemaOriginTelegram,
-	TagInitConnection:                   SchemaOriginTelegram,
-	TagInvokeWithLayer:                  SchemaOriginTelegram,
-	TagInvokeWithoutUpdates:             SchemaOriginTelegram,
-	TagAuthCheckPhone:                   SchemaOriginTelegram,
-	TagAuthSendCode:                     SchemaOriginTelegram,
-	TagAuthSignUp:                       SchemaOriginTelegram,
-	TagAuthSignIn:                       SchemaOriginTelegram,
-	TagAuthLogOut:                       SchemaOriginTelegram,
-	TagAuthResetAuthorizations:          SchemaOriginTelegram,
-	TagAuthSendInvites:                  SchemaOriginTelegram,
-	TagAuthExportAuthorization:          SchemaOriginTelegram,
-	TagAuthImportAuthorization:          SchemaOriginTelegram,
-	TagAuthBindTempAuthKey:              SchemaOriginTelegram,
-	TagAuthImportBotAuthorization:       SchemaOriginTelegram,
-	TagAuthCheckPassword:                SchemaOriginTelegram,
-	TagAuthRequestPasswordRecovery:      SchemaOriginTelegram,
-	TagAuthRecoverPassword:              SchemaOriginTelegram,
-	TagAuthResendCode:                   SchemaOriginTelegram,
-	TagAuthCancelCode:                   SchemaOriginTelegram,
-	TagAuthDropTempAuthKeys:             SchemaOriginTelegram,
-	TagAccountRegisterDevice:            SchemaOriginTelegram,
-	TagAccountUnregisterDevice:          SchemaOriginTelegram,
-	TagAccountUpdateNotifySettings:      SchemaOriginTelegram,
-	TagAccountGetNotifySettings:         SchemaOriginTelegram,
-	TagAccountResetNotifySettings:       SchemaOriginTelegram,
-	TagAccountUpdateProfile:             SchemaOriginTelegram,
-	TagAccountUpdateStatus:              SchemaOriginTelegram,
-	TagAccountGetWallPapers:             SchemaOriginTelegram,
-	TagAccountReportPeer:                SchemaOriginTelegram,
-	TagAccountCheckUsername:             SchemaOriginTelegram,
-	TagAccountUpdateUsername:            SchemaOriginTelegram,
-	TagAccountGetPrivacy:                SchemaOriginTelegram,
-	TagAccountSetPrivacy:                SchemaOriginTelegram,
-	TagAccountDeleteAccount:             SchemaOriginTelegram,
-	TagAccountGetAccountTTL:             SchemaOriginTelegram,
-	TagAccountSetAccountTTL:             SchemaOriginTelegram,
-	TagAccountSendChangePhoneCode:       SchemaOriginTelegram,
-	TagAccountChangePhone:               SchemaOriginTelegram,
-	TagAccountUpdateDeviceLocked:        SchemaOriginTelegram,
-	TagAccountGetAuthorizations:         SchemaOriginTelegram,
-	TagAccountResetAuthorization:        SchemaOriginTelegram,
-	TagAccountGetPassword:               SchemaOriginTelegram,
-	TagAccountGetPasswordSettings:       SchemaOriginTelegram,
-	TagAccountUpdatePasswordSettings:    SchemaOriginTelegram,
-	TagAccountSendConfirmPhoneCode:      SchemaOriginTelegram,
-	TagAccountConfirmPhone:              SchemaOriginTelegram,
-	TagAccountGetTmpPassword:            SchemaOriginTelegram,
-	TagUsersGetUsers:                    SchemaOriginTelegram,
-	TagUsersGetFullUser:                 SchemaOriginTelegram,
-	TagContactsGetStatuses:              SchemaOriginTelegram,
-	TagContactsGetContacts:              SchemaOriginTelegram,
-	TagContactsImportContacts:           SchemaOriginTelegram,
-	TagContactsDeleteContact:            SchemaOriginTelegram,
-	TagContactsDeleteContacts:           SchemaOriginTelegram,
-	TagContactsBlock:                    SchemaOriginTelegram,
-	TagContactsUnblock:                  SchemaOriginTelegram,
-	TagContactsGetBlocked:               SchemaOriginTelegram,
-	TagContactsExportCard:               SchemaOriginTelegram,
-	TagContactsImportCard:               SchemaOriginTelegram,
-	TagContactsSearch:                   SchemaOriginTelegram,
-	TagContactsResolveUsername:          SchemaOriginTelegram,
-	TagContactsGetTopPeers:              SchemaOriginTelegram,
-	TagContactsResetTopPeerRating:       SchemaOriginTelegram,
-	TagMessagesGetMessages:              SchemaOriginTelegram,
-	TagMessagesGetDialogs:               SchemaOriginTelegram,
-	TagMessagesGetHistory:               SchemaOriginTelegram,
-	TagMessagesSearch:                   SchemaOriginTelegram,
-	TagMessagesReadHistory:              SchemaOriginTelegram,
-	TagMessagesDeleteHistory:            SchemaOriginTelegram,
-	TagMessagesDeleteMessages:           SchemaOriginTelegram,
-	TagMessagesReceivedMessages:         SchemaOriginTelegram,
-	TagMessagesSetTyping:                SchemaOriginTelegram,
-	TagMessagesSendMessage:              SchemaOriginTelegram,
-	TagMessagesSendMedia:                SchemaOriginTelegram,
-	TagMessagesForwardMessages:          SchemaOriginTelegram,
-	TagMessagesReportSpam:               SchemaOriginTelegram,
-	TagMessagesHideReportSpam:           SchemaOriginTelegram,
-	TagMessagesGetPeerSettings:          SchemaOriginTelegram,
-	TagMessagesGetChats:                 SchemaOriginTelegram,
-	TagMessagesGetFullChat:              SchemaOriginTelegram,
-	TagMessagesEditChatTitle:            SchemaOriginTelegram,
-	TagMessagesEditChatPhoto:            SchemaOriginTelegram,
-	TagMessagesAddChatUser:              SchemaOriginTelegram,
-	TagMessagesDeleteChatUser:           SchemaOriginTelegram,
-	TagMessagesCreateChat:               SchemaOriginTelegram,
-	TagMessagesForwardMessage:           SchemaOriginTelegram,
-	TagMessagesGetDHConfig:              SchemaOriginTelegram,
-	TagMessagesRequestEncryption:        SchemaOriginTelegram,
-	TagMessagesAcceptEncryption:         SchemaOriginTelegram,
-	TagMessagesDiscardEncryption:        SchemaOriginTelegram,
-	TagMessagesSetEncryptedTyping:       SchemaOriginTelegram,
-	TagMessagesReadEncryptedHistory:     SchemaOriginTelegram,
-	TagMessagesSendEncrypted:            SchemaOriginTelegram,
-	TagMessagesSendEncryptedFile:        SchemaOriginTelegram,
-	TagMessagesSendEncryptedService:     SchemaOriginTelegram,
-	TagMessagesReceivedQueue:            SchemaOriginTelegram,
-	TagMessagesReportEncryptedSpam:      SchemaOriginTelegram,
-	TagMessagesReadMessageContents:      SchemaOriginTelegram,
-	TagMessagesGetAllStickers:           SchemaOriginTelegram,
-	TagMessagesGetWebPagePreview:        SchemaOriginTelegram,
-	TagMessagesExportChatInvite:         SchemaOriginTelegram,
-	TagMessagesCheckChatInvite:          SchemaOriginTelegram,
-	TagMessagesImportChatInvite:         SchemaOriginTelegram,
-	TagMessagesGetStickerSet:            SchemaOriginTelegram,
-	TagMessagesInstallStickerSet:        SchemaOriginTelegram,
-	TagMessagesUninstallStickerSet:      SchemaOriginTelegram,
-	TagMessagesStartBot:                 SchemaOriginTelegram,
-	TagMessagesGetMessagesViews:         SchemaOriginTelegram,
-	TagMessagesToggleChatAdmins:         SchemaOriginTelegram,
-	TagMessagesEditChatAdmin:            SchemaOriginTelegram,
-	TagMessagesMigrateChat:              SchemaOriginTelegram,
-	TagMessagesSearchGlobal:             SchemaOriginTelegram,
-	TagMessagesReorderStickerSets:       SchemaOriginTelegram,
-	TagMessagesGetDocumentByHash:        SchemaOriginTelegram,
-	TagMessagesSearchGifs:               SchemaOriginTelegram,
-	TagMessagesGetSavedGifs:             SchemaOriginTelegram,
-	TagMessagesSaveGif:                  SchemaOriginTelegram,
-	TagMessagesGetInlineBotResults:      SchemaOriginTelegram,
-	TagMessagesSetInlineBotResults:      SchemaOriginTelegram,
-	TagMessagesSendInlineBotResult:      SchemaOriginTelegram,
-	TagMessagesGetMessageEditData:       SchemaOriginTelegram,
-	TagMessagesEditMessage:              SchemaOriginTelegram,
-	TagMessagesEditInlineBotMessage:     SchemaOriginTelegram,
-	TagMessagesGetBotCallbackAnswer:     SchemaOriginTelegram,
-	TagMessagesSetBotCallbackAnswer:     SchemaOriginTelegram,
-	TagMessagesGetPeerDialogs:           SchemaOriginTelegram,
-	TagMessagesSaveDraft:                SchemaOriginTelegram,
-	TagMessagesGetAllDrafts:             SchemaOriginTelegram,
-	TagMessagesGetFeaturedStickers:      SchemaOriginTelegram,
-	TagMessagesReadFeaturedStickers:     SchemaOriginTelegram,
-	TagMessagesGetRecentStickers:        SchemaOriginTelegram,
-	TagMessagesSaveRecentSticker:        SchemaOriginTelegram,
-	TagMessagesClearRecentStickers:      SchemaOriginTelegram,
-	TagMessagesGetArchivedStickers:      SchemaOriginTelegram,
-	TagMessagesGetMaskStickers:          SchemaOriginTelegram,
-	TagMessagesGetAttachedStickers:      SchemaOriginTelegram,
-	TagMessagesSetGameScore:             SchemaOriginTelegram,
-	TagMessagesSetInlineGameScore:       SchemaOriginTelegram,
-	TagMessagesGetGameHighScores:        SchemaOriginTelegram,
-	TagMessagesGetInlineGameHighScores:  SchemaOriginTelegram,
-	TagMessagesGetCommonChats:           SchemaOriginTelegram,
-	TagMessagesGetAllChats:              SchemaOriginTelegram,
-	TagMessagesGetWebPage:               SchemaOriginTelegram,
-	TagMessagesToggleDialogPin:          SchemaOriginTelegram,
-	TagMessagesReorderPinnedDialogs:     SchemaOriginTelegram,
-	TagMessagesGetPinnedDialogs:         SchemaOriginTelegram,
-	TagMessagesSetBotShippingResults:    SchemaOriginTelegram,
-	TagMessagesSetBotPrecheckoutResults: SchemaOriginTelegram,
-	TagUpdatesGetState:                  SchemaOriginTelegram,
-	TagUpdatesGetDifference:             SchemaOriginTelegram,
-	TagUpdatesGetChannelDifference:      SchemaOriginTelegram,
-	TagPhotosUpdateProfilePhoto:         SchemaOriginTelegram,
-	TagPhotosUploadProfilePhoto:         SchemaOriginTelegram,
-	TagPhotosDeletePhotos:               SchemaOriginTelegram,
-	TagPhotosGetUserPhotos:              SchemaOriginTelegram,
-	TagUploadSaveFilePart:               SchemaOriginTelegram,
-	TagUploadGetFile:                    SchemaOriginTelegram,
-	TagUploadSaveBigFilePart:            SchemaOriginTelegram,
-	TagUploadGetWebFile:                 SchemaOriginTelegram,
-	TagHelpGetConfig:                    SchemaOriginTelegram,
-	TagHelpGetNearestDC:                 SchemaOriginTelegram,
-	TagHelpGetAppUpdate:                 SchemaOriginTelegram,
-	TagHelpSaveAppLog:                   SchemaOriginTelegram,
-	TagHelpGetInviteText:                SchemaOriginTelegram,
-	TagHelpGetSupport:                   SchemaOriginTelegram,
-	TagHelpGetAppChangelog:              SchemaOriginTelegram,
-	TagHelpGetTermsOfService:            SchemaOriginTelegram,
-	TagHelpSetBotUpdatesStatus:          SchemaOriginTelegram,
-	TagChannelsReadHistory:              SchemaOriginTelegram,
-	TagChannelsDeleteMessages:           SchemaOriginTelegram,
-	TagChannelsDeleteUserHistory:        SchemaOriginTelegram,
-	TagChannelsReportSpam:               SchemaOriginTelegram,
-	TagChannelsGetMessages:              SchemaOriginTelegram,
-	TagChannelsGetParticipants:          SchemaOriginTelegram,
-	TagChannelsGetParticipant:           SchemaOriginTelegram,
-	TagChannelsGetChannels:              SchemaOriginTelegram,
-	TagChannelsGetFullChannel:           SchemaOriginTelegram,
-	TagChannelsCreateChannel:            SchemaOriginTelegram,
-	TagChannelsEditAbout:                SchemaOriginTelegram,
-	TagChannelsEditAdmin:                SchemaOriginTelegram,
-	TagChannelsEditTitle:                SchemaOriginTelegram,
-	TagChannelsEditPhoto:                SchemaOriginTelegram,
-	TagChannelsCheckUsername:            SchemaOriginTelegram,
-	TagChannelsUpdateUsername:           SchemaOriginTelegram,
-	TagChannelsJoinChannel:              SchemaOriginTelegram,
-	TagChannelsLeaveChannel:             SchemaOriginTelegram,
-	TagChannelsInviteToChannel:          SchemaOriginTelegram,
-	TagChannelsKickFromChannel:          SchemaOriginTelegram,
-	TagChannelsExportInvite:             SchemaOriginTelegram,
-	TagChannelsDeleteChannel:            SchemaOriginTelegram,
-	TagChannelsToggleInvites:            SchemaOriginTelegram,
-	TagChannelsExportMessageLink:        SchemaOriginTelegram,
-	TagChannelsToggleSignatures:         SchemaOriginTelegram,
-	TagChannelsUpdatePinnedMessage:      SchemaOriginTelegram,
-	TagChannelsGetAdminedPublicChannels: SchemaOriginTelegram,
-	TagBotsSendCustomRequest:            SchemaOriginTelegram,
-	TagBotsAnswerWebhookJSONQuery:       SchemaOriginTelegram,
-	TagPaymentsGetPaymentForm:           SchemaOriginTelegram,
-	TagPaymentsGetPaymentReceipt:        SchemaOriginTelegram,
-	TagPaymentsValidateRequestedInfo:    SchemaOriginTelegram,
-	TagPaymentsSendPaymentForm:          SchemaOriginTelegram,
-	TagPaymentsGetSavedInfo:             SchemaOriginTelegram,
-	TagPaymentsClearSavedInfo:           SchemaOriginTelegram,
-	TagPhoneGetCallConfig:               SchemaOriginTelegram,
-	TagPhoneRequestCall:                 SchemaOriginTelegram,
-	TagPhoneAcceptCall:                  SchemaOriginTelegram,
-	TagPhoneConfirmCall:                 SchemaOriginTelegram,
-	TagPhoneReceivedCall:                SchemaOriginTelegram,
-	TagPhoneDiscardCall:                 SchemaOriginTelegram,
-	TagPhoneSetCallRating:               SchemaOriginTelegram,
-	TagPhoneSaveCallDebug:               SchemaOriginTelegram,
-	TagTrue:                             SchemaOriginBuiltin,
-	TagBoolFalse:                        SchemaOriginBuiltin,
-	TagBoolTrue:                         SchemaOriginBuiltin,
-	TagString:                           SchemaOriginBuiltin,
-	TagInt:                              SchemaOriginBuiltin,
-	TagLong:                             SchemaOriginBuiltin,
-	TagDouble:                           SchemaOriginBuiltin,
-	TagBytes:                            SchemaOriginBuiltin,
-	TagObject:                           SchemaOriginBuiltin,
-	TagVector:                           SchemaOriginBuiltin,
+	TagGame:                                   SchemaOriginTelegram,
+	TagInputGameID:                            SchemaOriginTelegram,
+	TagInputGameShortName:                     SchemaOriginTelegram,
+	TagHighScore:                              SchemaOriginTelegram,
+	TagMessagesHighScores:                     SchemaOriginTelegram,
+	TagTextEmpty:                              SchemaOriginTelegram,
+	TagTextPlain:                              SchemaOriginTelegram,
+	TagTextBold:                               SchemaOriginTelegram,
+	TagTextItalic:                             SchemaOriginTelegram,
+	TagTextUnderline:                          SchemaOriginTelegram,
+	TagTextStrike:                             SchemaOriginTelegram,
+	TagTextFixed:                              SchemaOriginTelegram,
+	TagTextURL:                                SchemaOriginTelegram,
+	TagTextEmail:                              SchemaOriginTelegram,
+	TagTextConcat:                             SchemaOriginTelegram,
+	TagPageBlockUnsupported:                   SchemaOriginTelegram,
+	TagPageBlockTitle:                         SchemaOriginTelegram,
+	TagPageBlockSubtitle:                      SchemaOriginTelegram,
+	TagPageBlockAuthorDate:                    SchemaOriginTelegram,
+	TagPageBlockHeader:                        SchemaOriginTelegram,
+	TagPageBlockSubheader:                     SchemaOriginTelegram,
+	TagPageBlockParagraph:                     SchemaOriginTelegram,
+	TagPageBlockPreformatted:                  SchemaOriginTelegram,
+	TagPageBlockFooter:                        SchemaOriginTelegram,
+	TagPageBlockDivider:                       SchemaOriginTelegram,
+	TagPageBlockAnchor:                        SchemaOriginTelegram,
+	TagPageBlockList:                          SchemaOriginTelegram,
+	TagPageBlockBlockquote:                    SchemaOriginTelegram,
+	TagPageBlockPullquote:                     SchemaOriginTelegram,
+	TagPageBlockPhoto:                         SchemaOriginTelegram,
+	TagPageBlockVideo:                         SchemaOriginTelegram,
+	TagPageBlockCover:                         SchemaOriginTelegram,
+	TagPageBlockEmbed:                         SchemaOriginTelegram,
+	TagPageBlockEmbedPost:                     SchemaOriginTelegram,
+	TagPageBlockCollage:                       SchemaOriginTelegram,
+	TagPageBlockSlideshow:                     SchemaOriginTelegram,
+	TagPagePart:                               SchemaOriginTelegram,
+	TagPageFull:                               SchemaOriginTelegram,
+	TagPhoneCallDiscardReasonMissed:           SchemaOriginTelegram,
+	TagPhoneCallDiscardReasonDisconnect:       SchemaOriginTelegram,
+	TagPhoneCallDiscardReasonHangup:           SchemaOriginTelegram,
+	TagPhoneCallDiscardReasonBusy:             SchemaOriginTelegram,
+	TagDataJSON:                               SchemaOriginTelegram,
+	TagLabeledPrice:                           SchemaOriginTelegram,
+	TagInvoice:                                SchemaOriginTelegram,
+	TagPaymentCharge:                          SchemaOriginTelegram,
+	TagPostAddress:                            SchemaOriginTelegram,
+	TagPaymentRequestedInfo:                   SchemaOriginTelegram,
+	TagPaymentSavedCredentialsCard:            SchemaOriginTelegram,
+	TagWebDocument:                            SchemaOriginTelegram,
+	TagInputWebDocument:                       SchemaOriginTelegram,
+	TagInputWebFileLocation:                   SchemaOriginTelegram,
+	TagUploadWebFile:                          SchemaOriginTelegram,
+	TagPaymentsPaymentForm:                    SchemaOriginTelegram,
+	TagPaymentsValidatedRequestedInfo:         SchemaOriginTelegram,
+	TagPaymentsPaymentResult:                  SchemaOriginTelegram,
+	TagPaymentsPaymentVerficationNeeded:       SchemaOriginTelegram,
+	TagPaymentsPaymentReceipt:                 SchemaOriginTelegram,
+	TagPaymentsSavedInfo:                      SchemaOriginTelegram,
+	TagInputPaymentCredentialsSaved:           SchemaOriginTelegram,
+	TagInputPaymentCredentials:                SchemaOriginTelegram,
+	TagAccountTmpPassword:                     SchemaOriginTelegram,
+	TagShippingOption:                         SchemaOriginTelegram,
+	TagInputPhoneCall:                         SchemaOriginTelegram,
+	TagPhoneCallEmpty:                         SchemaOriginTelegram,
+	TagPhoneCallWaiting:                       SchemaOriginTelegram,
+	TagPhoneCallRequested:                     SchemaOriginTelegram,
+	TagPhoneCallAccepted:                      SchemaOriginTelegram,
+	TagPhoneCall:                              SchemaOriginTelegram,
+	TagPhoneCallDiscarded:                     SchemaOriginTelegram,
+	TagPhoneConnection:                        SchemaOriginTelegram,
+	TagPhoneCallProtocol:                      SchemaOriginTelegram,
+	TagPhonePhoneCall:                         SchemaOriginTelegram,
+	TagInvokeAfterMsg:                         SchemaOriginTelegram,
+	TagInvokeAfterMsgs:                        SchemaOriginTelegram,
+	TagInitConnection:                         SchemaOriginTelegram,
+	TagInvokeWithLayer:                        SchemaOriginTelegram,
+	TagInvokeWithoutUpdates:                   SchemaOriginTelegram,
+	TagAuthCheckPhone:                         SchemaOriginTelegram,
+	TagAuthSendCode:                           SchemaOriginTelegram,
+	TagAuthSignUp:                             SchemaOriginTelegram,
+	TagAuthSignIn:                             SchemaOriginTelegram,
+	TagAuthLogOut:                             SchemaOriginTelegram,
+	TagAuthResetAuthorizations:                SchemaOriginTelegram,
+	TagAuthSendInvites:                        SchemaOriginTelegram,
+	TagAuthExportAuthorization:                SchemaOriginTelegram,
+	TagAuthImportAuthorization:                SchemaOriginTelegram,
+	TagAuthBindTempAuthKey:                    SchemaOriginTelegram,
+	TagAuthImportBotAuthorization:             SchemaOriginTelegram,
+	TagAuthCheckPassword:                      SchemaOriginTelegram,
+	TagAuthRequestPasswordRecovery:            SchemaOriginTelegram,
+	TagAuthRecoverPassword:                    SchemaOriginTelegram,
+	TagAuthResendCode:                         SchemaOriginTelegram,
+	TagAuthCancelCode:                         SchemaOriginTelegram,
+	TagAuthDropTempAuthKeys:                   SchemaOriginTelegram,
+	TagAccountRegisterDevice:                  SchemaOriginTelegram,
+	TagAccountUnregisterDevice:                SchemaOriginTelegram,
+	TagAccountUpdateNotifySettings:            SchemaOriginTelegram,
+	TagAccountGetNotifySettings:               SchemaOriginTelegram,
+	TagAccountResetNotifySettings:             SchemaOriginTelegram,
+	TagAccountUpdateProfile:                   SchemaOriginTelegram,
+	TagAccountUpdateStatus:                    SchemaOriginTelegram,
+	TagAccountGetWallPapers:                   SchemaOriginTelegram,
+	TagAccountReportPeer:                      SchemaOriginTelegram,
+	TagAccountCheckUsername:                   SchemaOriginTelegram,
+	TagAccountUpdateUsername:                  SchemaOriginTelegram,
+	TagAccountGetPrivacy:                      SchemaOriginTelegram,
+	TagAccountSetPrivacy:                      SchemaOriginTelegram,
+	TagAccountDeleteAccount:                   SchemaOriginTelegram,
+	TagAccountGetAccountTTL:                   SchemaOriginTelegram,
+	TagAccountSetAccountTTL:                   SchemaOriginTelegram,
+	TagAccountSendChangePhoneCode:             SchemaOriginTelegram,
+	TagAccountChangePhone:                     SchemaOriginTelegram,
+	TagAccountUpdateDeviceLocked:              SchemaOriginTelegram,
+	TagAccountGetAuthorizations:               SchemaOriginTelegram,
+	TagAccountResetAuthorization:              SchemaOriginTelegram,
+	TagAccountGetPassword:                     SchemaOriginTelegram,
+	TagAccountGetPasswordSettings:             SchemaOriginTelegram,
+	TagAccountUpdatePasswordSettings:          SchemaOriginTelegram,
+	TagAccountSendConfirmPhoneCode:            SchemaOriginTelegram,
+	TagAccountConfirmPhone:                    SchemaOriginTelegram,
+	TagAccountGetTmpPassword:                  SchemaOriginTelegram,
+	TagUsersGetUsers:                          SchemaOriginTelegram,
+	TagUsersGetFullUser:                       SchemaOriginTelegram,
+	TagContactsGetStatuses:                    SchemaOriginTelegram,
+	TagContactsGetContacts:                    SchemaOriginTelegram,
+	TagContactsImportContacts:                 SchemaOriginTelegram,
+	TagContactsDeleteContact:                  SchemaOriginTelegram,
+	TagContactsDeleteContacts:                 SchemaOriginTelegram,
+	TagContactsBlock:                          SchemaOriginTelegram,
+	TagContactsUnblock:                        SchemaOriginTelegram,
+	TagContactsGetBlocked:                     SchemaOriginTelegram,
+	TagContactsExportCard:                     SchemaOriginTelegram,
+	TagContactsImportCard:                     SchemaOriginTelegram,
+	TagContactsSearch:                         SchemaOriginTelegram,
+	TagContactsResolveUsername:                SchemaOriginTelegram,
+	TagContactsGetTopPeers:                    SchemaOriginTelegram,
+	TagContactsResetTopPeerRating:             SchemaOriginTelegram,
+	TagMessagesGetMessages:                    SchemaOriginTelegram,
+	TagMessagesGetDialogs:                     SchemaOriginTelegram,
+	TagMessagesGetHistory:                     SchemaOriginTelegram,
+	TagMessagesSearch:                         SchemaOriginTelegram,
+	TagMessagesReadHistory:                    SchemaOriginTelegram,
+	TagMessagesDeleteHistory:                  SchemaOriginTelegram,
+	TagMessagesDeleteMessages:                 SchemaOriginTelegram,
+	TagMessagesReceivedMessages:               SchemaOriginTelegram,
+	TagMessagesSetTyping:                      SchemaOriginTelegram,
+	TagMessagesSendMessage:                    SchemaOriginTelegram,
+	TagMessagesSendMedia:                      SchemaOriginTelegram,
+	TagMessagesForwardMessages:                SchemaOriginTelegram,
+	TagMessagesReportSpam:                     SchemaOriginTelegram,
+	TagMessagesHideReportSpam:                 SchemaOriginTelegram,
+	TagMessagesGetPeerSettings:                SchemaOriginTelegram,
+	TagMessagesGetChats:                       SchemaOriginTelegram,
+	TagMessagesGetFullChat:                    SchemaOriginTelegram,
+	TagMessagesEditChatTitle:                  SchemaOriginTelegram,
+	TagMessagesEditChatPhoto:                  SchemaOriginTelegram,
+	TagMessagesAddChatUser:                    SchemaOriginTelegram,
+	TagMessagesDeleteChatUser:                 SchemaOriginTelegram,
+	TagMessagesCreateChat:                     SchemaOriginTelegram,
+	TagMessagesForwardMessage:                 SchemaOriginTelegram,
+	TagMessagesGetDHConfig:                    SchemaOriginTelegram,
+	TagMessagesRequestEncryption:              SchemaOriginTelegram,
+	TagMessagesAcceptEncryption:               SchemaOriginTelegram,
+	TagMessagesDiscardEncryption:              SchemaOriginTelegram,
+	TagMessagesSetEncryptedTyping:             SchemaOriginTelegram,
+	TagMessagesReadEncryptedHistory:           SchemaOriginTelegram,
+	TagMessagesSendEncrypted:                  SchemaOriginTelegram,
+	TagMessagesSendEncryptedFile:              SchemaOriginTelegram,
+	TagMessagesSendEncryptedService:           SchemaOriginTelegram,
+	TagMessagesReceivedQueue:                  SchemaOriginTelegram,
+	TagMessagesReportEncryptedSpam:            SchemaOriginTelegram,
+	TagMessagesReadMessageContents:            SchemaOriginTelegram,
+	TagMessagesGetAllStickers:                 SchemaOriginTelegram,
+	TagMessagesGetWebPagePreview:              SchemaOriginTelegram,
+	TagMessagesExportChatInvite:               SchemaOriginTelegram,
+	TagMessagesCheckChatInvite:                SchemaOriginTelegram,
+	TagMessagesImportChatInvite:               SchemaOriginTelegram,
+	TagMessagesGetStickerSet:                  SchemaOriginTelegram,
+	TagMessagesInstallStickerSet:              SchemaOriginTelegram,
+	TagMessagesUninstallStickerSet:            SchemaOriginTelegram,
+	TagMessagesStartBot:                       SchemaOriginTelegram,
+	TagMessagesGetMessagesViews:               SchemaOriginTelegram,
+	TagMessagesToggleChatAdmins:               SchemaOriginTelegram,
+	TagMessagesEditChatAdmin:                  SchemaOriginTelegram,
+	TagMessagesMigrateChat:                    SchemaOriginTelegram,
+	TagMessagesSearchGlobal:                   SchemaOriginTelegram,
+	TagMessagesReorderStickerSets:             SchemaOriginTelegram,
+	TagMessagesGetDocumentByHash:              SchemaOriginTelegram,
+	TagMessagesSearchGifs:                     SchemaOriginTelegram,
+	TagMessagesGetSavedGifs:                   SchemaOriginTelegram,
+	TagMessagesSaveGif:                        SchemaOriginTelegram,
+	TagMessagesGetInlineBotResults:            SchemaOriginTelegram,
+	TagMessagesSetInlineBotResults:            SchemaOriginTelegram,
+	TagMessagesSendInlineBotResult:            SchemaOriginTelegram,
+	TagMessagesGetMessageEditData:             SchemaOriginTelegram,
+	TagMessagesEditMessage:                    SchemaOriginTelegram,
+	TagMessagesEditInlineBotMessage:           SchemaOriginTelegram,
+	TagMessagesGetBotCallbackAnswer:           SchemaOriginTelegram,
+	TagMessagesSetBotCallbackAnswer:           SchemaOriginTelegram,
+	TagMessagesGetPeerDialogs:                 SchemaOriginTelegram,
+	TagMessagesSaveDraft:                      SchemaOriginTelegram,
+	TagMessagesGetAllDrafts:                   SchemaOriginTelegram,
+	TagMessagesGetFeaturedStickers:            SchemaOriginTelegram,
+	TagMessagesReadFeaturedStickers:           SchemaOriginTelegram,
+	TagMessagesGetRecentStickers:              SchemaOriginTelegram,
+	TagMessagesSaveRecentSticker:              SchemaOriginTelegram,
+	TagMessagesClearRecentStickers:            SchemaOriginTelegram,
+	TagMessagesGetArchivedStickers:            SchemaOriginTelegram,
+	TagMessagesGetMaskStickers:                SchemaOriginTelegram,
+	TagMessagesGetAttachedStickers:            SchemaOriginTelegram,
+	TagMessagesSetGameScore:                   SchemaOriginTelegram,
+	TagMessagesSetInlineGameScore:             SchemaOriginTelegram,
+	TagMessagesGetGameHighScores:              SchemaOriginTelegram,
+	TagMessagesGetInlineGameHighScores:        SchemaOriginTelegram,
+	TagMessagesGetCommonChats:                 SchemaOriginTelegram,
+	TagMessagesGetAllChats:                    SchemaOriginTelegram,
+	TagMessagesGetWebPage:                     SchemaOriginTelegram,
+	TagMessagesToggleDialogPin:                SchemaOriginTelegram,
+	TagMessagesReorderPinnedDialogs:           SchemaOriginTelegram,
+	TagMessagesGetPinnedDialogs:               SchemaOriginTelegram,
+	TagMessagesSetBotShippingResults:          SchemaOriginTelegram,
+	TagMessagesSetBotPrecheckoutResults:       SchemaOriginTelegram,
+	TagUpdatesGetState:                        SchemaOriginTelegram,
+	TagUpdatesGetDifference:                   SchemaOriginTelegram,
+	TagUpdatesGetChannelDifference:            SchemaOriginTelegram,
+	TagPhotosUpdateProfilePhoto:               SchemaOriginTelegram,
+	TagPhotosUploadProfilePhoto:               SchemaOriginTelegram,
+	TagPhotosDeletePhotos:                     SchemaOriginTelegram,
+	TagPhotosGetUserPhotos:                    SchemaOriginTelegram,
+	TagUploadSaveFilePart:                     SchemaOriginTelegram,
+	TagUploadGetFile:                          SchemaOriginTelegram,
+	TagUploadSaveBigFilePart:                  SchemaOriginTelegram,
+	TagUploadGetWebFile:                       SchemaOriginTelegram,
+	TagHelpGetConfig:                          SchemaOriginTelegram,
+	TagHelpGetNearestDC:                       SchemaOriginTelegram,
+	TagHelpGetAppUpdate:                       SchemaOriginTelegram,
+	TagHelpSaveAppLog:                         SchemaOriginTelegram,
+	TagHelpGetInviteText:                      SchemaOriginTelegram,
+	TagHelpGetSupport:                         SchemaOriginTelegram,
+	TagHelpGetAppChangelog:                    SchemaOriginTelegram,
+	TagHelpGetTermsOfService:                  SchemaOriginTelegram,
+	TagHelpSetBotUpdatesStatus:                SchemaOriginTelegram,
+	TagChannelsReadHistory:                    SchemaOriginTelegram,
+	TagChannelsDeleteMessages:                 SchemaOriginTelegram,
+	TagChannelsDeleteUserHistory:              SchemaOriginTelegram,
+	TagChannelsReportSpam:                     SchemaOriginTelegram,
+	TagChannelsGetMessages:                    SchemaOriginTelegram,
+	TagChannelsGetParticipants:                SchemaOriginTelegram,
+	TagChannelsGetParticipant:                 SchemaOriginTelegram,
+	TagChannelsGetChannels:                    SchemaOriginTelegram,
+	TagChannelsGetFullChannel:                 SchemaOriginTelegram,
+	TagChannelsCreateChannel:                  SchemaOriginTelegram,
+	TagChannelsEditAbout:                      SchemaOriginTelegram,
+	TagChannelsEditAdmin:                      SchemaOriginTelegram,
+	TagChannelsEditTitle:                      SchemaOriginTelegram,
+	TagChannelsEditPhoto:                      SchemaOriginTelegram,
+	TagChannelsCheckUsername:                  SchemaOriginTelegram,
+	TagChannelsUpdateUsername:                 SchemaOriginTelegram,
+	TagChannelsJoinChannel:                    SchemaOriginTelegram,
+	TagChannelsLeaveChannel:                   SchemaOriginTelegram,
+	TagChannelsInviteToChannel:                SchemaOriginTelegram,
+	TagChannelsKickFromChannel:                SchemaOriginTelegram,
+	TagChannelsExportInvite:                   SchemaOriginTelegram,
+	TagChannelsDeleteChannel:                  SchemaOriginTelegram,
+	TagChannelsToggleInvites:                  SchemaOriginTelegram,
+	TagChannelsExportMessageLink:              SchemaOriginTelegram,
+	TagChannelsToggleSignatures:               SchemaOriginTelegram,
+	TagChannelsUpdatePinnedMessage:            SchemaOriginTelegram,
+	TagChannelsGetAdminedPublicChannels:       SchemaOriginTelegram,
+	TagBotsSendCustomRequest:                  SchemaOriginTelegram,
+	TagBotsAnswerWebhookJSONQuery:             SchemaOriginTelegram,
+	TagPaymentsGetPaymentForm:                 SchemaOriginTelegram,
+	TagPaymentsGetPaymentReceipt:              SchemaOriginTelegram,
+	TagPaymentsValidateRequestedInfo:          SchemaOriginTelegram,
+	TagPaymentsSendPaymentForm:                SchemaOriginTelegram,
+	TagPaymentsGetSavedInfo:                   SchemaOriginTelegram,
+	TagPaymentsClearSavedInfo:                 SchemaOriginTelegram,
+	TagPhoneGetCallConfig:                     SchemaOriginTelegram,
+	TagPhoneRequestCall:                       SchemaOriginTelegram,
+	TagPhoneAcceptCall:                        SchemaOriginTelegram,
+	TagPhoneConfirmCall:                       SchemaOriginTelegram,
+	TagPhoneReceivedCall:                      SchemaOriginTelegram,
+	TagPhoneDiscardCall:                       SchemaOriginTelegram,
+	TagPhoneSetCallRating:                     SchemaOriginTelegram,
+	TagPhoneSaveCallDebug:                     SchemaOriginTelegram,
+	TagTrue:                                   SchemaOriginBuiltin,
+	TagBoolFalse:                              SchemaOriginBuiltin,
+	TagBoolTrue:                               SchemaOriginBuiltin,
+	TagString:                                 SchemaOriginBuiltin,
+	TagInt:                                    SchemaOriginBuiltin,
+	TagLong:                                   SchemaOriginBuiltin,
+	TagDouble:                                 SchemaOriginBuiltin,
+	TagBytes:                                  SchemaOriginBuiltin,
+	TagObject:                                 SchemaOriginBuiltin,
+	TagVector:                                 SchemaOriginBuiltin,
 }
 
 // TLResPQ represents ctor resPQ#05162463 nonce:int128 server_nonce:int128 pq:bytes server_public_key_fingerprints:Vector<long> = ResPQ from MTProto
@@ -1671,40 +1675,12 @@ func (o *TLResPQ) String() string {
 	return tl.Pretty(o)
 }
 
-// TLPQInnerData represents ctor p_q_inner_data#83c95aec pq:bytes p:bytes q:bytes nonce:int128 server_nonce:int128 new_nonce:int256 = P_Q_inner_data from MTProto
-type TLPQInnerData struct {
-	PQ          *big.Int // pq:bytes
-	P           *big.Int // p:bytes
-	Q           *big.Int // q:bytes
-	Nonce       [16]byte // nonce:int128
-	ServerNonce [16]byte // server_nonce:int128
-	NewNonce    [32]byte // new_nonce:int256
-}
-
-func (o *TLPQInnerData) Cmd() uint32 {
-	return TagPQInnerData
-}
-
-func (o *TLPQInnerData) ReadBareFrom(r *tl.Reader) {
-	o.PQ = r.ReadBigInt()
-	o.P = r.ReadBigInt()
-	o.Q = r.ReadBigInt()
-	r.ReadUint128(o.Nonce[:])
-	r.ReadUint128(o.ServerNonce[:])
-	r.ReadFull(o.NewNonce[:])
-}
-
-func (o *TLPQInnerData) WriteBareTo(w *tl.Writer) {
-	w.WriteBigInt(o.PQ)
-	w.WriteBigInt(o.P)
-	w.WriteBigInt(o.Q)
-	w.WriteUint128(o.Nonce[:])
-	w.WriteUint128(o.ServerNonce[:])
-	w.Write(o.NewNonce[:])
-}
-
-func (o *TLPQInnerData) String() string {
-	return tl.Pretty(o)
+// TLPQInnerDataType represents P_Q_inner_data from MTProto
+type TLPQInnerDataType interface {
+	IsTLPQInnerData()
+	Cmd() uint32
+	ReadBareFrom(r *tl.Reader)
+	WriteBareTo(w *tl.Writer)
 }
 
 // TLServerDHParamsType represents Server_DH_Params from MTProto
@@ -2025,9 +2001,9 @@ func (o *TLProtoMessage) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMsgCopy represents ctor msg_copy#e06046b2 orig_message:Message = MessageCopy from MTProto
+// TLMsgCopy represents ctor msg_copy#e06046b2 orig_message:%ProtoMessage = MessageCopy from MTProto
 type TLMsgCopy struct {
-	OrigMessage TLMessageType // orig_message:Message
+	OrigMessage *TLProtoMessage // orig_message:%ProtoMessage
 }
 
 func (o *TLMsgCopy) Cmd() uint32 {
@@ -2035,11 +2011,11 @@ func (o *TLMsgCopy) Cmd() uint32 {
 }
 
 func (o *TLMsgCopy) ReadBareFrom(r *tl.Reader) {
-	o.OrigMessage = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageEmpty, TagMessage, TagMessageService).(TLMessageType)
+	o.OrigMessage = new(TLProtoMessage)
+	o.OrigMessage.ReadBareFrom(r)
 }
 
 func (o *TLMsgCopy) WriteBareTo(w *tl.Writer) {
-	w.WriteCmd(o.OrigMessage.Cmd())
 	o.OrigMessage.WriteBareTo(w)
 }
 
@@ -2078,6 +2054,39 @@ func (o *TLMsgsAck) String() string {
 	return tl.Pretty(o)
 }
 
+// TLBindAuthKeyInner represents ctor bind_auth_key_inner#75a3f765 nonce:long temp_auth_key_id:long perm_auth_key_id:long temp_session_id:long expires_at:int = BindAuthKeyInner from MTProto
+type TLBindAuthKeyInner struct {
+	Nonce         uint64 // nonce:long
+	TempAuthKeyID uint64 // temp_auth_key_id:long
+	PermAuthKeyID uint64 // perm_auth_key_id:long
+	TempSessionID uint64 // temp_session_id:long
+	ExpiresAt     int    // expires_at:int
+}
+
+func (o *TLBindAuthKeyInner) Cmd() uint32 {
+	return TagBindAuthKeyInner
+}
+
+func (o *TLBindAuthKeyInner) ReadBareFrom(r *tl.Reader) {
+	o.Nonce = r.ReadUint64()
+	o.TempAuthKeyID = r.ReadUint64()
+	o.PermAuthKeyID = r.ReadUint64()
+	o.TempSessionID = r.ReadUint64()
+	o.ExpiresAt = r.ReadInt()
+}
+
+func (o *TLBindAuthKeyInner) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.Nonce)
+	w.WriteUint64(o.TempAuthKeyID)
+	w.WriteUint64(o.PermAuthKeyID)
+	w.WriteUint64(o.TempSessionID)
+	w.WriteInt(o.ExpiresAt)
+}
+
+func (o *TLBindAuthKeyInner) String() string {
+	return tl.Pretty(o)
+}
+
 // TLBadMsgNotificationType represents BadMsgNotification from MTProto
 type TLBadMsgNotificationType interface {
 	IsTLBadMsgNotification()
@@ -14262,6 +14271,85 @@ func (o *TLPhoneSaveCallDebug) String() string {
 	return tl.Pretty(o)
 }
 
+// TLPQInnerData represents ctor p_q_inner_data#83c95aec pq:bytes p:bytes q:bytes nonce:int128 server_nonce:int128 new_nonce:int256 = P_Q_inner_data from MTProto
+type TLPQInnerData struct {
+	PQ          *big.Int // pq:bytes
+	P           *big.Int // p:bytes
+	Q           *big.Int // q:bytes
+	Nonce       [16]byte // nonce:int128
+	ServerNonce [16]byte // server_nonce:int128
+	NewNonce    [32]byte // new_nonce:int256
+}
+
+func (o *TLPQInnerData) IsTLPQInnerData() {}
+
+func (o *TLPQInnerData) Cmd() uint32 {
+	return TagPQInnerData
+}
+
+func (o *TLPQInnerData) ReadBareFrom(r *tl.Reader) {
+	o.PQ = r.ReadBigInt()
+	o.P = r.ReadBigInt()
+	o.Q = r.ReadBigInt()
+	r.ReadUint128(o.Nonce[:])
+	r.ReadUint128(o.ServerNonce[:])
+	r.ReadFull(o.NewNonce[:])
+}
+
+func (o *TLPQInnerData) WriteBareTo(w *tl.Writer) {
+	w.WriteBigInt(o.PQ)
+	w.WriteBigInt(o.P)
+	w.WriteBigInt(o.Q)
+	w.WriteUint128(o.Nonce[:])
+	w.WriteUint128(o.ServerNonce[:])
+	w.Write(o.NewNonce[:])
+}
+
+func (o *TLPQInnerData) String() string {
+	return tl.Pretty(o)
+}
+
+// TLPQInnerDataTemp represents ctor p_q_inner_data_temp#3c6a84d4 pq:bytes p:bytes q:bytes nonce:int128 server_nonce:int128 new_nonce:int256 expires_in:int = P_Q_inner_data from MTProto
+type TLPQInnerDataTemp struct {
+	PQ          *big.Int // pq:bytes
+	P           *big.Int // p:bytes
+	Q           *big.Int // q:bytes
+	Nonce       [16]byte // nonce:int128
+	ServerNonce [16]byte // server_nonce:int128
+	NewNonce    [32]byte // new_nonce:int256
+	ExpiresIn   int      // expires_in:int
+}
+
+func (o *TLPQInnerDataTemp) IsTLPQInnerData() {}
+
+func (o *TLPQInnerDataTemp) Cmd() uint32 {
+	return TagPQInnerDataTemp
+}
+
+func (o *TLPQInnerDataTemp) ReadBareFrom(r *tl.Reader) {
+	o.PQ = r.ReadBigInt()
+	o.P = r.ReadBigInt()
+	o.Q = r.ReadBigInt()
+	r.ReadUint128(o.Nonce[:])
+	r.ReadUint128(o.ServerNonce[:])
+	r.ReadFull(o.NewNonce[:])
+	o.ExpiresIn = r.ReadInt()
+}
+
+func (o *TLPQInnerDataTemp) WriteBareTo(w *tl.Writer) {
+	w.WriteBigInt(o.PQ)
+	w.WriteBigInt(o.P)
+	w.WriteBigInt(o.Q)
+	w.WriteUint128(o.Nonce[:])
+	w.WriteUint128(o.ServerNonce[:])
+	w.Write(o.NewNonce[:])
+	w.WriteInt(o.ExpiresIn)
+}
+
+func (o *TLPQInnerDataTemp) String() string {
+	return tl.Pretty(o)
+}
+
 // TLServerDHParamsFail represents ctor server_DH_params_fail#79cb045d nonce:int128 server_nonce:int128 new_nonce_hash:int128 = Server_DH_Params from MTProto
 type TLServerDHParamsFail struct {
 	Nonce        [16]byte // nonce:int128
@@ -14522,845 +14610,402 @@ func (o *TLDestroySessionNone) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMessageEmpty represents ctor messageEmpty#83e5de54 id:int = Message from Telegram
-type TLMessageEmpty struct {
-	ID int // id:int
+// TLBadMsgNotification represents ctor bad_msg_notification#a7eff811 bad_msg_id:long bad_msg_seqno:int error_code:int = BadMsgNotification from MTProto
+type TLBadMsgNotification struct {
+	BadMsgID    uint64 // bad_msg_id:long
+	BadMsgSeqno int    // bad_msg_seqno:int
+	ErrorCode   int    // error_code:int
 }
 
-func (o *TLMessageEmpty) IsTLMessage() {}
+func (o *TLBadMsgNotification) IsTLBadMsgNotification() {}
 
-func (o *TLMessageEmpty) Cmd() uint32 {
-	return TagMessageEmpty
+func (o *TLBadMsgNotification) Cmd() uint32 {
+	return TagBadMsgNotification
 }
 
-func (o *TLMessageEmpty) ReadBareFrom(r *tl.Reader) {
-	o.ID = r.ReadInt()
+func (o *TLBadMsgNotification) ReadBareFrom(r *tl.Reader) {
+	o.BadMsgID = r.ReadUint64()
+	o.BadMsgSeqno = r.ReadInt()
+	o.ErrorCode = r.ReadInt()
 }
 
-func (o *TLMessageEmpty) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.ID)
+func (o *TLBadMsgNotification) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.BadMsgID)
+	w.WriteInt(o.BadMsgSeqno)
+	w.WriteInt(o.ErrorCode)
 }
 
-func (o *TLMessageEmpty) String() string {
+func (o *TLBadMsgNotification) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMessage represents ctor message#c09be45f flags:# flags.1?out:true flags.4?mentioned:true flags.5?media_unread:true flags.13?silent:true flags.14?post:true id:int flags.8?from_id:int to_id:Peer flags.2?fwd_from:MessageFwdHeader flags.11?via_bot_id:int flags.3?reply_to_msg_id:int date:int message:string flags.9?media:MessageMedia flags.6?reply_markup:ReplyMarkup flags.7?entities:Vector<MessageEntity> flags.10?views:int flags.15?edit_date:int = Message from Telegram
-type TLMessage struct {
-	Flags        uint                  // flags:#
-	ID           int                   // id:int
-	FromID       int                   // flags.8?from_id:int
-	ToID         TLPeerType            // to_id:Peer
-	FwdFrom      *TLMessageFwdHeader   // flags.2?fwd_from:MessageFwdHeader
-	ViaBotID     int                   // flags.11?via_bot_id:int
-	ReplyToMsgID int                   // flags.3?reply_to_msg_id:int
-	Date         int                   // date:int
-	Message      string                // message:string
-	Media        TLMessageMediaType    // flags.9?media:MessageMedia
-	ReplyMarkup  TLReplyMarkupType     // flags.6?reply_markup:ReplyMarkup
-	Entities     []TLMessageEntityType // flags.7?entities:Vector<MessageEntity>
-	Views        int                   // flags.10?views:int
-	EditDate     int                   // flags.15?edit_date:int
+// TLBadServerSalt represents ctor bad_server_salt#edab447b bad_msg_id:long bad_msg_seqno:int error_code:int new_server_salt:long = BadMsgNotification from MTProto
+type TLBadServerSalt struct {
+	BadMsgID      uint64 // bad_msg_id:long
+	BadMsgSeqno   int    // bad_msg_seqno:int
+	ErrorCode     int    // error_code:int
+	NewServerSalt uint64 // new_server_salt:long
 }
 
-func (o *TLMessage) IsTLMessage() {}
+func (o *TLBadServerSalt) IsTLBadMsgNotification() {}
 
-func (o *TLMessage) Cmd() uint32 {
-	return TagMessage
+func (o *TLBadServerSalt) Cmd() uint32 {
+	return TagBadServerSalt
 }
 
-func (o *TLMessage) ReadBareFrom(r *tl.Reader) {
-	o.Flags = uint(r.ReadUint32())
-	o.ID = r.ReadInt()
-	if (o.Flags & (1 << 8)) != 0 {
-		o.FromID = r.ReadInt()
-	}
-	o.ToID = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerUser, TagPeerChat, TagPeerChannel).(TLPeerType)
-	if (o.Flags & (1 << 2)) != 0 {
-		if cmd := r.ReadCmd(); cmd != TagMessageFwdHeader {
-			r.Fail(errors.New("expected: messageFwdHeader"))
-		}
-		o.FwdFrom = new(TLMessageFwdHeader)
-		o.FwdFrom.ReadBareFrom(r)
-	}
-	if (o.Flags & (1 << 11)) != 0 {
-		o.ViaBotID = r.ReadInt()
-	}
-	if (o.Flags & (1 << 3)) != 0 {
-		o.ReplyToMsgID = r.ReadInt()
-	}
-	o.Date = r.ReadInt()
-	o.Message = r.ReadString()
-	if (o.Flags & (1 << 9)) != 0 {
-		o.Media = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageMediaEmpty, TagMessageMediaPhoto, TagMessageMediaGeo, TagMessageMediaContact, TagMessageMediaUnsupported, TagMessageMediaDocument, TagMessageMediaWebPage, TagMessageMediaVenue, TagMessageMediaGame, TagMessageMediaInvoice).(TLMessageMediaType)
-	}
-	if (o.Flags & (1 << 6)) != 0 {
-		o.ReplyMarkup = Schema.ReadLimitedBoxedObjectFrom(r, TagReplyKeyboardHide, TagReplyKeyboardForceReply, TagReplyKeyboardMarkup, TagReplyInlineMarkup).(TLReplyMarkupType)
-	}
-	if (o.Flags & (1 << 7)) != 0 {
-		if cmd := r.ReadCmd(); cmd != TagVector {
-			r.Fail(errors.New("expected: vector"))
-		}
-		o.Entities = make([]TLMessageEntityType, r.ReadInt())
-		for i := 0; i < len(o.Entities); i++ {
-			o.Entities[i] = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageEntityUnknown, TagMessageEntityMention, TagMessageEntityHashtag, TagMessageEntityBotCommand, TagMessageEntityURL, TagMessageEntityEmail, TagMessageEntityBold, TagMessageEntityItalic, TagMessageEntityCode, TagMessageEntityPre, TagMessageEntityTextURL, TagMessageEntityMentionName, TagInputMessageEntityMentionName).(TLMessageEntityType)
-		}
-	}
-	if (o.Flags & (1 << 10)) != 0 {
-		o.Views = r.ReadInt()
-	}
-	if (o.Flags & (1 << 15)) != 0 {
-		o.EditDate = r.ReadInt()
-	}
+func (o *TLBadServerSalt) ReadBareFrom(r *tl.Reader) {
+	o.BadMsgID = r.ReadUint64()
+	o.BadMsgSeqno = r.ReadInt()
+	o.ErrorCode = r.ReadInt()
+	o.NewServerSalt = r.ReadUint64()
 }
 
-func (o *TLMessage) WriteBareTo(w *tl.Writer) {
-	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ID)
-	if (o.Flags & (1 << 8)) != 0 {
-		w.WriteInt(o.FromID)
-	}
-	w.WriteCmd(o.ToID.Cmd())
-	o.ToID.WriteBareTo(w)
-	if (o.Flags & (1 << 2)) != 0 {
-		w.WriteCmd(TagMessageFwdHeader)
-		o.FwdFrom.WriteBareTo(w)
-	}
-	if (o.Flags & (1 << 11)) != 0 {
-		w.WriteInt(o.ViaBotID)
-	}
-	if (o.Flags & (1 << 3)) != 0 {
-		w.WriteInt(o.ReplyToMsgID)
-	}
-	w.WriteInt(o.Date)
-	w.WriteString(o.Message)
-	if (o.Flags & (1 << 9)) != 0 {
-		w.WriteCmd(o.Media.Cmd())
-		o.Media.WriteBareTo(w)
-	}
-	if (o.Flags & (1 << 6)) != 0 {
-		w.WriteCmd(o.ReplyMarkup.Cmd())
-		o.ReplyMarkup.WriteBareTo(w)
-	}
-	if (o.Flags & (1 << 7)) != 0 {
-		w.WriteCmd(TagVector)
-		w.WriteInt(len(o.Entities))
-		for i := 0; i < len(o.Entities); i++ {
-			w.WriteCmd(o.Entities[i].Cmd())
-			o.Entities[i].WriteBareTo(w)
-		}
-	}
-	if (o.Flags & (1 << 10)) != 0 {
-		w.WriteInt(o.Views)
-	}
-	if (o.Flags & (1 << 15)) != 0 {
-		w.WriteInt(o.EditDate)
-	}
+func (o *TLBadServerSalt) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.BadMsgID)
+	w.WriteInt(o.BadMsgSeqno)
+	w.WriteInt(o.ErrorCode)
+	w.WriteUint64(o.NewServerSalt)
 }
 
-func (o *TLMessage) Out() bool {
-	return (o.Flags & (1 << 1)) != 0
+func (o *TLBadServerSalt) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessage) SetOut(v bool) {
-	if v {
-		o.Flags |= (1 << 1)
-	} else {
-		o.Flags &= ^uint(1 << 1)
-	}
+// TLMsgDetailedInfo represents ctor msg_detailed_info#276d3ec6 msg_id:long answer_msg_id:long bytes:int status:int = MsgDetailedInfo from MTProto
+type TLMsgDetailedInfo struct {
+	MsgID       uint64 // msg_id:long
+	AnswerMsgID uint64 // answer_msg_id:long
+	Bytes       int    // bytes:int
+	Status      int    // status:int
 }
 
-func (o *TLMessage) Mentioned() bool {
-	return (o.Flags & (1 << 4)) != 0
-}
+func (o *TLMsgDetailedInfo) IsTLMsgDetailedInfo() {}
 
-func (o *TLMessage) SetMentioned(v bool) {
-	if v {
-		o.Flags |= (1 << 4)
-	} else {
-		o.Flags &= ^uint(1 << 4)
-	}
+func (o *TLMsgDetailedInfo) Cmd() uint32 {
+	return TagMsgDetailedInfo
 }
 
-func (o *TLMessage) MediaUnread() bool {
-	return (o.Flags & (1 << 5)) != 0
+func (o *TLMsgDetailedInfo) ReadBareFrom(r *tl.Reader) {
+	o.MsgID = r.ReadUint64()
+	o.AnswerMsgID = r.ReadUint64()
+	o.Bytes = r.ReadInt()
+	o.Status = r.ReadInt()
 }
 
-func (o *TLMessage) SetMediaUnread(v bool) {
-	if v {
-		o.Flags |= (1 << 5)
-	} else {
-		o.Flags &= ^uint(1 << 5)
-	}
+func (o *TLMsgDetailedInfo) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.MsgID)
+	w.WriteUint64(o.AnswerMsgID)
+	w.WriteInt(o.Bytes)
+	w.WriteInt(o.Status)
 }
 
-func (o *TLMessage) Silent() bool {
-	return (o.Flags & (1 << 13)) != 0
+func (o *TLMsgDetailedInfo) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessage) SetSilent(v bool) {
-	if v {
-		o.Flags |= (1 << 13)
-	} else {
-		o.Flags &= ^uint(1 << 13)
-	}
+// TLMsgNewDetailedInfo represents ctor msg_new_detailed_info#809db6df answer_msg_id:long bytes:int status:int = MsgDetailedInfo from MTProto
+type TLMsgNewDetailedInfo struct {
+	AnswerMsgID uint64 // answer_msg_id:long
+	Bytes       int    // bytes:int
+	Status      int    // status:int
 }
 
-func (o *TLMessage) Post() bool {
-	return (o.Flags & (1 << 14)) != 0
-}
+func (o *TLMsgNewDetailedInfo) IsTLMsgDetailedInfo() {}
 
-func (o *TLMessage) SetPost(v bool) {
-	if v {
-		o.Flags |= (1 << 14)
-	} else {
-		o.Flags &= ^uint(1 << 14)
-	}
+func (o *TLMsgNewDetailedInfo) Cmd() uint32 {
+	return TagMsgNewDetailedInfo
 }
 
-func (o *TLMessage) HasFromID() bool {
-	return (o.Flags & (1 << 8)) != 0
+func (o *TLMsgNewDetailedInfo) ReadBareFrom(r *tl.Reader) {
+	o.AnswerMsgID = r.ReadUint64()
+	o.Bytes = r.ReadInt()
+	o.Status = r.ReadInt()
 }
 
-func (o *TLMessage) SetHasFromID(v bool) {
-	if v {
-		o.Flags |= (1 << 8)
-	} else {
-		o.Flags &= ^uint(1 << 8)
-	}
+func (o *TLMsgNewDetailedInfo) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.AnswerMsgID)
+	w.WriteInt(o.Bytes)
+	w.WriteInt(o.Status)
 }
 
-func (o *TLMessage) HasFwdFrom() bool {
-	return (o.Flags & (1 << 2)) != 0
+func (o *TLMsgNewDetailedInfo) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessage) SetHasFwdFrom(v bool) {
-	if v {
-		o.Flags |= (1 << 2)
-	} else {
-		o.Flags &= ^uint(1 << 2)
-	}
+// TLInputPeerEmpty represents ctor inputPeerEmpty#7f3b18ea = InputPeer from Telegram
+type TLInputPeerEmpty struct {
 }
 
-func (o *TLMessage) HasViaBotID() bool {
-	return (o.Flags & (1 << 11)) != 0
-}
+func (o *TLInputPeerEmpty) IsTLInputPeer() {}
 
-func (o *TLMessage) SetHasViaBotID(v bool) {
-	if v {
-		o.Flags |= (1 << 11)
-	} else {
-		o.Flags &= ^uint(1 << 11)
-	}
+func (o *TLInputPeerEmpty) Cmd() uint32 {
+	return TagInputPeerEmpty
 }
 
-func (o *TLMessage) HasReplyToMsgID() bool {
-	return (o.Flags & (1 << 3)) != 0
+func (o *TLInputPeerEmpty) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLMessage) SetHasReplyToMsgID(v bool) {
-	if v {
-		o.Flags |= (1 << 3)
-	} else {
-		o.Flags &= ^uint(1 << 3)
-	}
+func (o *TLInputPeerEmpty) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLMessage) HasMedia() bool {
-	return (o.Flags & (1 << 9)) != 0
+func (o *TLInputPeerEmpty) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessage) SetHasMedia(v bool) {
-	if v {
-		o.Flags |= (1 << 9)
-	} else {
-		o.Flags &= ^uint(1 << 9)
-	}
+// TLInputPeerSelf represents ctor inputPeerSelf#7da07ec9 = InputPeer from Telegram
+type TLInputPeerSelf struct {
 }
 
-func (o *TLMessage) HasReplyMarkup() bool {
-	return (o.Flags & (1 << 6)) != 0
+func (o *TLInputPeerSelf) IsTLInputPeer() {}
+
+func (o *TLInputPeerSelf) Cmd() uint32 {
+	return TagInputPeerSelf
 }
 
-func (o *TLMessage) SetHasReplyMarkup(v bool) {
-	if v {
-		o.Flags |= (1 << 6)
-	} else {
-		o.Flags &= ^uint(1 << 6)
-	}
+func (o *TLInputPeerSelf) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLMessage) HasEntities() bool {
-	return (o.Flags & (1 << 7)) != 0
+func (o *TLInputPeerSelf) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLMessage) SetHasEntities(v bool) {
-	if v {
-		o.Flags |= (1 << 7)
-	} else {
-		o.Flags &= ^uint(1 << 7)
-	}
+func (o *TLInputPeerSelf) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessage) HasViews() bool {
-	return (o.Flags & (1 << 10)) != 0
+// TLInputPeerChat represents ctor inputPeerChat#179be863 chat_id:int = InputPeer from Telegram
+type TLInputPeerChat struct {
+	ChatID int // chat_id:int
 }
 
-func (o *TLMessage) SetHasViews(v bool) {
-	if v {
-		o.Flags |= (1 << 10)
-	} else {
-		o.Flags &= ^uint(1 << 10)
-	}
+func (o *TLInputPeerChat) IsTLInputPeer() {}
+
+func (o *TLInputPeerChat) Cmd() uint32 {
+	return TagInputPeerChat
 }
 
-func (o *TLMessage) HasEditDate() bool {
-	return (o.Flags & (1 << 15)) != 0
+func (o *TLInputPeerChat) ReadBareFrom(r *tl.Reader) {
+	o.ChatID = r.ReadInt()
 }
 
-func (o *TLMessage) SetHasEditDate(v bool) {
-	if v {
-		o.Flags |= (1 << 15)
-	} else {
-		o.Flags &= ^uint(1 << 15)
-	}
+func (o *TLInputPeerChat) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.ChatID)
 }
 
-func (o *TLMessage) String() string {
+func (o *TLInputPeerChat) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMessageService represents ctor messageService#9e19a1f6 flags:# flags.1?out:true flags.4?mentioned:true flags.5?media_unread:true flags.13?silent:true flags.14?post:true id:int flags.8?from_id:int to_id:Peer flags.3?reply_to_msg_id:int date:int action:MessageAction = Message from Telegram
-type TLMessageService struct {
-	Flags        uint                // flags:#
-	ID           int                 // id:int
-	FromID       int                 // flags.8?from_id:int
-	ToID         TLPeerType          // to_id:Peer
-	ReplyToMsgID int                 // flags.3?reply_to_msg_id:int
-	Date         int                 // date:int
-	Action       TLMessageActionType // action:MessageAction
+// TLInputPeerUser represents ctor inputPeerUser#7b8e7de6 user_id:int access_hash:long = InputPeer from Telegram
+type TLInputPeerUser struct {
+	UserID     int    // user_id:int
+	AccessHash uint64 // access_hash:long
 }
 
-func (o *TLMessageService) IsTLMessage() {}
+func (o *TLInputPeerUser) IsTLInputPeer() {}
 
-func (o *TLMessageService) Cmd() uint32 {
-	return TagMessageService
+func (o *TLInputPeerUser) Cmd() uint32 {
+	return TagInputPeerUser
 }
 
-func (o *TLMessageService) ReadBareFrom(r *tl.Reader) {
-	o.Flags = uint(r.ReadUint32())
-	o.ID = r.ReadInt()
-	if (o.Flags & (1 << 8)) != 0 {
-		o.FromID = r.ReadInt()
-	}
-	o.ToID = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerUser, TagPeerChat, TagPeerChannel).(TLPeerType)
-	if (o.Flags & (1 << 3)) != 0 {
-		o.ReplyToMsgID = r.ReadInt()
-	}
-	o.Date = r.ReadInt()
-	o.Action = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageActionEmpty, TagMessageActionChatCreate, TagMessageActionChatEditTitle, TagMessageActionChatEditPhoto, TagMessageActionChatDeletePhoto, TagMessageActionChatAddUser, TagMessageActionChatDeleteUser, TagMessageActionChatJoinedByLink, TagMessageActionChannelCreate, TagMessageActionChatMigrateTo, TagMessageActionChannelMigrateFrom, TagMessageActionPinMessage, TagMessageActionHistoryClear, TagMessageActionGameScore, TagMessageActionPaymentSentMe, TagMessageActionPaymentSent, TagMessageActionPhoneCall).(TLMessageActionType)
+func (o *TLInputPeerUser) ReadBareFrom(r *tl.Reader) {
+	o.UserID = r.ReadInt()
+	o.AccessHash = r.ReadUint64()
 }
 
-func (o *TLMessageService) WriteBareTo(w *tl.Writer) {
-	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ID)
-	if (o.Flags & (1 << 8)) != 0 {
-		w.WriteInt(o.FromID)
-	}
-	w.WriteCmd(o.ToID.Cmd())
-	o.ToID.WriteBareTo(w)
-	if (o.Flags & (1 << 3)) != 0 {
-		w.WriteInt(o.ReplyToMsgID)
-	}
-	w.WriteInt(o.Date)
-	w.WriteCmd(o.Action.Cmd())
-	o.Action.WriteBareTo(w)
+func (o *TLInputPeerUser) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.UserID)
+	w.WriteUint64(o.AccessHash)
 }
 
-func (o *TLMessageService) Out() bool {
-	return (o.Flags & (1 << 1)) != 0
-}
-
-func (o *TLMessageService) SetOut(v bool) {
-	if v {
-		o.Flags |= (1 << 1)
-	} else {
-		o.Flags &= ^uint(1 << 1)
-	}
-}
-
-func (o *TLMessageService) Mentioned() bool {
-	return (o.Flags & (1 << 4)) != 0
+func (o *TLInputPeerUser) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessageService) SetMentioned(v bool) {
-	if v {
-		o.Flags |= (1 << 4)
-	} else {
-		o.Flags &= ^uint(1 << 4)
-	}
+// TLInputPeerChannel represents ctor inputPeerChannel#20adaef8 channel_id:int access_hash:long = InputPeer from Telegram
+type TLInputPeerChannel struct {
+	ChannelID  int    // channel_id:int
+	AccessHash uint64 // access_hash:long
 }
 
-func (o *TLMessageService) MediaUnread() bool {
-	return (o.Flags & (1 << 5)) != 0
-}
+func (o *TLInputPeerChannel) IsTLInputPeer() {}
 
-func (o *TLMessageService) SetMediaUnread(v bool) {
-	if v {
-		o.Flags |= (1 << 5)
-	} else {
-		o.Flags &= ^uint(1 << 5)
-	}
+func (o *TLInputPeerChannel) Cmd() uint32 {
+	return TagInputPeerChannel
 }
 
-func (o *TLMessageService) Silent() bool {
-	return (o.Flags & (1 << 13)) != 0
+func (o *TLInputPeerChannel) ReadBareFrom(r *tl.Reader) {
+	o.ChannelID = r.ReadInt()
+	o.AccessHash = r.ReadUint64()
 }
 
-func (o *TLMessageService) SetSilent(v bool) {
-	if v {
-		o.Flags |= (1 << 13)
-	} else {
-		o.Flags &= ^uint(1 << 13)
-	}
+func (o *TLInputPeerChannel) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.ChannelID)
+	w.WriteUint64(o.AccessHash)
 }
 
-func (o *TLMessageService) Post() bool {
-	return (o.Flags & (1 << 14)) != 0
+func (o *TLInputPeerChannel) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLMessageService) SetPost(v bool) {
-	if v {
-		o.Flags |= (1 << 14)
-	} else {
-		o.Flags &= ^uint(1 << 14)
-	}
+// TLInputUserEmpty represents ctor inputUserEmpty#b98886cf = InputUser from Telegram
+type TLInputUserEmpty struct {
 }
 
-func (o *TLMessageService) HasFromID() bool {
-	return (o.Flags & (1 << 8)) != 0
-}
+func (o *TLInputUserEmpty) IsTLInputUser() {}
 
-func (o *TLMessageService) SetHasFromID(v bool) {
-	if v {
-		o.Flags |= (1 << 8)
-	} else {
-		o.Flags &= ^uint(1 << 8)
-	}
+func (o *TLInputUserEmpty) Cmd() uint32 {
+	return TagInputUserEmpty
 }
 
-func (o *TLMessageService) HasReplyToMsgID() bool {
-	return (o.Flags & (1 << 3)) != 0
+func (o *TLInputUserEmpty) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLMessageService) SetHasReplyToMsgID(v bool) {
-	if v {
-		o.Flags |= (1 << 3)
-	} else {
-		o.Flags &= ^uint(1 << 3)
-	}
+func (o *TLInputUserEmpty) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLMessageService) String() string {
+func (o *TLInputUserEmpty) String() string {
 	return tl.Pretty(o)
 }
 
-// TLBadMsgNotification represents ctor bad_msg_notification#a7eff811 bad_msg_id:long bad_msg_seqno:int error_code:int = BadMsgNotification from MTProto
-type TLBadMsgNotification struct {
-	BadMsgID    uint64 // bad_msg_id:long
-	BadMsgSeqno int    // bad_msg_seqno:int
-	ErrorCode   int    // error_code:int
+// TLInputUserSelf represents ctor inputUserSelf#f7c1b13f = InputUser from Telegram
+type TLInputUserSelf struct {
 }
 
-func (o *TLBadMsgNotification) IsTLBadMsgNotification() {}
+func (o *TLInputUserSelf) IsTLInputUser() {}
 
-func (o *TLBadMsgNotification) Cmd() uint32 {
-	return TagBadMsgNotification
+func (o *TLInputUserSelf) Cmd() uint32 {
+	return TagInputUserSelf
 }
 
-func (o *TLBadMsgNotification) ReadBareFrom(r *tl.Reader) {
-	o.BadMsgID = r.ReadUint64()
-	o.BadMsgSeqno = r.ReadInt()
-	o.ErrorCode = r.ReadInt()
+func (o *TLInputUserSelf) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLBadMsgNotification) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.BadMsgID)
-	w.WriteInt(o.BadMsgSeqno)
-	w.WriteInt(o.ErrorCode)
+func (o *TLInputUserSelf) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLBadMsgNotification) String() string {
+func (o *TLInputUserSelf) String() string {
 	return tl.Pretty(o)
 }
 
-// TLBadServerSalt represents ctor bad_server_salt#edab447b bad_msg_id:long bad_msg_seqno:int error_code:int new_server_salt:long = BadMsgNotification from MTProto
-type TLBadServerSalt struct {
-	BadMsgID      uint64 // bad_msg_id:long
-	BadMsgSeqno   int    // bad_msg_seqno:int
-	ErrorCode     int    // error_code:int
-	NewServerSalt uint64 // new_server_salt:long
+// TLInputUser represents ctor inputUser#d8292816 user_id:int access_hash:long = InputUser from Telegram
+type TLInputUser struct {
+	UserID     int    // user_id:int
+	AccessHash uint64 // access_hash:long
 }
 
-func (o *TLBadServerSalt) IsTLBadMsgNotification() {}
+func (o *TLInputUser) IsTLInputUser() {}
 
-func (o *TLBadServerSalt) Cmd() uint32 {
-	return TagBadServerSalt
+func (o *TLInputUser) Cmd() uint32 {
+	return TagInputUser
 }
 
-func (o *TLBadServerSalt) ReadBareFrom(r *tl.Reader) {
-	o.BadMsgID = r.ReadUint64()
-	o.BadMsgSeqno = r.ReadInt()
-	o.ErrorCode = r.ReadInt()
-	o.NewServerSalt = r.ReadUint64()
+func (o *TLInputUser) ReadBareFrom(r *tl.Reader) {
+	o.UserID = r.ReadInt()
+	o.AccessHash = r.ReadUint64()
 }
 
-func (o *TLBadServerSalt) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.BadMsgID)
-	w.WriteInt(o.BadMsgSeqno)
-	w.WriteInt(o.ErrorCode)
-	w.WriteUint64(o.NewServerSalt)
+func (o *TLInputUser) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.UserID)
+	w.WriteUint64(o.AccessHash)
 }
 
-func (o *TLBadServerSalt) String() string {
+func (o *TLInputUser) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMsgDetailedInfo represents ctor msg_detailed_info#276d3ec6 msg_id:long answer_msg_id:long bytes:int status:int = MsgDetailedInfo from MTProto
-type TLMsgDetailedInfo struct {
-	MsgID       uint64 // msg_id:long
-	AnswerMsgID uint64 // answer_msg_id:long
-	Bytes       int    // bytes:int
-	Status      int    // status:int
+// TLInputFile represents ctor inputFile#f52ff27f id:long parts:int name:string md5_checksum:string = InputFile from Telegram
+type TLInputFile struct {
+	ID          uint64 // id:long
+	Parts       int    // parts:int
+	Name        string // name:string
+	Md5Checksum string // md5_checksum:string
 }
 
-func (o *TLMsgDetailedInfo) IsTLMsgDetailedInfo() {}
+func (o *TLInputFile) IsTLInputFile() {}
 
-func (o *TLMsgDetailedInfo) Cmd() uint32 {
-	return TagMsgDetailedInfo
+func (o *TLInputFile) Cmd() uint32 {
+	return TagInputFile
 }
 
-func (o *TLMsgDetailedInfo) ReadBareFrom(r *tl.Reader) {
-	o.MsgID = r.ReadUint64()
-	o.AnswerMsgID = r.ReadUint64()
-	o.Bytes = r.ReadInt()
-	o.Status = r.ReadInt()
+func (o *TLInputFile) ReadBareFrom(r *tl.Reader) {
+	o.ID = r.ReadUint64()
+	o.Parts = r.ReadInt()
+	o.Name = r.ReadString()
+	o.Md5Checksum = r.ReadString()
 }
 
-func (o *TLMsgDetailedInfo) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.MsgID)
-	w.WriteUint64(o.AnswerMsgID)
-	w.WriteInt(o.Bytes)
-	w.WriteInt(o.Status)
+func (o *TLInputFile) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.ID)
+	w.WriteInt(o.Parts)
+	w.WriteString(o.Name)
+	w.WriteString(o.Md5Checksum)
 }
 
-func (o *TLMsgDetailedInfo) String() string {
+func (o *TLInputFile) String() string {
 	return tl.Pretty(o)
 }
 
-// TLMsgNewDetailedInfo represents ctor msg_new_detailed_info#809db6df answer_msg_id:long bytes:int status:int = MsgDetailedInfo from MTProto
-type TLMsgNewDetailedInfo struct {
-	AnswerMsgID uint64 // answer_msg_id:long
-	Bytes       int    // bytes:int
-	Status      int    // status:int
+// TLInputFileBig represents ctor inputFileBig#fa4f0bb5 id:long parts:int name:string = InputFile from Telegram
+type TLInputFileBig struct {
+	ID    uint64 // id:long
+	Parts int    // parts:int
+	Name  string // name:string
 }
 
-func (o *TLMsgNewDetailedInfo) IsTLMsgDetailedInfo() {}
+func (o *TLInputFileBig) IsTLInputFile() {}
 
-func (o *TLMsgNewDetailedInfo) Cmd() uint32 {
-	return TagMsgNewDetailedInfo
+func (o *TLInputFileBig) Cmd() uint32 {
+	return TagInputFileBig
 }
 
-func (o *TLMsgNewDetailedInfo) ReadBareFrom(r *tl.Reader) {
-	o.AnswerMsgID = r.ReadUint64()
-	o.Bytes = r.ReadInt()
-	o.Status = r.ReadInt()
+func (o *TLInputFileBig) ReadBareFrom(r *tl.Reader) {
+	o.ID = r.ReadUint64()
+	o.Parts = r.ReadInt()
+	o.Name = r.ReadString()
 }
 
-func (o *TLMsgNewDetailedInfo) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.AnswerMsgID)
-	w.WriteInt(o.Bytes)
-	w.WriteInt(o.Status)
+func (o *TLInputFileBig) WriteBareTo(w *tl.Writer) {
+	w.WriteUint64(o.ID)
+	w.WriteInt(o.Parts)
+	w.WriteString(o.Name)
 }
 
-func (o *TLMsgNewDetailedInfo) String() string {
+func (o *TLInputFileBig) String() string {
 	return tl.Pretty(o)
 }
 
-// TLInputPeerEmpty represents ctor inputPeerEmpty#7f3b18ea = InputPeer from Telegram
-type TLInputPeerEmpty struct {
+// TLInputMediaEmpty represents ctor inputMediaEmpty#9664f57f = InputMedia from Telegram
+type TLInputMediaEmpty struct {
 }
 
-func (o *TLInputPeerEmpty) IsTLInputPeer() {}
+func (o *TLInputMediaEmpty) IsTLInputMedia() {}
 
-func (o *TLInputPeerEmpty) Cmd() uint32 {
-	return TagInputPeerEmpty
+func (o *TLInputMediaEmpty) Cmd() uint32 {
+	return TagInputMediaEmpty
 }
 
-func (o *TLInputPeerEmpty) ReadBareFrom(r *tl.Reader) {
+func (o *TLInputMediaEmpty) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLInputPeerEmpty) WriteBareTo(w *tl.Writer) {
+func (o *TLInputMediaEmpty) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLInputPeerEmpty) String() string {
+func (o *TLInputMediaEmpty) String() string {
 	return tl.Pretty(o)
 }
 
-// TLInputPeerSelf represents ctor inputPeerSelf#7da07ec9 = InputPeer from Telegram
-type TLInputPeerSelf struct {
-}
-
-func (o *TLInputPeerSelf) IsTLInputPeer() {}
-
-func (o *TLInputPeerSelf) Cmd() uint32 {
-	return TagInputPeerSelf
+// TLInputMediaUploadedPhoto represents ctor inputMediaUploadedPhoto#630c9af1 flags:# file:InputFile caption:string flags.0?stickers:Vector<InputDocument> = InputMedia from Telegram
+type TLInputMediaUploadedPhoto struct {
+	Flags    uint                  // flags:#
+	File     TLInputFileType       // file:InputFile
+	Caption  string                // caption:string
+	Stickers []TLInputDocumentType // flags.0?stickers:Vector<InputDocument>
 }
 
-func (o *TLInputPeerSelf) ReadBareFrom(r *tl.Reader) {
-}
+func (o *TLInputMediaUploadedPhoto) IsTLInputMedia() {}
 
-func (o *TLInputPeerSelf) WriteBareTo(w *tl.Writer) {
-}
-
-func (o *TLInputPeerSelf) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputPeerChat represents ctor inputPeerChat#179be863 chat_id:int = InputPeer from Telegram
-type TLInputPeerChat struct {
-	ChatID int // chat_id:int
-}
-
-func (o *TLInputPeerChat) IsTLInputPeer() {}
-
-func (o *TLInputPeerChat) Cmd() uint32 {
-	return TagInputPeerChat
-}
-
-func (o *TLInputPeerChat) ReadBareFrom(r *tl.Reader) {
-	o.ChatID = r.ReadInt()
-}
-
-func (o *TLInputPeerChat) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.ChatID)
-}
-
-func (o *TLInputPeerChat) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputPeerUser represents ctor inputPeerUser#7b8e7de6 user_id:int access_hash:long = InputPeer from Telegram
-type TLInputPeerUser struct {
-	UserID     int    // user_id:int
-	AccessHash uint64 // access_hash:long
-}
-
-func (o *TLInputPeerUser) IsTLInputPeer() {}
-
-func (o *TLInputPeerUser) Cmd() uint32 {
-	return TagInputPeerUser
-}
-
-func (o *TLInputPeerUser) ReadBareFrom(r *tl.Reader) {
-	o.UserID = r.ReadInt()
-	o.AccessHash = r.ReadUint64()
-}
-
-func (o *TLInputPeerUser) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.UserID)
-	w.WriteUint64(o.AccessHash)
-}
-
-func (o *TLInputPeerUser) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputPeerChannel represents ctor inputPeerChannel#20adaef8 channel_id:int access_hash:long = InputPeer from Telegram
-type TLInputPeerChannel struct {
-	ChannelID  int    // channel_id:int
-	AccessHash uint64 // access_hash:long
-}
-
-func (o *TLInputPeerChannel) IsTLInputPeer() {}
-
-func (o *TLInputPeerChannel) Cmd() uint32 {
-	return TagInputPeerChannel
-}
-
-func (o *TLInputPeerChannel) ReadBareFrom(r *tl.Reader) {
-	o.ChannelID = r.ReadInt()
-	o.AccessHash = r.ReadUint64()
-}
-
-func (o *TLInputPeerChannel) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.ChannelID)
-	w.WriteUint64(o.AccessHash)
-}
-
-func (o *TLInputPeerChannel) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputUserEmpty represents ctor inputUserEmpty#b98886cf = InputUser from Telegram
-type TLInputUserEmpty struct {
-}
-
-func (o *TLInputUserEmpty) IsTLInputUser() {}
-
-func (o *TLInputUserEmpty) Cmd() uint32 {
-	return TagInputUserEmpty
-}
-
-func (o *TLInputUserEmpty) ReadBareFrom(r *tl.Reader) {
-}
-
-func (o *TLInputUserEmpty) WriteBareTo(w *tl.Writer) {
-}
-
-func (o *TLInputUserEmpty) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputUserSelf represents ctor inputUserSelf#f7c1b13f = InputUser from Telegram
-type TLInputUserSelf struct {
-}
-
-func (o *TLInputUserSelf) IsTLInputUser() {}
-
-func (o *TLInputUserSelf) Cmd() uint32 {
-	return TagInputUserSelf
-}
-
-func (o *TLInputUserSelf) ReadBareFrom(r *tl.Reader) {
-}
-
-func (o *TLInputUserSelf) WriteBareTo(w *tl.Writer) {
-}
-
-func (o *TLInputUserSelf) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputUser represents ctor inputUser#d8292816 user_id:int access_hash:long = InputUser from Telegram
-type TLInputUser struct {
-	UserID     int    // user_id:int
-	AccessHash uint64 // access_hash:long
-}
-
-func (o *TLInputUser) IsTLInputUser() {}
-
-func (o *TLInputUser) Cmd() uint32 {
-	return TagInputUser
-}
-
-func (o *TLInputUser) ReadBareFrom(r *tl.Reader) {
-	o.UserID = r.ReadInt()
-	o.AccessHash = r.ReadUint64()
-}
-
-func (o *TLInputUser) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.UserID)
-	w.WriteUint64(o.AccessHash)
-}
-
-func (o *TLInputUser) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputFile represents ctor inputFile#f52ff27f id:long parts:int name:string md5_checksum:string = InputFile from Telegram
-type TLInputFile struct {
-	ID          uint64 // id:long
-	Parts       int    // parts:int
-	Name        string // name:string
-	Md5Checksum string // md5_checksum:string
-}
-
-func (o *TLInputFile) IsTLInputFile() {}
-
-func (o *TLInputFile) Cmd() uint32 {
-	return TagInputFile
-}
-
-func (o *TLInputFile) ReadBareFrom(r *tl.Reader) {
-	o.ID = r.ReadUint64()
-	o.Parts = r.ReadInt()
-	o.Name = r.ReadString()
-	o.Md5Checksum = r.ReadString()
-}
-
-func (o *TLInputFile) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.ID)
-	w.WriteInt(o.Parts)
-	w.WriteString(o.Name)
-	w.WriteString(o.Md5Checksum)
-}
-
-func (o *TLInputFile) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputFileBig represents ctor inputFileBig#fa4f0bb5 id:long parts:int name:string = InputFile from Telegram
-type TLInputFileBig struct {
-	ID    uint64 // id:long
-	Parts int    // parts:int
-	Name  string // name:string
-}
-
-func (o *TLInputFileBig) IsTLInputFile() {}
-
-func (o *TLInputFileBig) Cmd() uint32 {
-	return TagInputFileBig
-}
-
-func (o *TLInputFileBig) ReadBareFrom(r *tl.Reader) {
-	o.ID = r.ReadUint64()
-	o.Parts = r.ReadInt()
-	o.Name = r.ReadString()
-}
-
-func (o *TLInputFileBig) WriteBareTo(w *tl.Writer) {
-	w.WriteUint64(o.ID)
-	w.WriteInt(o.Parts)
-	w.WriteString(o.Name)
-}
-
-func (o *TLInputFileBig) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputMediaEmpty represents ctor inputMediaEmpty#9664f57f = InputMedia from Telegram
-type TLInputMediaEmpty struct {
-}
-
-func (o *TLInputMediaEmpty) IsTLInputMedia() {}
-
-func (o *TLInputMediaEmpty) Cmd() uint32 {
-	return TagInputMediaEmpty
-}
-
-func (o *TLInputMediaEmpty) ReadBareFrom(r *tl.Reader) {
-}
-
-func (o *TLInputMediaEmpty) WriteBareTo(w *tl.Writer) {
-}
-
-func (o *TLInputMediaEmpty) String() string {
-	return tl.Pretty(o)
-}
-
-// TLInputMediaUploadedPhoto represents ctor inputMediaUploadedPhoto#630c9af1 flags:# file:InputFile caption:string flags.0?stickers:Vector<InputDocument> = InputMedia from Telegram
-type TLInputMediaUploadedPhoto struct {
-	Flags    uint                  // flags:#
-	File     TLInputFileType       // file:InputFile
-	Caption  string                // caption:string
-	Stickers []TLInputDocumentType // flags.0?stickers:Vector<InputDocument>
-}
-
-func (o *TLInputMediaUploadedPhoto) IsTLInputMedia() {}
-
-func (o *TLInputMediaUploadedPhoto) Cmd() uint32 {
-	return TagInputMediaUploadedPhoto
+func (o *TLInputMediaUploadedPhoto) Cmd() uint32 {
+	return TagInputMediaUploadedPhoto
 }
 
 func (o *TLInputMediaUploadedPhoto) ReadBareFrom(r *tl.Reader) {
@@ -17060,26 +16705,623 @@ func (o *TLChat) ReadBareFrom(r *tl.Reader) {
 	}
 }
 
-func (o *TLChat) WriteBareTo(w *tl.Writer) {
-	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ID)
-	w.WriteString(o.Title)
-	w.WriteCmd(o.Photo.Cmd())
-	o.Photo.WriteBareTo(w)
-	w.WriteInt(o.ParticipantsCount)
-	w.WriteInt(o.Date)
-	w.WriteInt(o.Version)
-	if (o.Flags & (1 << 6)) != 0 {
-		w.WriteCmd(o.MigratedTo.Cmd())
-		o.MigratedTo.WriteBareTo(w)
+func (o *TLChat) WriteBareTo(w *tl.Writer) {
+	w.WriteUint32(uint32(o.Flags))
+	w.WriteInt(o.ID)
+	w.WriteString(o.Title)
+	w.WriteCmd(o.Photo.Cmd())
+	o.Photo.WriteBareTo(w)
+	w.WriteInt(o.ParticipantsCount)
+	w.WriteInt(o.Date)
+	w.WriteInt(o.Version)
+	if (o.Flags & (1 << 6)) != 0 {
+		w.WriteCmd(o.MigratedTo.Cmd())
+		o.MigratedTo.WriteBareTo(w)
+	}
+}
+
+func (o *TLChat) Creator() bool {
+	return (o.Flags & (1 << 0)) != 0
+}
+
+func (o *TLChat) SetCreator(v bool) {
+	if v {
+		o.Flags |= (1 << 0)
+	} else {
+		o.Flags &= ^uint(1 << 0)
+	}
+}
+
+func (o *TLChat) Kicked() bool {
+	return (o.Flags & (1 << 1)) != 0
+}
+
+func (o *TLChat) SetKicked(v bool) {
+	if v {
+		o.Flags |= (1 << 1)
+	} else {
+		o.Flags &= ^uint(1 << 1)
+	}
+}
+
+func (o *TLChat) Left() bool {
+	return (o.Flags & (1 << 2)) != 0
+}
+
+func (o *TLChat) SetLeft(v bool) {
+	if v {
+		o.Flags |= (1 << 2)
+	} else {
+		o.Flags &= ^uint(1 << 2)
+	}
+}
+
+func (o *TLChat) AdminsEnabled() bool {
+	return (o.Flags & (1 << 3)) != 0
+}
+
+func (o *TLChat) SetAdminsEnabled(v bool) {
+	if v {
+		o.Flags |= (1 << 3)
+	} else {
+		o.Flags &= ^uint(1 << 3)
+	}
+}
+
+func (o *TLChat) Admin() bool {
+	return (o.Flags & (1 << 4)) != 0
+}
+
+func (o *TLChat) SetAdmin(v bool) {
+	if v {
+		o.Flags |= (1 << 4)
+	} else {
+		o.Flags &= ^uint(1 << 4)
+	}
+}
+
+func (o *TLChat) Deactivated() bool {
+	return (o.Flags & (1 << 5)) != 0
+}
+
+func (o *TLChat) SetDeactivated(v bool) {
+	if v {
+		o.Flags |= (1 << 5)
+	} else {
+		o.Flags &= ^uint(1 << 5)
+	}
+}
+
+func (o *TLChat) HasMigratedTo() bool {
+	return (o.Flags & (1 << 6)) != 0
+}
+
+func (o *TLChat) SetHasMigratedTo(v bool) {
+	if v {
+		o.Flags |= (1 << 6)
+	} else {
+		o.Flags &= ^uint(1 << 6)
+	}
+}
+
+func (o *TLChat) String() string {
+	return tl.Pretty(o)
+}
+
+// TLChatForbidden represents ctor chatForbidden#07328bdb id:int title:string = Chat from Telegram
+type TLChatForbidden struct {
+	ID    int    // id:int
+	Title string // title:string
+}
+
+func (o *TLChatForbidden) IsTLChat() {}
+
+func (o *TLChatForbidden) Cmd() uint32 {
+	return TagChatForbidden
+}
+
+func (o *TLChatForbidden) ReadBareFrom(r *tl.Reader) {
+	o.ID = r.ReadInt()
+	o.Title = r.ReadString()
+}
+
+func (o *TLChatForbidden) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.ID)
+	w.WriteString(o.Title)
+}
+
+func (o *TLChatForbidden) String() string {
+	return tl.Pretty(o)
+}
+
+// TLChannel represents ctor channel#a14dca52 flags:# flags.0?creator:true flags.1?kicked:true flags.2?left:true flags.3?editor:true flags.4?moderator:true flags.5?broadcast:true flags.7?verified:true flags.8?megagroup:true flags.9?restricted:true flags.10?democracy:true flags.11?signatures:true flags.12?min:true id:int flags.13?access_hash:long title:string flags.6?username:string photo:ChatPhoto date:int version:int flags.9?restriction_reason:string = Chat from Telegram
+type TLChannel struct {
+	Flags             uint            // flags:#
+	ID                int             // id:int
+	AccessHash        uint64          // flags.13?access_hash:long
+	Title             string          // title:string
+	Username          string          // flags.6?username:string
+	Photo             TLChatPhotoType // photo:ChatPhoto
+	Date              int             // date:int
+	Version           int             // version:int
+	RestrictionReason string          // flags.9?restriction_reason:string
+}
+
+func (o *TLChannel) IsTLChat() {}
+
+func (o *TLChannel) Cmd() uint32 {
+	return TagChannel
+}
+
+func (o *TLChannel) ReadBareFrom(r *tl.Reader) {
+	o.Flags = uint(r.ReadUint32())
+	o.ID = r.ReadInt()
+	if (o.Flags & (1 << 13)) != 0 {
+		o.AccessHash = r.ReadUint64()
+	}
+	o.Title = r.ReadString()
+	if (o.Flags & (1 << 6)) != 0 {
+		o.Username = r.ReadString()
+	}
+	o.Photo = Schema.ReadLimitedBoxedObjectFrom(r, TagChatPhotoEmpty, TagChatPhoto).(TLChatPhotoType)
+	o.Date = r.ReadInt()
+	o.Version = r.ReadInt()
+	if (o.Flags & (1 << 9)) != 0 {
+		o.RestrictionReason = r.ReadString()
+	}
+}
+
+func (o *TLChannel) WriteBareTo(w *tl.Writer) {
+	w.WriteUint32(uint32(o.Flags))
+	w.WriteInt(o.ID)
+	if (o.Flags & (1 << 13)) != 0 {
+		w.WriteUint64(o.AccessHash)
+	}
+	w.WriteString(o.Title)
+	if (o.Flags & (1 << 6)) != 0 {
+		w.WriteString(o.Username)
+	}
+	w.WriteCmd(o.Photo.Cmd())
+	o.Photo.WriteBareTo(w)
+	w.WriteInt(o.Date)
+	w.WriteInt(o.Version)
+	if (o.Flags & (1 << 9)) != 0 {
+		w.WriteString(o.RestrictionReason)
+	}
+}
+
+func (o *TLChannel) Creator() bool {
+	return (o.Flags & (1 << 0)) != 0
+}
+
+func (o *TLChannel) SetCreator(v bool) {
+	if v {
+		o.Flags |= (1 << 0)
+	} else {
+		o.Flags &= ^uint(1 << 0)
+	}
+}
+
+func (o *TLChannel) Kicked() bool {
+	return (o.Flags & (1 << 1)) != 0
+}
+
+func (o *TLChannel) SetKicked(v bool) {
+	if v {
+		o.Flags |= (1 << 1)
+	} else {
+		o.Flags &= ^uint(1 << 1)
+	}
+}
+
+func (o *TLChannel) Left() bool {
+	return (o.Flags & (1 << 2)) != 0
+}
+
+func (o *TLChannel) SetLeft(v bool) {
+	if v {
+		o.Flags |= (1 << 2)
+	} else {
+		o.Flags &= ^uint(1 << 2)
+	}
+}
+
+func (o *TLChannel) Editor() bool {
+	return (o.Flags & (1 << 3)) != 0
+}
+
+func (o *TLChannel) SetEditor(v bool) {
+	if v {
+		o.Flags |= (1 << 3)
+	} else {
+		o.Flags &= ^uint(1 << 3)
+	}
+}
+
+func (o *TLChannel) Moderator() bool {
+	return (o.Flags & (1 << 4)) != 0
+}
+
+func (o *TLChannel) SetModerator(v bool) {
+	if v {
+		o.Flags |= (1 << 4)
+	} else {
+		o.Flags &= ^uint(1 << 4)
+	}
+}
+
+func (o *TLChannel) Broadcast() bool {
+	return (o.Flags & (1 << 5)) != 0
+}
+
+func (o *TLChannel) SetBroadcast(v bool) {
+	if v {
+		o.Flags |= (1 << 5)
+	} else {
+		o.Flags &= ^uint(1 << 5)
+	}
+}
+
+func (o *TLChannel) Verified() bool {
+	return (o.Flags & (1 << 7)) != 0
+}
+
+func (o *TLChannel) SetVerified(v bool) {
+	if v {
+		o.Flags |= (1 << 7)
+	} else {
+		o.Flags &= ^uint(1 << 7)
+	}
+}
+
+func (o *TLChannel) Megagroup() bool {
+	return (o.Flags & (1 << 8)) != 0
+}
+
+func (o *TLChannel) SetMegagroup(v bool) {
+	if v {
+		o.Flags |= (1 << 8)
+	} else {
+		o.Flags &= ^uint(1 << 8)
+	}
+}
+
+func (o *TLChannel) Restricted() bool {
+	return (o.Flags & (1 << 9)) != 0
+}
+
+func (o *TLChannel) SetRestricted(v bool) {
+	if v {
+		o.Flags |= (1 << 9)
+	} else {
+		o.Flags &= ^uint(1 << 9)
+	}
+}
+
+func (o *TLChannel) Democracy() bool {
+	return (o.Flags & (1 << 10)) != 0
+}
+
+func (o *TLChannel) SetDemocracy(v bool) {
+	if v {
+		o.Flags |= (1 << 10)
+	} else {
+		o.Flags &= ^uint(1 << 10)
+	}
+}
+
+func (o *TLChannel) Signatures() bool {
+	return (o.Flags & (1 << 11)) != 0
+}
+
+func (o *TLChannel) SetSignatures(v bool) {
+	if v {
+		o.Flags |= (1 << 11)
+	} else {
+		o.Flags &= ^uint(1 << 11)
+	}
+}
+
+func (o *TLChannel) Min() bool {
+	return (o.Flags & (1 << 12)) != 0
+}
+
+func (o *TLChannel) SetMin(v bool) {
+	if v {
+		o.Flags |= (1 << 12)
+	} else {
+		o.Flags &= ^uint(1 << 12)
+	}
+}
+
+func (o *TLChannel) HasAccessHash() bool {
+	return (o.Flags & (1 << 13)) != 0
+}
+
+func (o *TLChannel) SetHasAccessHash(v bool) {
+	if v {
+		o.Flags |= (1 << 13)
+	} else {
+		o.Flags &= ^uint(1 << 13)
+	}
+}
+
+func (o *TLChannel) HasUsername() bool {
+	return (o.Flags & (1 << 6)) != 0
+}
+
+func (o *TLChannel) SetHasUsername(v bool) {
+	if v {
+		o.Flags |= (1 << 6)
+	} else {
+		o.Flags &= ^uint(1 << 6)
+	}
+}
+
+func (o *TLChannel) HasRestrictionReason() bool {
+	return (o.Flags & (1 << 9)) != 0
+}
+
+func (o *TLChannel) SetHasRestrictionReason(v bool) {
+	if v {
+		o.Flags |= (1 << 9)
+	} else {
+		o.Flags &= ^uint(1 << 9)
+	}
+}
+
+func (o *TLChannel) String() string {
+	return tl.Pretty(o)
+}
+
+// TLChannelForbidden represents ctor channelForbidden#8537784f flags:# flags.5?broadcast:true flags.8?megagroup:true id:int access_hash:long title:string = Chat from Telegram
+type TLChannelForbidden struct {
+	Flags      uint   // flags:#
+	ID         int    // id:int
+	AccessHash uint64 // access_hash:long
+	Title      string // title:string
+}
+
+func (o *TLChannelForbidden) IsTLChat() {}
+
+func (o *TLChannelForbidden) Cmd() uint32 {
+	return TagChannelForbidden
+}
+
+func (o *TLChannelForbidden) ReadBareFrom(r *tl.Reader) {
+	o.Flags = uint(r.ReadUint32())
+	o.ID = r.ReadInt()
+	o.AccessHash = r.ReadUint64()
+	o.Title = r.ReadString()
+}
+
+func (o *TLChannelForbidden) WriteBareTo(w *tl.Writer) {
+	w.WriteUint32(uint32(o.Flags))
+	w.WriteInt(o.ID)
+	w.WriteUint64(o.AccessHash)
+	w.WriteString(o.Title)
+}
+
+func (o *TLChannelForbidden) Broadcast() bool {
+	return (o.Flags & (1 << 5)) != 0
+}
+
+func (o *TLChannelForbidden) SetBroadcast(v bool) {
+	if v {
+		o.Flags |= (1 << 5)
+	} else {
+		o.Flags &= ^uint(1 << 5)
+	}
+}
+
+func (o *TLChannelForbidden) Megagroup() bool {
+	return (o.Flags & (1 << 8)) != 0
+}
+
+func (o *TLChannelForbidden) SetMegagroup(v bool) {
+	if v {
+		o.Flags |= (1 << 8)
+	} else {
+		o.Flags &= ^uint(1 << 8)
+	}
+}
+
+func (o *TLChannelForbidden) String() string {
+	return tl.Pretty(o)
+}
+
+// TLChatFull represents ctor chatFull#2e02a614 id:int participants:ChatParticipants chat_photo:Photo notify_settings:PeerNotifySettings exported_invite:ExportedChatInvite bot_info:Vector<BotInfo> = ChatFull from Telegram
+type TLChatFull struct {
+	ID             int                      // id:int
+	Participants   TLChatParticipantsType   // participants:ChatParticipants
+	ChatPhoto      TLPhotoType              // chat_photo:Photo
+	NotifySettings TLPeerNotifySettingsType // notify_settings:PeerNotifySettings
+	ExportedInvite TLExportedChatInviteType // exported_invite:ExportedChatInvite
+	BotInfo        []*TLBotInfo             // bot_info:Vector<BotInfo>
+}
+
+func (o *TLChatFull) IsTLChatFull() {}
+
+func (o *TLChatFull) Cmd() uint32 {
+	return TagChatFull
+}
+
+func (o *TLChatFull) ReadBareFrom(r *tl.Reader) {
+	o.ID = r.ReadInt()
+	o.Participants = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipantsForbidden, TagChatParticipants).(TLChatParticipantsType)
+	o.ChatPhoto = Schema.ReadLimitedBoxedObjectFrom(r, TagPhotoEmpty, TagPhoto).(TLPhotoType)
+	o.NotifySettings = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerNotifySettingsEmpty, TagPeerNotifySettings).(TLPeerNotifySettingsType)
+	o.ExportedInvite = Schema.ReadLimitedBoxedObjectFrom(r, TagChatInviteEmpty, TagChatInviteExported).(TLExportedChatInviteType)
+	if cmd := r.ReadCmd(); cmd != TagVector {
+		r.Fail(errors.New("expected: vector"))
+	}
+	o.BotInfo = make([]*TLBotInfo, r.ReadInt())
+	for i := 0; i < len(o.BotInfo); i++ {
+		if cmd := r.ReadCmd(); cmd != TagBotInfo {
+			r.Fail(errors.New("expected: botInfo"))
+		}
+		o.BotInfo[i] = new(TLBotInfo)
+		o.BotInfo[i].ReadBareFrom(r)
+	}
+}
+
+func (o *TLChatFull) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.ID)
+	w.WriteCmd(o.Participants.Cmd())
+	o.Participants.WriteBareTo(w)
+	w.WriteCmd(o.ChatPhoto.Cmd())
+	o.ChatPhoto.WriteBareTo(w)
+	w.WriteCmd(o.NotifySettings.Cmd())
+	o.NotifySettings.WriteBareTo(w)
+	w.WriteCmd(o.ExportedInvite.Cmd())
+	o.ExportedInvite.WriteBareTo(w)
+	w.WriteCmd(TagVector)
+	w.WriteInt(len(o.BotInfo))
+	for i := 0; i < len(o.BotInfo); i++ {
+		w.WriteCmd(TagBotInfo)
+		o.BotInfo[i].WriteBareTo(w)
+	}
+}
+
+func (o *TLChatFull) String() string {
+	return tl.Pretty(o)
+}
+
+// TLChannelFull represents ctor channelFull#c3d5512f flags:# flags.3?can_view_participants:true flags.6?can_set_username:true id:int about:string flags.0?participants_count:int flags.1?admins_count:int flags.2?kicked_count:int read_inbox_max_id:int read_outbox_max_id:int unread_count:int chat_photo:Photo notify_settings:PeerNotifySettings exported_invite:ExportedChatInvite bot_info:Vector<BotInfo> flags.4?migrated_from_chat_id:int flags.4?migrated_from_max_id:int flags.5?pinned_msg_id:int = ChatFull from Telegram
+type TLChannelFull struct {
+	Flags              uint                     // flags:#
+	ID                 int                      // id:int
+	About              string                   // about:string
+	ParticipantsCount  int                      // flags.0?participants_count:int
+	AdminsCount        int                      // flags.1?admins_count:int
+	KickedCount        int                      // flags.2?kicked_count:int
+	ReadInboxMaxID     int                      // read_inbox_max_id:int
+	ReadOutboxMaxID    int                      // read_outbox_max_id:int
+	UnreadCount        int                      // unread_count:int
+	ChatPhoto          TLPhotoType              // chat_photo:Photo
+	NotifySettings     TLPeerNotifySettingsType // notify_settings:PeerNotifySettings
+	ExportedInvite     TLExportedChatInviteType // exported_invite:ExportedChatInvite
+	BotInfo            []*TLBotInfo             // bot_info:Vector<BotInfo>
+	MigratedFromChatID int                      // flags.4?migrated_from_chat_id:int
+	MigratedFromMaxID  int                      // flags.4?migrated_from_max_id:int
+	PinnedMsgID        int                      // flags.5?pinned_msg_id:int
+}
+
+func (o *TLChannelFull) IsTLChatFull() {}
+
+func (o *TLChannelFull) Cmd() uint32 {
+	return TagChannelFull
+}
+
+func (o *TLChannelFull) ReadBareFrom(r *tl.Reader) {
+	o.Flags = uint(r.ReadUint32())
+	o.ID = r.ReadInt()
+	o.About = r.ReadString()
+	if (o.Flags & (1 << 0)) != 0 {
+		o.ParticipantsCount = r.ReadInt()
+	}
+	if (o.Flags & (1 << 1)) != 0 {
+		o.AdminsCount = r.ReadInt()
+	}
+	if (o.Flags & (1 << 2)) != 0 {
+		o.KickedCount = r.ReadInt()
+	}
+	o.ReadInboxMaxID = r.ReadInt()
+	o.ReadOutboxMaxID = r.ReadInt()
+	o.UnreadCount = r.ReadInt()
+	o.ChatPhoto = Schema.ReadLimitedBoxedObjectFrom(r, TagPhotoEmpty, TagPhoto).(TLPhotoType)
+	o.NotifySettings = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerNotifySettingsEmpty, TagPeerNotifySettings).(TLPeerNotifySettingsType)
+	o.ExportedInvite = Schema.ReadLimitedBoxedObjectFrom(r, TagChatInviteEmpty, TagChatInviteExported).(TLExportedChatInviteType)
+	if cmd := r.ReadCmd(); cmd != TagVector {
+		r.Fail(errors.New("expected: vector"))
+	}
+	o.BotInfo = make([]*TLBotInfo, r.ReadInt())
+	for i := 0; i < len(o.BotInfo); i++ {
+		if cmd := r.ReadCmd(); cmd != TagBotInfo {
+			r.Fail(errors.New("expected: botInfo"))
+		}
+		o.BotInfo[i] = new(TLBotInfo)
+		o.BotInfo[i].ReadBareFrom(r)
+	}
+	if (o.Flags & (1 << 4)) != 0 {
+		o.MigratedFromChatID = r.ReadInt()
+	}
+	if (o.Flags & (1 << 4)) != 0 {
+		o.MigratedFromMaxID = r.ReadInt()
+	}
+	if (o.Flags & (1 << 5)) != 0 {
+		o.PinnedMsgID = r.ReadInt()
+	}
+}
+
+func (o *TLChannelFull) WriteBareTo(w *tl.Writer) {
+	w.WriteUint32(uint32(o.Flags))
+	w.WriteInt(o.ID)
+	w.WriteString(o.About)
+	if (o.Flags & (1 << 0)) != 0 {
+		w.WriteInt(o.ParticipantsCount)
+	}
+	if (o.Flags & (1 << 1)) != 0 {
+		w.WriteInt(o.AdminsCount)
+	}
+	if (o.Flags & (1 << 2)) != 0 {
+		w.WriteInt(o.KickedCount)
+	}
+	w.WriteInt(o.ReadInboxMaxID)
+	w.WriteInt(o.ReadOutboxMaxID)
+	w.WriteInt(o.UnreadCount)
+	w.WriteCmd(o.ChatPhoto.Cmd())
+	o.ChatPhoto.WriteBareTo(w)
+	w.WriteCmd(o.NotifySettings.Cmd())
+	o.NotifySettings.WriteBareTo(w)
+	w.WriteCmd(o.ExportedInvite.Cmd())
+	o.ExportedInvite.WriteBareTo(w)
+	w.WriteCmd(TagVector)
+	w.WriteInt(len(o.BotInfo))
+	for i := 0; i < len(o.BotInfo); i++ {
+		w.WriteCmd(TagBotInfo)
+		o.BotInfo[i].WriteBareTo(w)
+	}
+	if (o.Flags & (1 << 4)) != 0 {
+		w.WriteInt(o.MigratedFromChatID)
+	}
+	if (o.Flags & (1 << 4)) != 0 {
+		w.WriteInt(o.MigratedFromMaxID)
+	}
+	if (o.Flags & (1 << 5)) != 0 {
+		w.WriteInt(o.PinnedMsgID)
+	}
+}
+
+func (o *TLChannelFull) CanViewParticipants() bool {
+	return (o.Flags & (1 << 3)) != 0
+}
+
+func (o *TLChannelFull) SetCanViewParticipants(v bool) {
+	if v {
+		o.Flags |= (1 << 3)
+	} else {
+		o.Flags &= ^uint(1 << 3)
+	}
+}
+
+func (o *TLChannelFull) CanSetUsername() bool {
+	return (o.Flags & (1 << 6)) != 0
+}
+
+func (o *TLChannelFull) SetCanSetUsername(v bool) {
+	if v {
+		o.Flags |= (1 << 6)
+	} else {
+		o.Flags &= ^uint(1 << 6)
 	}
 }
 
-func (o *TLChat) Creator() bool {
+func (o *TLChannelFull) HasParticipantsCount() bool {
 	return (o.Flags & (1 << 0)) != 0
 }
 
-func (o *TLChat) SetCreator(v bool) {
+func (o *TLChannelFull) SetHasParticipantsCount(v bool) {
 	if v {
 		o.Flags |= (1 << 0)
 	} else {
@@ -17087,11 +17329,11 @@ func (o *TLChat) SetCreator(v bool) {
 	}
 }
 
-func (o *TLChat) Kicked() bool {
+func (o *TLChannelFull) HasAdminsCount() bool {
 	return (o.Flags & (1 << 1)) != 0
 }
 
-func (o *TLChat) SetKicked(v bool) {
+func (o *TLChannelFull) SetHasAdminsCount(v bool) {
 	if v {
 		o.Flags |= (1 << 1)
 	} else {
@@ -17099,11 +17341,11 @@ func (o *TLChat) SetKicked(v bool) {
 	}
 }
 
-func (o *TLChat) Left() bool {
+func (o *TLChannelFull) HasKickedCount() bool {
 	return (o.Flags & (1 << 2)) != 0
 }
 
-func (o *TLChat) SetLeft(v bool) {
+func (o *TLChannelFull) SetHasKickedCount(v bool) {
 	if v {
 		o.Flags |= (1 << 2)
 	} else {
@@ -17111,23 +17353,23 @@ func (o *TLChat) SetLeft(v bool) {
 	}
 }
 
-func (o *TLChat) AdminsEnabled() bool {
-	return (o.Flags & (1 << 3)) != 0
+func (o *TLChannelFull) HasMigratedFromChatID() bool {
+	return (o.Flags & (1 << 4)) != 0
 }
 
-func (o *TLChat) SetAdminsEnabled(v bool) {
+func (o *TLChannelFull) SetHasMigratedFromChatID(v bool) {
 	if v {
-		o.Flags |= (1 << 3)
+		o.Flags |= (1 << 4)
 	} else {
-		o.Flags &= ^uint(1 << 3)
+		o.Flags &= ^uint(1 << 4)
 	}
 }
 
-func (o *TLChat) Admin() bool {
+func (o *TLChannelFull) HasMigratedFromMaxID() bool {
 	return (o.Flags & (1 << 4)) != 0
 }
 
-func (o *TLChat) SetAdmin(v bool) {
+func (o *TLChannelFull) SetHasMigratedFromMaxID(v bool) {
 	if v {
 		o.Flags |= (1 << 4)
 	} else {
@@ -17135,11 +17377,11 @@ func (o *TLChat) SetAdmin(v bool) {
 	}
 }
 
-func (o *TLChat) Deactivated() bool {
+func (o *TLChannelFull) HasPinnedMsgID() bool {
 	return (o.Flags & (1 << 5)) != 0
 }
 
-func (o *TLChat) SetDeactivated(v bool) {
+func (o *TLChannelFull) SetHasPinnedMsgID(v bool) {
 	if v {
 		o.Flags |= (1 << 5)
 	} else {
@@ -17147,819 +17389,665 @@ func (o *TLChat) SetDeactivated(v bool) {
 	}
 }
 
-func (o *TLChat) HasMigratedTo() bool {
-	return (o.Flags & (1 << 6)) != 0
-}
-
-func (o *TLChat) SetHasMigratedTo(v bool) {
-	if v {
-		o.Flags |= (1 << 6)
-	} else {
-		o.Flags &= ^uint(1 << 6)
-	}
-}
-
-func (o *TLChat) String() string {
-	return tl.Pretty(o)
-}
-
-// TLChatForbidden represents ctor chatForbidden#07328bdb id:int title:string = Chat from Telegram
-type TLChatForbidden struct {
-	ID    int    // id:int
-	Title string // title:string
-}
-
-func (o *TLChatForbidden) IsTLChat() {}
-
-func (o *TLChatForbidden) Cmd() uint32 {
-	return TagChatForbidden
-}
-
-func (o *TLChatForbidden) ReadBareFrom(r *tl.Reader) {
-	o.ID = r.ReadInt()
-	o.Title = r.ReadString()
-}
-
-func (o *TLChatForbidden) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.ID)
-	w.WriteString(o.Title)
-}
-
-func (o *TLChatForbidden) String() string {
+func (o *TLChannelFull) String() string {
 	return tl.Pretty(o)
 }
 
-// TLChannel represents ctor channel#a14dca52 flags:# flags.0?creator:true flags.1?kicked:true flags.2?left:true flags.3?editor:true flags.4?moderator:true flags.5?broadcast:true flags.7?verified:true flags.8?megagroup:true flags.9?restricted:true flags.10?democracy:true flags.11?signatures:true flags.12?min:true id:int flags.13?access_hash:long title:string flags.6?username:string photo:ChatPhoto date:int version:int flags.9?restriction_reason:string = Chat from Telegram
-type TLChannel struct {
-	Flags             uint            // flags:#
-	ID                int             // id:int
-	AccessHash        uint64          // flags.13?access_hash:long
-	Title             string          // title:string
-	Username          string          // flags.6?username:string
-	Photo             TLChatPhotoType // photo:ChatPhoto
-	Date              int             // date:int
-	Version           int             // version:int
-	RestrictionReason string          // flags.9?restriction_reason:string
+// TLChatParticipant represents ctor chatParticipant#c8d7493e user_id:int inviter_id:int date:int = ChatParticipant from Telegram
+type TLChatParticipant struct {
+	UserID    int // user_id:int
+	InviterID int // inviter_id:int
+	Date      int // date:int
 }
 
-func (o *TLChannel) IsTLChat() {}
+func (o *TLChatParticipant) IsTLChatParticipant() {}
 
-func (o *TLChannel) Cmd() uint32 {
-	return TagChannel
+func (o *TLChatParticipant) Cmd() uint32 {
+	return TagChatParticipant
 }
 
-func (o *TLChannel) ReadBareFrom(r *tl.Reader) {
-	o.Flags = uint(r.ReadUint32())
-	o.ID = r.ReadInt()
-	if (o.Flags & (1 << 13)) != 0 {
-		o.AccessHash = r.ReadUint64()
-	}
-	o.Title = r.ReadString()
-	if (o.Flags & (1 << 6)) != 0 {
-		o.Username = r.ReadString()
-	}
-	o.Photo = Schema.ReadLimitedBoxedObjectFrom(r, TagChatPhotoEmpty, TagChatPhoto).(TLChatPhotoType)
+func (o *TLChatParticipant) ReadBareFrom(r *tl.Reader) {
+	o.UserID = r.ReadInt()
+	o.InviterID = r.ReadInt()
 	o.Date = r.ReadInt()
-	o.Version = r.ReadInt()
-	if (o.Flags & (1 << 9)) != 0 {
-		o.RestrictionReason = r.ReadString()
-	}
 }
 
-func (o *TLChannel) WriteBareTo(w *tl.Writer) {
-	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ID)
-	if (o.Flags & (1 << 13)) != 0 {
-		w.WriteUint64(o.AccessHash)
-	}
-	w.WriteString(o.Title)
-	if (o.Flags & (1 << 6)) != 0 {
-		w.WriteString(o.Username)
-	}
-	w.WriteCmd(o.Photo.Cmd())
-	o.Photo.WriteBareTo(w)
+func (o *TLChatParticipant) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.UserID)
+	w.WriteInt(o.InviterID)
 	w.WriteInt(o.Date)
-	w.WriteInt(o.Version)
-	if (o.Flags & (1 << 9)) != 0 {
-		w.WriteString(o.RestrictionReason)
-	}
-}
-
-func (o *TLChannel) Creator() bool {
-	return (o.Flags & (1 << 0)) != 0
-}
-
-func (o *TLChannel) SetCreator(v bool) {
-	if v {
-		o.Flags |= (1 << 0)
-	} else {
-		o.Flags &= ^uint(1 << 0)
-	}
-}
-
-func (o *TLChannel) Kicked() bool {
-	return (o.Flags & (1 << 1)) != 0
-}
-
-func (o *TLChannel) SetKicked(v bool) {
-	if v {
-		o.Flags |= (1 << 1)
-	} else {
-		o.Flags &= ^uint(1 << 1)
-	}
 }
 
-func (o *TLChannel) Left() bool {
-	return (o.Flags & (1 << 2)) != 0
+func (o *TLChatParticipant) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLChannel) SetLeft(v bool) {
-	if v {
-		o.Flags |= (1 << 2)
-	} else {
-		o.Flags &= ^uint(1 << 2)
-	}
+// TLChatParticipantCreator represents ctor chatParticipantCreator#da13538a user_id:int = ChatParticipant from Telegram
+type TLChatParticipantCreator struct {
+	UserID int // user_id:int
 }
 
-func (o *TLChannel) Editor() bool {
-	return (o.Flags & (1 << 3)) != 0
-}
+func (o *TLChatParticipantCreator) IsTLChatParticipant() {}
 
-func (o *TLChannel) SetEditor(v bool) {
-	if v {
-		o.Flags |= (1 << 3)
-	} else {
-		o.Flags &= ^uint(1 << 3)
-	}
+func (o *TLChatParticipantCreator) Cmd() uint32 {
+	return TagChatParticipantCreator
 }
 
-func (o *TLChannel) Moderator() bool {
-	return (o.Flags & (1 << 4)) != 0
+func (o *TLChatParticipantCreator) ReadBareFrom(r *tl.Reader) {
+	o.UserID = r.ReadInt()
 }
 
-func (o *TLChannel) SetModerator(v bool) {
-	if v {
-		o.Flags |= (1 << 4)
-	} else {
-		o.Flags &= ^uint(1 << 4)
-	}
+func (o *TLChatParticipantCreator) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.UserID)
 }
 
-func (o *TLChannel) Broadcast() bool {
-	return (o.Flags & (1 << 5)) != 0
+func (o *TLChatParticipantCreator) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLChannel) SetBroadcast(v bool) {
-	if v {
-		o.Flags |= (1 << 5)
-	} else {
-		o.Flags &= ^uint(1 << 5)
-	}
+// TLChatParticipantAdmin represents ctor chatParticipantAdmin#e2d6e436 user_id:int inviter_id:int date:int = ChatParticipant from Telegram
+type TLChatParticipantAdmin struct {
+	UserID    int // user_id:int
+	InviterID int // inviter_id:int
+	Date      int // date:int
 }
 
-func (o *TLChannel) Verified() bool {
-	return (o.Flags & (1 << 7)) != 0
-}
+func (o *TLChatParticipantAdmin) IsTLChatParticipant() {}
 
-func (o *TLChannel) SetVerified(v bool) {
-	if v {
-		o.Flags |= (1 << 7)
-	} else {
-		o.Flags &= ^uint(1 << 7)
-	}
+func (o *TLChatParticipantAdmin) Cmd() uint32 {
+	return TagChatParticipantAdmin
 }
 
-func (o *TLChannel) Megagroup() bool {
-	return (o.Flags & (1 << 8)) != 0
+func (o *TLChatParticipantAdmin) ReadBareFrom(r *tl.Reader) {
+	o.UserID = r.ReadInt()
+	o.InviterID = r.ReadInt()
+	o.Date = r.ReadInt()
 }
 
-func (o *TLChannel) SetMegagroup(v bool) {
-	if v {
-		o.Flags |= (1 << 8)
-	} else {
-		o.Flags &= ^uint(1 << 8)
-	}
+func (o *TLChatParticipantAdmin) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.UserID)
+	w.WriteInt(o.InviterID)
+	w.WriteInt(o.Date)
 }
 
-func (o *TLChannel) Restricted() bool {
-	return (o.Flags & (1 << 9)) != 0
+func (o *TLChatParticipantAdmin) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLChannel) SetRestricted(v bool) {
-	if v {
-		o.Flags |= (1 << 9)
-	} else {
-		o.Flags &= ^uint(1 << 9)
-	}
+// TLChatParticipantsForbidden represents ctor chatParticipantsForbidden#fc900c2b flags:# chat_id:int flags.0?self_participant:ChatParticipant = ChatParticipants from Telegram
+type TLChatParticipantsForbidden struct {
+	Flags           uint                  // flags:#
+	ChatID          int                   // chat_id:int
+	SelfParticipant TLChatParticipantType // flags.0?self_participant:ChatParticipant
 }
 
-func (o *TLChannel) Democracy() bool {
-	return (o.Flags & (1 << 10)) != 0
-}
+func (o *TLChatParticipantsForbidden) IsTLChatParticipants() {}
 
-func (o *TLChannel) SetDemocracy(v bool) {
-	if v {
-		o.Flags |= (1 << 10)
-	} else {
-		o.Flags &= ^uint(1 << 10)
-	}
+func (o *TLChatParticipantsForbidden) Cmd() uint32 {
+	return TagChatParticipantsForbidden
 }
 
-func (o *TLChannel) Signatures() bool {
-	return (o.Flags & (1 << 11)) != 0
+func (o *TLChatParticipantsForbidden) ReadBareFrom(r *tl.Reader) {
+	o.Flags = uint(r.ReadUint32())
+	o.ChatID = r.ReadInt()
+	if (o.Flags & (1 << 0)) != 0 {
+		o.SelfParticipant = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipant, TagChatParticipantCreator, TagChatParticipantAdmin).(TLChatParticipantType)
+	}
 }
 
-func (o *TLChannel) SetSignatures(v bool) {
-	if v {
-		o.Flags |= (1 << 11)
-	} else {
-		o.Flags &= ^uint(1 << 11)
+func (o *TLChatParticipantsForbidden) WriteBareTo(w *tl.Writer) {
+	w.WriteUint32(uint32(o.Flags))
+	w.WriteInt(o.ChatID)
+	if (o.Flags & (1 << 0)) != 0 {
+		w.WriteCmd(o.SelfParticipant.Cmd())
+		o.SelfParticipant.WriteBareTo(w)
 	}
 }
 
-func (o *TLChannel) Min() bool {
-	return (o.Flags & (1 << 12)) != 0
+func (o *TLChatParticipantsForbidden) HasSelfParticipant() bool {
+	return (o.Flags & (1 << 0)) != 0
 }
 
-func (o *TLChannel) SetMin(v bool) {
+func (o *TLChatParticipantsForbidden) SetHasSelfParticipant(v bool) {
 	if v {
-		o.Flags |= (1 << 12)
+		o.Flags |= (1 << 0)
 	} else {
-		o.Flags &= ^uint(1 << 12)
+		o.Flags &= ^uint(1 << 0)
 	}
 }
 
-func (o *TLChannel) HasAccessHash() bool {
-	return (o.Flags & (1 << 13)) != 0
+func (o *TLChatParticipantsForbidden) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLChannel) SetHasAccessHash(v bool) {
-	if v {
-		o.Flags |= (1 << 13)
-	} else {
-		o.Flags &= ^uint(1 << 13)
-	}
+// TLChatParticipants represents ctor chatParticipants#3f460fed chat_id:int participants:Vector<ChatParticipant> version:int = ChatParticipants from Telegram
+type TLChatParticipants struct {
+	ChatID       int                     // chat_id:int
+	Participants []TLChatParticipantType // participants:Vector<ChatParticipant>
+	Version      int                     // version:int
 }
 
-func (o *TLChannel) HasUsername() bool {
-	return (o.Flags & (1 << 6)) != 0
-}
+func (o *TLChatParticipants) IsTLChatParticipants() {}
 
-func (o *TLChannel) SetHasUsername(v bool) {
-	if v {
-		o.Flags |= (1 << 6)
-	} else {
-		o.Flags &= ^uint(1 << 6)
-	}
+func (o *TLChatParticipants) Cmd() uint32 {
+	return TagChatParticipants
 }
 
-func (o *TLChannel) HasRestrictionReason() bool {
-	return (o.Flags & (1 << 9)) != 0
+func (o *TLChatParticipants) ReadBareFrom(r *tl.Reader) {
+	o.ChatID = r.ReadInt()
+	if cmd := r.ReadCmd(); cmd != TagVector {
+		r.Fail(errors.New("expected: vector"))
+	}
+	o.Participants = make([]TLChatParticipantType, r.ReadInt())
+	for i := 0; i < len(o.Participants); i++ {
+		o.Participants[i] = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipant, TagChatParticipantCreator, TagChatParticipantAdmin).(TLChatParticipantType)
+	}
+	o.Version = r.ReadInt()
 }
 
-func (o *TLChannel) SetHasRestrictionReason(v bool) {
-	if v {
-		o.Flags |= (1 << 9)
-	} else {
-		o.Flags &= ^uint(1 << 9)
+func (o *TLChatParticipants) WriteBareTo(w *tl.Writer) {
+	w.WriteInt(o.ChatID)
+	w.WriteCmd(TagVector)
+	w.WriteInt(len(o.Participants))
+	for i := 0; i < len(o.Participants); i++ {
+		w.WriteCmd(o.Participants[i].Cmd())
+		o.Participants[i].WriteBareTo(w)
 	}
+	w.WriteInt(o.Version)
 }
 
-func (o *TLChannel) String() string {
+func (o *TLChatParticipants) String() string {
 	return tl.Pretty(o)
 }
 
-// TLChannelForbidden represents ctor channelForbidden#8537784f flags:# flags.5?broadcast:true flags.8?megagroup:true id:int access_hash:long title:string = Chat from Telegram
-type TLChannelForbidden struct {
-	Flags      uint   // flags:#
-	ID         int    // id:int
-	AccessHash uint64 // access_hash:long
-	Title      string // title:string
+// TLChatPhotoEmpty represents ctor chatPhotoEmpty#37c1011c = ChatPhoto from Telegram
+type TLChatPhotoEmpty struct {
 }
 
-func (o *TLChannelForbidden) IsTLChat() {}
+func (o *TLChatPhotoEmpty) IsTLChatPhoto() {}
 
-func (o *TLChannelForbidden) Cmd() uint32 {
-	return TagChannelForbidden
+func (o *TLChatPhotoEmpty) Cmd() uint32 {
+	return TagChatPhotoEmpty
 }
 
-func (o *TLChannelForbidden) ReadBareFrom(r *tl.Reader) {
-	o.Flags = uint(r.ReadUint32())
-	o.ID = r.ReadInt()
-	o.AccessHash = r.ReadUint64()
-	o.Title = r.ReadString()
+func (o *TLChatPhotoEmpty) ReadBareFrom(r *tl.Reader) {
 }
 
-func (o *TLChannelForbidden) WriteBareTo(w *tl.Writer) {
-	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ID)
-	w.WriteUint64(o.AccessHash)
-	w.WriteString(o.Title)
+func (o *TLChatPhotoEmpty) WriteBareTo(w *tl.Writer) {
 }
 
-func (o *TLChannelForbidden) Broadcast() bool {
-	return (o.Flags & (1 << 5)) != 0
+func (o *TLChatPhotoEmpty) String() string {
+	return tl.Pretty(o)
 }
 
-func (o *TLChannelForbidden) SetBroadcast(v bool) {
-	if v {
-		o.Flags |= (1 << 5)
-	} else {
-		o.Flags &= ^uint(1 << 5)
-	}
+// TLChatPhoto represents ctor chatPhoto#6153276a photo_small:FileLocation photo_big:FileLocation = ChatPhoto from Telegram
+type TLChatPhoto struct {
+	PhotoSmall TLFileLocationType // photo_small:FileLocation
+	PhotoBig   TLFileLocationType // photo_big:FileLocation
 }
 
-func (o *TLChannelForbidden) Megagroup() bool {
-	return (o.Flags & (1 << 8)) != 0
+func (o *TLChatPhoto) IsTLChatPhoto() {}
+
+func (o *TLChatPhoto) Cmd() uint32 {
+	return TagChatPhoto
 }
 
-func (o *TLChannelForbidden) SetMegagroup(v bool) {
-	if v {
-		o.Flags |= (1 << 8)
-	} else {
-		o.Flags &= ^uint(1 << 8)
-	}
+func (o *TLChatPhoto) ReadBareFrom(r *tl.Reader) {
+	o.PhotoSmall = Schema.ReadLimitedBoxedObjectFrom(r, TagFileLocationUnavailable, TagFileLocation).(TLFileLocationType)
+	o.PhotoBig = Schema.ReadLimitedBoxedObjectFrom(r, TagFileLocationUnavailable, TagFileLocation).(TLFileLocationType)
 }
 
-func (o *TLChannelForbidden) String() string {
+func (o *TLChatPhoto) WriteBareTo(w *tl.Writer) {
+	w.WriteCmd(o.PhotoSmall.Cmd())
+	o.PhotoSmall.WriteBareTo(w)
+	w.WriteCmd(o.PhotoBig.Cmd())
+	o.PhotoBig.WriteBareTo(w)
+}
+
+func (o *TLChatPhoto) String() string {
 	return tl.Pretty(o)
 }
 
-// TLChatFull represents ctor chatFull#2e02a614 id:int participants:ChatParticipants chat_photo:Photo notify_settings:PeerNotifySettings exported_invite:ExportedChatInvite bot_info:Vector<BotInfo> = ChatFull from Telegram
-type TLChatFull struct {
-	ID             int                      // id:int
-	Participants   TLChatParticipantsType   // participants:ChatParticipants
-	ChatPhoto      TLPhotoType              // chat_photo:Photo
-	NotifySettings TLPeerNotifySettingsType // notify_settings:PeerNotifySettings
-	ExportedInvite TLExportedChatInviteType // exported_invite:ExportedChatInvite
-	BotInfo        []*TLBotInfo             // bot_info:Vector<BotInfo>
+// TLMessageEmpty represents ctor messageEmpty#83e5de54 id:int = Message from Telegram
+type TLMessageEmpty struct {
+	ID int // id:int
 }
 
-func (o *TLChatFull) IsTLChatFull() {}
+func (o *TLMessageEmpty) IsTLMessage() {}
 
-func (o *TLChatFull) Cmd() uint32 {
-	return TagChatFull
+func (o *TLMessageEmpty) Cmd() uint32 {
+	return TagMessageEmpty
 }
 
-func (o *TLChatFull) ReadBareFrom(r *tl.Reader) {
+func (o *TLMessageEmpty) ReadBareFrom(r *tl.Reader) {
 	o.ID = r.ReadInt()
-	o.Participants = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipantsForbidden, TagChatParticipants).(TLChatParticipantsType)
-	o.ChatPhoto = Schema.ReadLimitedBoxedObjectFrom(r, TagPhotoEmpty, TagPhoto).(TLPhotoType)
-	o.NotifySettings = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerNotifySettingsEmpty, TagPeerNotifySettings).(TLPeerNotifySettingsType)
-	o.ExportedInvite = Schema.ReadLimitedBoxedObjectFrom(r, TagChatInviteEmpty, TagChatInviteExported).(TLExportedChatInviteType)
-	if cmd := r.ReadCmd(); cmd != TagVector {
-		r.Fail(errors.New("expected: vector"))
-	}
-	o.BotInfo = make([]*TLBotInfo, r.ReadInt())
-	for i := 0; i < len(o.BotInfo); i++ {
-		if cmd := r.ReadCmd(); cmd != TagBotInfo {
-			r.Fail(errors.New("expected: botInfo"))
-		}
-		o.BotInfo[i] = new(TLBotInfo)
-		o.BotInfo[i].ReadBareFrom(r)
-	}
 }
 
-func (o *TLChatFull) WriteBareTo(w *tl.Writer) {
+func (o *TLMessageEmpty) WriteBareTo(w *tl.Writer) {
 	w.WriteInt(o.ID)
-	w.WriteCmd(o.Participants.Cmd())
-	o.Participants.WriteBareTo(w)
-	w.WriteCmd(o.ChatPhoto.Cmd())
-	o.ChatPhoto.WriteBareTo(w)
-	w.WriteCmd(o.NotifySettings.Cmd())
-	o.NotifySettings.WriteBareTo(w)
-	w.WriteCmd(o.ExportedInvite.Cmd())
-	o.ExportedInvite.WriteBareTo(w)
-	w.WriteCmd(TagVector)
-	w.WriteInt(len(o.BotInfo))
-	for i := 0; i < len(o.BotInfo); i++ {
-		w.WriteCmd(TagBotInfo)
-		o.BotInfo[i].WriteBareTo(w)
-	}
 }
 
-func (o *TLChatFull) String() string {
+func (o *TLMessageEmpty) String() string {
 	return tl.Pretty(o)
 }
 
-// TLChannelFull represents ctor channelFull#c3d5512f flags:# flags.3?can_view_participants:true flags.6?can_set_username:true id:int about:string flags.0?participants_count:int flags.1?admins_count:int flags.2?kicked_count:int read_inbox_max_id:int read_outbox_max_id:int unread_count:int chat_photo:Photo notify_settings:PeerNotifySettings exported_invite:ExportedChatInvite bot_info:Vector<BotInfo> flags.4?migrated_from_chat_id:int flags.4?migrated_from_max_id:int flags.5?pinned_msg_id:int = ChatFull from Telegram
-type TLChannelFull struct {
-	Flags              uint                     // flags:#
-	ID                 int                      // id:int
-	About              string                   // about:string
-	ParticipantsCount  int                      // flags.0?participants_count:int
-	AdminsCount        int                      // flags.1?admins_count:int
-	KickedCount        int                      // flags.2?kicked_count:int
-	ReadInboxMaxID     int                      // read_inbox_max_id:int
-	ReadOutboxMaxID    int                      // read_outbox_max_id:int
-	UnreadCount        int                      // unread_count:int
-	ChatPhoto          TLPhotoType              // chat_photo:Photo
-	NotifySettings     TLPeerNotifySettingsType // notify_settings:PeerNotifySettings
-	ExportedInvite     TLExportedChatInviteType // exported_invite:ExportedChatInvite
-	BotInfo            []*TLBotInfo             // bot_info:Vector<BotInfo>
-	MigratedFromChatID int                      // flags.4?migrated_from_chat_id:int
-	MigratedFromMaxID  int                      // flags.4?migrated_from_max_id:int
-	PinnedMsgID        int                      // flags.5?pinned_msg_id:int
+// TLMessage represents ctor message#c09be45f flags:# flags.1?out:true flags.4?mentioned:true flags.5?media_unread:true flags.13?silent:true flags.14?post:true id:int flags.8?from_id:int to_id:Peer flags.2?fwd_from:MessageFwdHeader flags.11?via_bot_id:int flags.3?reply_to_msg_id:int date:int message:string flags.9?media:MessageMedia flags.6?reply_markup:ReplyMarkup flags.7?entities:Vector<MessageEntity> flags.10?views:int flags.15?edit_date:int = Message from Telegram
+type TLMessage struct {
+	Flags        uint                  // flags:#
+	ID           int                   // id:int
+	FromID       int                   // flags.8?from_id:int
+	ToID         TLPeerType            // to_id:Peer
+	FwdFrom      *TLMessageFwdHeader   // flags.2?fwd_from:MessageFwdHeader
+	ViaBotID     int                   // flags.11?via_bot_id:int
+	ReplyToMsgID int                   // flags.3?reply_to_msg_id:int
+	Date         int                   // date:int
+	Message      string                // message:string
+	Media        TLMessageMediaType    // flags.9?media:MessageMedia
+	ReplyMarkup  TLReplyMarkupType     // flags.6?reply_markup:ReplyMarkup
+	Entities     []TLMessageEntityType // flags.7?entities:Vector<MessageEntity>
+	Views        int                   // flags.10?views:int
+	EditDate     int                   // flags.15?edit_date:int
 }
 
-func (o *TLChannelFull) IsTLChatFull() {}
+func (o *TLMessage) IsTLMessage() {}
 
-func (o *TLChannelFull) Cmd() uint32 {
-	return TagChannelFull
+func (o *TLMessage) Cmd() uint32 {
+	return TagMessage
 }
 
-func (o *TLChannelFull) ReadBareFrom(r *tl.Reader) {
+func (o *TLMessage) ReadBareFrom(r *tl.Reader) {
 	o.Flags = uint(r.ReadUint32())
 	o.ID = r.ReadInt()
-	o.About = r.ReadString()
-	if (o.Flags & (1 << 0)) != 0 {
-		o.ParticipantsCount = r.ReadInt()
-	}
-	if (o.Flags & (1 << 1)) != 0 {
-		o.AdminsCount = r.ReadInt()
+	if (o.Flags & (1 << 8)) != 0 {
+		o.FromID = r.ReadInt()
 	}
+	o.ToID = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerUser, TagPeerChat, TagPeerChannel).(TLPeerType)
 	if (o.Flags & (1 << 2)) != 0 {
-		o.KickedCount = r.ReadInt()
+		if cmd := r.ReadCmd(); cmd != TagMessageFwdHeader {
+			r.Fail(errors.New("expected: messageFwdHeader"))
+		}
+		o.FwdFrom = new(TLMessageFwdHeader)
+		o.FwdFrom.ReadBareFrom(r)
 	}
-	o.ReadInboxMaxID = r.ReadInt()
-	o.ReadOutboxMaxID = r.ReadInt()
-	o.UnreadCount = r.ReadInt()
-	o.ChatPhoto = Schema.ReadLimitedBoxedObjectFrom(r, TagPhotoEmpty, TagPhoto).(TLPhotoType)
-	o.NotifySettings = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerNotifySettingsEmpty, TagPeerNotifySettings).(TLPeerNotifySettingsType)
-	o.ExportedInvite = Schema.ReadLimitedBoxedObjectFrom(r, TagChatInviteEmpty, TagChatInviteExported).(TLExportedChatInviteType)
-	if cmd := r.ReadCmd(); cmd != TagVector {
-		r.Fail(errors.New("expected: vector"))
+	if (o.Flags & (1 << 11)) != 0 {
+		o.ViaBotID = r.ReadInt()
 	}
-	o.BotInfo = make([]*TLBotInfo, r.ReadInt())
-	for i := 0; i < len(o.BotInfo); i++ {
-		if cmd := r.ReadCmd(); cmd != TagBotInfo {
-			r.Fail(errors.New("expected: botInfo"))
-		}
-		o.BotInfo[i] = new(TLBotInfo)
-		o.BotInfo[i].ReadBareFrom(r)
+	if (o.Flags & (1 << 3)) != 0 {
+		o.ReplyToMsgID = r.ReadInt()
 	}
-	if (o.Flags & (1 << 4)) != 0 {
-		o.MigratedFromChatID = r.ReadInt()
+	o.Date = r.ReadInt()
+	o.Message = r.ReadString()
+	if (o.Flags & (1 << 9)) != 0 {
+		o.Media = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageMediaEmpty, TagMessageMediaPhoto, TagMessageMediaGeo, TagMessageMediaContact, TagMessageMediaUnsupported, TagMessageMediaDocument, TagMessageMediaWebPage, TagMessageMediaVenue, TagMessageMediaGame, TagMessageMediaInvoice).(TLMessageMediaType)
 	}
-	if (o.Flags & (1 << 4)) != 0 {
-		o.MigratedFromMaxID = r.ReadInt()
+	if (o.Flags & (1 << 6)) != 0 {
+		o.ReplyMarkup = Schema.ReadLimitedBoxedObjectFrom(r, TagReplyKeyboardHide, TagReplyKeyboardForceReply, TagReplyKeyboardMarkup, TagReplyInlineMarkup).(TLReplyMarkupType)
 	}
-	if (o.Flags & (1 << 5)) != 0 {
-		o.PinnedMsgID = r.ReadInt()
+	if (o.Flags & (1 << 7)) != 0 {
+		if cmd := r.ReadCmd(); cmd != TagVector {
+			r.Fail(errors.New("expected: vector"))
+		}
+		o.Entities = make([]TLMessageEntityType, r.ReadInt())
+		for i := 0; i < len(o.Entities); i++ {
+			o.Entities[i] = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageEntityUnknown, TagMessageEntityMention, TagMessageEntityHashtag, TagMessageEntityBotCommand, TagMessageEntityURL, TagMessageEntityEmail, TagMessageEntityBold, TagMessageEntityItalic, TagMessageEntityCode, TagMessageEntityPre, TagMessageEntityTextURL, TagMessageEntityMentionName, TagInputMessageEntityMentionName).(TLMessageEntityType)
+		}
+	}
+	if (o.Flags & (1 << 10)) != 0 {
+		o.Views = r.ReadInt()
+	}
+	if (o.Flags & (1 << 15)) != 0 {
+		o.EditDate = r.ReadInt()
 	}
 }
 
-func (o *TLChannelFull) WriteBareTo(w *tl.Writer) {
+func (o *TLMessage) WriteBareTo(w *tl.Writer) {
 	w.WriteUint32(uint32(o.Flags))
 	w.WriteInt(o.ID)
-	w.WriteString(o.About)
-	if (o.Flags & (1 << 0)) != 0 {
-		w.WriteInt(o.ParticipantsCount)
-	}
-	if (o.Flags & (1 << 1)) != 0 {
-		w.WriteInt(o.AdminsCount)
+	if (o.Flags & (1 << 8)) != 0 {
+		w.WriteInt(o.FromID)
 	}
+	w.WriteCmd(o.ToID.Cmd())
+	o.ToID.WriteBareTo(w)
 	if (o.Flags & (1 << 2)) != 0 {
-		w.WriteInt(o.KickedCount)
+		w.WriteCmd(TagMessageFwdHeader)
+		o.FwdFrom.WriteBareTo(w)
 	}
-	w.WriteInt(o.ReadInboxMaxID)
-	w.WriteInt(o.ReadOutboxMaxID)
-	w.WriteInt(o.UnreadCount)
-	w.WriteCmd(o.ChatPhoto.Cmd())
-	o.ChatPhoto.WriteBareTo(w)
-	w.WriteCmd(o.NotifySettings.Cmd())
-	o.NotifySettings.WriteBareTo(w)
-	w.WriteCmd(o.ExportedInvite.Cmd())
-	o.ExportedInvite.WriteBareTo(w)
-	w.WriteCmd(TagVector)
-	w.WriteInt(len(o.BotInfo))
-	for i := 0; i < len(o.BotInfo); i++ {
-		w.WriteCmd(TagBotInfo)
-		o.BotInfo[i].WriteBareTo(w)
+	if (o.Flags & (1 << 11)) != 0 {
+		w.WriteInt(o.ViaBotID)
 	}
-	if (o.Flags & (1 << 4)) != 0 {
-		w.WriteInt(o.MigratedFromChatID)
+	if (o.Flags & (1 << 3)) != 0 {
+		w.WriteInt(o.ReplyToMsgID)
 	}
-	if (o.Flags & (1 << 4)) != 0 {
-		w.WriteInt(o.MigratedFromMaxID)
+	w.WriteInt(o.Date)
+	w.WriteString(o.Message)
+	if (o.Flags & (1 << 9)) != 0 {
+		w.WriteCmd(o.Media.Cmd())
+		o.Media.WriteBareTo(w)
 	}
-	if (o.Flags & (1 << 5)) != 0 {
-		w.WriteInt(o.PinnedMsgID)
+	if (o.Flags & (1 << 6)) != 0 {
+		w.WriteCmd(o.ReplyMarkup.Cmd())
+		o.ReplyMarkup.WriteBareTo(w)
+	}
+	if (o.Flags & (1 << 7)) != 0 {
+		w.WriteCmd(TagVector)
+		w.WriteInt(len(o.Entities))
+		for i := 0; i < len(o.Entities); i++ {
+			w.WriteCmd(o.Entities[i].Cmd())
+			o.Entities[i].WriteBareTo(w)
+		}
+	}
+	if (o.Flags & (1 << 10)) != 0 {
+		w.WriteInt(o.Views)
+	}
+	if (o.Flags & (1 << 15)) != 0 {
+		w.WriteInt(o.EditDate)
 	}
 }
 
-func (o *TLChannelFull) CanViewParticipants() bool {
-	return (o.Flags & (1 << 3)) != 0
+func (o *TLMessage) Out() bool {
+	return (o.Flags & (1 << 1)) != 0
 }
 
-func (o *TLChannelFull) SetCanViewParticipants(v bool) {
+func (o *TLMessage) SetOut(v bool) {
 	if v {
-		o.Flags |= (1 << 3)
+		o.Flags |= (1 << 1)
 	} else {
-		o.Flags &= ^uint(1 << 3)
+		o.Flags &= ^uint(1 << 1)
 	}
 }
 
-func (o *TLChannelFull) CanSetUsername() bool {
-	return (o.Flags & (1 << 6)) != 0
+func (o *TLMessage) Mentioned() bool {
+	return (o.Flags & (1 << 4)) != 0
 }
 
-func (o *TLChannelFull) SetCanSetUsername(v bool) {
+func (o *TLMessage) SetMentioned(v bool) {
 	if v {
-		o.Flags |= (1 << 6)
+		o.Flags |= (1 << 4)
 	} else {
-		o.Flags &= ^uint(1 << 6)
+		o.Flags &= ^uint(1 << 4)
 	}
 }
 
-func (o *TLChannelFull) HasParticipantsCount() bool {
-	return (o.Flags & (1 << 0)) != 0
+func (o *TLMessage) MediaUnread() bool {
+	return (o.Flags & (1 << 5)) != 0
 }
 
-func (o *TLChannelFull) SetHasParticipantsCount(v bool) {
+func (o *TLMessage) SetMediaUnread(v bool) {
 	if v {
-		o.Flags |= (1 << 0)
+		o.Flags |= (1 << 5)
 	} else {
-		o.Flags &= ^uint(1 << 0)
+		o.Flags &= ^uint(1 << 5)
 	}
 }
 
-func (o *TLChannelFull) HasAdminsCount() bool {
-	return (o.Flags & (1 << 1)) != 0
+func (o *TLMessage) Silent() bool {
+	return (o.Flags & (1 << 13)) != 0
 }
 
-func (o *TLChannelFull) SetHasAdminsCount(v bool) {
+func (o *TLMessage) SetSilent(v bool) {
 	if v {
-		o.Flags |= (1 << 1)
+		o.Flags |= (1 << 13)
 	} else {
-		o.Flags &= ^uint(1 << 1)
+		o.Flags &= ^uint(1 << 13)
 	}
 }
 
-func (o *TLChannelFull) HasKickedCount() bool {
-	return (o.Flags & (1 << 2)) != 0
+func (o *TLMessage) Post() bool {
+	return (o.Flags & (1 << 14)) != 0
 }
 
-func (o *TLChannelFull) SetHasKickedCount(v bool) {
+func (o *TLMessage) SetPost(v bool) {
 	if v {
-		o.Flags |= (1 << 2)
+		o.Flags |= (1 << 14)
 	} else {
-		o.Flags &= ^uint(1 << 2)
+		o.Flags &= ^uint(1 << 14)
 	}
 }
 
-func (o *TLChannelFull) HasMigratedFromChatID() bool {
-	return (o.Flags & (1 << 4)) != 0
+func (o *TLMessage) HasFromID() bool {
+	return (o.Flags & (1 << 8)) != 0
 }
 
-func (o *TLChannelFull) SetHasMigratedFromChatID(v bool) {
+func (o *TLMessage) SetHasFromID(v bool) {
 	if v {
-		o.Flags |= (1 << 4)
+		o.Flags |= (1 << 8)
 	} else {
-		o.Flags &= ^uint(1 << 4)
+		o.Flags &= ^uint(1 << 8)
 	}
 }
 
-func (o *TLChannelFull) HasMigratedFromMaxID() bool {
-	return (o.Flags & (1 << 4)) != 0
+func (o *TLMessage) HasFwdFrom() bool {
+	return (o.Flags & (1 << 2)) != 0
 }
 
-func (o *TLChannelFull) SetHasMigratedFromMaxID(v bool) {
+func (o *TLMessage) SetHasFwdFrom(v bool) {
 	if v {
-		o.Flags |= (1 << 4)
+		o.Flags |= (1 << 2)
 	} else {
-		o.Flags &= ^uint(1 << 4)
+		o.Flags &= ^uint(1 << 2)
 	}
 }
 
-func (o *TLChannelFull) HasPinnedMsgID() bool {
-	return (o.Flags & (1 << 5)) != 0
+func (o *TLMessage) HasViaBotID() bool {
+	return (o.Flags & (1 << 11)) != 0
 }
 
-func (o *TLChannelFull) SetHasPinnedMsgID(v bool) {
+func (o *TLMessage) SetHasViaBotID(v bool) {
 	if v {
-		o.Flags |= (1 << 5)
+		o.Flags |= (1 << 11)
 	} else {
-		o.Flags &= ^uint(1 << 5)
+		o.Flags &= ^uint(1 << 11)
 	}
 }
 
-func (o *TLChannelFull) String() string {
-	return tl.Pretty(o)
-}
-
-// TLChatParticipant represents ctor chatParticipant#c8d7493e user_id:int inviter_id:int date:int = ChatParticipant from Telegram
-type TLChatParticipant struct {
-	UserID    int // user_id:int
-	InviterID int // inviter_id:int
-	Date      int // date:int
-}
-
-func (o *TLChatParticipant) IsTLChatParticipant() {}
-
-func (o *TLChatParticipant) Cmd() uint32 {
-	return TagChatParticipant
-}
-
-func (o *TLChatParticipant) ReadBareFrom(r *tl.Reader) {
-	o.UserID = r.ReadInt()
-	o.InviterID = r.ReadInt()
-	o.Date = r.ReadInt()
-}
-
-func (o *TLChatParticipant) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.UserID)
-	w.WriteInt(o.InviterID)
-	w.WriteInt(o.Date)
+func (o *TLMessage) HasReplyToMsgID() bool {
+	return (o.Flags & (1 << 3)) != 0
 }
 
-func (o *TLChatParticipant) String() string {
-	return tl.Pretty(o)
+func (o *TLMessage) SetHasReplyToMsgID(v bool) {
+	if v {
+		o.Flags |= (1 << 3)
+	} else {
+		o.Flags &= ^uint(1 << 3)
+	}
 }
 
-// TLChatParticipantCreator represents ctor chatParticipantCreator#da13538a user_id:int = ChatParticipant from Telegram
-type TLChatParticipantCreator struct {
-	UserID int // user_id:int
+func (o *TLMessage) HasMedia() bool {
+	return (o.Flags & (1 << 9)) != 0
 }
 
-func (o *TLChatParticipantCreator) IsTLChatParticipant() {}
-
-func (o *TLChatParticipantCreator) Cmd() uint32 {
-	return TagChatParticipantCreator
+func (o *TLMessage) SetHasMedia(v bool) {
+	if v {
+		o.Flags |= (1 << 9)
+	} else {
+		o.Flags &= ^uint(1 << 9)
+	}
 }
 
-func (o *TLChatParticipantCreator) ReadBareFrom(r *tl.Reader) {
-	o.UserID = r.ReadInt()
+func (o *TLMessage) HasReplyMarkup() bool {
+	return (o.Flags & (1 << 6)) != 0
 }
 
-func (o *TLChatParticipantCreator) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.UserID)
+func (o *TLMessage) SetHasReplyMarkup(v bool) {
+	if v {
+		o.Flags |= (1 << 6)
+	} else {
+		o.Flags &= ^uint(1 << 6)
+	}
 }
 
-func (o *TLChatParticipantCreator) String() string {
-	return tl.Pretty(o)
+func (o *TLMessage) HasEntities() bool {
+	return (o.Flags & (1 << 7)) != 0
 }
 
-// TLChatParticipantAdmin represents ctor chatParticipantAdmin#e2d6e436 user_id:int inviter_id:int date:int = ChatParticipant from Telegram
-type TLChatParticipantAdmin struct {
-	UserID    int // user_id:int
-	InviterID int // inviter_id:int
-	Date      int // date:int
+func (o *TLMessage) SetHasEntities(v bool) {
+	if v {
+		o.Flags |= (1 << 7)
+	} else {
+		o.Flags &= ^uint(1 << 7)
+	}
 }
 
-func (o *TLChatParticipantAdmin) IsTLChatParticipant() {}
+func (o *TLMessage) HasViews() bool {
+	return (o.Flags & (1 << 10)) != 0
+}
 
-func (o *TLChatParticipantAdmin) Cmd() uint32 {
-	return TagChatParticipantAdmin
+func (o *TLMessage) SetHasViews(v bool) {
+	if v {
+		o.Flags |= (1 << 10)
+	} else {
+		o.Flags &= ^uint(1 << 10)
+	}
 }
 
-func (o *TLChatParticipantAdmin) ReadBareFrom(r *tl.Reader) {
-	o.UserID = r.ReadInt()
-	o.InviterID = r.ReadInt()
-	o.Date = r.ReadInt()
+func (o *TLMessage) HasEditDate() bool {
+	return (o.Flags & (1 << 15)) != 0
 }
 
-func (o *TLChatParticipantAdmin) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.UserID)
-	w.WriteInt(o.InviterID)
-	w.WriteInt(o.Date)
+func (o *TLMessage) SetHasEditDate(v bool) {
+	if v {
+		o.Flags |= (1 << 15)
+	} else {
+		o.Flags &= ^uint(1 << 15)
+	}
 }
 
-func (o *TLChatParticipantAdmin) String() string {
+func (o *TLMessage) String() string {
 	return tl.Pretty(o)
 }
 
-// TLChatParticipantsForbidden represents ctor chatParticipantsForbidden#fc900c2b flags:# chat_id:int flags.0?self_participant:ChatParticipant = ChatParticipants from Telegram
-type TLChatParticipantsForbidden struct {
-	Flags           uint                  // flags:#
-	ChatID          int                   // chat_id:int
-	SelfParticipant TLChatParticipantType // flags.0?self_participant:ChatParticipant
+// TLMessageService represents ctor messageService#9e19a1f6 flags:# flags.1?out:true flags.4?mentioned:true flags.5?media_unread:true flags.13?silent:true flags.14?post:true id:int flags.8?from_id:int to_id:Peer flags.3?reply_to_msg_id:int date:int action:MessageAction = Message from Telegram
+type TLMessageService struct {
+	Flags        uint                // flags:#
+	ID           int                 // id:int
+	FromID       int                 // flags.8?from_id:int
+	ToID         TLPeerType          // to_id:Peer
+	ReplyToMsgID int                 // flags.3?reply_to_msg_id:int
+	Date         int                 // date:int
+	Action       TLMessageActionType // action:MessageAction
 }
 
-func (o *TLChatParticipantsForbidden) IsTLChatParticipants() {}
+func (o *TLMessageService) IsTLMessage() {}
 
-func (o *TLChatParticipantsForbidden) Cmd() uint32 {
-	return TagChatParticipantsForbidden
+func (o *TLMessageService) Cmd() uint32 {
+	return TagMessageService
 }
 
-func (o *TLChatParticipantsForbidden) ReadBareFrom(r *tl.Reader) {
+func (o *TLMessageService) ReadBareFrom(r *tl.Reader) {
 	o.Flags = uint(r.ReadUint32())
-	o.ChatID = r.ReadInt()
-	if (o.Flags & (1 << 0)) != 0 {
-		o.SelfParticipant = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipant, TagChatParticipantCreator, TagChatParticipantAdmin).(TLChatParticipantType)
+	o.ID = r.ReadInt()
+	if (o.Flags & (1 << 8)) != 0 {
+		o.FromID = r.ReadInt()
+	}
+	o.ToID = Schema.ReadLimitedBoxedObjectFrom(r, TagPeerUser, TagPeerChat, TagPeerChannel).(TLPeerType)
+	if (o.Flags & (1 << 3)) != 0 {
+		o.ReplyToMsgID = r.ReadInt()
 	}
+	o.Date = r.ReadInt()
+	o.Action = Schema.ReadLimitedBoxedObjectFrom(r, TagMessageActionEmpty, TagMessageActionChatCreate, TagMessageActionChatEditTitle, TagMessageActionChatEditPhoto, TagMessageActionChatDeletePhoto, TagMessageActionChatAddUser, TagMessageActionChatDeleteUser, TagMessageActionChatJoinedByLink, TagMessageActionChannelCreate, TagMessageActionChatMigrateTo, TagMessageActionChannelMigrateFrom, TagMessageActionPinMessage, TagMessageActionHistoryClear, TagMessageActionGameScore, TagMessageActionPaymentSentMe, TagMessageActionPaymentSent, TagMessageActionPhoneCall).(TLMessageActionType)
 }
 
-func (o *TLChatParticipantsForbidden) WriteBareTo(w *tl.Writer) {
+func (o *TLMessageService) WriteBareTo(w *tl.Writer) {
 	w.WriteUint32(uint32(o.Flags))
-	w.WriteInt(o.ChatID)
-	if (o.Flags & (1 << 0)) != 0 {
-		w.WriteCmd(o.SelfParticipant.Cmd())
-		o.SelfParticipant.WriteBareTo(w)
+	w.WriteInt(o.ID)
+	if (o.Flags & (1 << 8)) != 0 {
+		w.WriteInt(o.FromID)
+	}
+	w.WriteCmd(o.ToID.Cmd())
+	o.ToID.WriteBareTo(w)
+	if (o.Flags & (1 << 3)) != 0 {
+		w.WriteInt(o.ReplyToMsgID)
 	}
+	w.WriteInt(o.Date)
+	w.WriteCmd(o.Action.Cmd())
+	o.Action.WriteBareTo(w)
 }
 
-func (o *TLChatParticipantsForbidden) HasSelfParticipant() bool {
-	return (o.Flags & (1 << 0)) != 0
+func (o *TLMessageService) Out() bool {
+	return (o.Flags & (1 << 1)) != 0
 }
 
-func (o *TLChatParticipantsForbidden) SetHasSelfParticipant(v bool) {
+func (o *TLMessageService) SetOut(v bool) {
 	if v {
-		o.Flags |= (1 << 0)
+		o.Flags |= (1 << 1)
 	} else {
-		o.Flags &= ^uint(1 << 0)
+		o.Flags &= ^uint(1 << 1)
 	}
 }
 
-func (o *TLChatParticipantsForbidden) String() string {
-	return tl.Pretty(o)
-}
-
-// TLChatParticipants represents ctor chatParticipants#3f460fed chat_id:int participants:Vector<ChatParticipant> version:int = ChatParticipants from Telegram
-type TLChatParticipants struct {
-	ChatID       int                     // chat_id:int
-	Participants []TLChatParticipantType // participants:Vector<ChatParticipant>
-	Version      int                     // version:int
-}
-
-func (o *TLChatParticipants) IsTLChatParticipants() {}
-
-func (o *TLChatParticipants) Cmd() uint32 {
-	return TagChatParticipants
-}
-
-func (o *TLChatParticipants) ReadBareFrom(r *tl.Reader) {
-	o.ChatID = r.ReadInt()
-	if cmd := r.ReadCmd(); cmd != TagVector {
-		r.Fail(errors.New("expected: vector"))
-	}
-	o.Participants = make([]TLChatParticipantType, r.ReadInt())
-	for i := 0; i < len(o.Participants); i++ {
-		o.Participants[i] = Schema.ReadLimitedBoxedObjectFrom(r, TagChatParticipant, TagChatParticipantCreator, TagChatParticipantAdmin).(TLChatParticipantType)
-	}
-	o.Version = r.ReadInt()
+func (o *TLMessageService) Mentioned() bool {
+	return (o.Flags & (1 << 4)) != 0
 }
 
-func (o *TLChatParticipants) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(o.ChatID)
-	w.WriteCmd(TagVector)
-	w.WriteInt(len(o.Participants))
-	for i := 0; i < len(o.Participants); i++ {
-		w.WriteCmd(o.Participants[i].Cmd())
-		o.Participants[i].WriteBareTo(w)
+func (o *TLMessageService) SetMentioned(v bool) {
+	if v {
+		o.Flags |= (1 << 4)
+	} else {
+		o.Flags &= ^uint(1 << 4)
 	}
-	w.WriteInt(o.Version)
 }
 
-func (o *TLChatParticipants) String() string {
-	return tl.Pretty(o)
+func (o *TLMessageService) MediaUnread() bool {
+	return (o.Flags & (1 << 5)) != 0
 }
 
-// TLChatPhotoEmpty represents ctor chatPhotoEmpty#37c1011c = ChatPhoto from Telegram
-type TLChatPhotoEmpty struct {
+func (o *TLMessageService) SetMediaUnread(v bool) {
+	if v {
+		o.Flags |= (1 << 5)
+	} else {
+		o.Flags &= ^uint(1 << 5)
+	}
 }
 
-func (o *TLChatPhotoEmpty) IsTLChatPhoto() {}
-
-func (o *TLChatPhotoEmpty) Cmd() uint32 {
-	return TagChatPhotoEmpty
+func (o *TLMessageService) Silent() bool {
+	return (o.Flags & (1 << 13)) != 0
 }
 
-func (o *TLChatPhotoEmpty) ReadBareFrom(r *tl.Reader) {
+func (o *TLMessageService) SetSilent(v bool) {
+	if v {
+		o.Flags |= (1 << 13)
+	} else {
+		o.Flags &= ^uint(1 << 13)
+	}
 }
 
-func (o *TLChatPhotoEmpty) WriteBareTo(w *tl.Writer) {
+func (o *TLMessageService) Post() bool {
+	return (o.Flags & (1 << 14)) != 0
 }
 
-func (o *TLChatPhotoEmpty) String() string {
-	return tl.Pretty(o)
+func (o *TLMessageService) SetPost(v bool) {
+	if v {
+		o.Flags |= (1 << 14)
+	} else {
+		o.Flags &= ^uint(1 << 14)
+	}
 }
 
-// TLChatPhoto represents ctor chatPhoto#6153276a photo_small:FileLocation photo_big:FileLocation = ChatPhoto from Telegram
-type TLChatPhoto struct {
-	PhotoSmall TLFileLocationType // photo_small:FileLocation
-	PhotoBig   TLFileLocationType // photo_big:FileLocation
+func (o *TLMessageService) HasFromID() bool {
+	return (o.Flags & (1 << 8)) != 0
 }
 
-func (o *TLChatPhoto) IsTLChatPhoto() {}
-
-func (o *TLChatPhoto) Cmd() uint32 {
-	return TagChatPhoto
+func (o *TLMessageService) SetHasFromID(v bool) {
+	if v {
+		o.Flags |= (1 << 8)
+	} else {
+		o.Flags &= ^uint(1 << 8)
+	}
 }
 
-func (o *TLChatPhoto) ReadBareFrom(r *tl.Reader) {
-	o.PhotoSmall = Schema.ReadLimitedBoxedObjectFrom(r, TagFileLocationUnavailable, TagFileLocation).(TLFileLocationType)
-	o.PhotoBig = Schema.ReadLimitedBoxedObjectFrom(r, TagFileLocationUnavailable, TagFileLocation).(TLFileLocationType)
+func (o *TLMessageService) HasReplyToMsgID() bool {
+	return (o.Flags & (1 << 3)) != 0
 }
 
-func (o *TLChatPhoto) WriteBareTo(w *tl.Writer) {
-	w.WriteCmd(o.PhotoSmall.Cmd())
-	o.PhotoSmall.WriteBareTo(w)
-	w.WriteCmd(o.PhotoBig.Cmd())
-	o.PhotoBig.WriteBareTo(w)
+func (o *TLMessageService) SetHasReplyToMsgID(v bool) {
+	if v {
+		o.Flags |= (1 << 3)
+	} else {
+		o.Flags &= ^uint(1 << 3)
+	}
 }
 
-func (o *TLChatPhoto) String() string {
+func (o *TLMessageService) String() string {
 	return tl.Pretty(o)
 }
 
@@ -31249,8 +31337,6 @@ var Schema = &tl.Schema{
 		switch cmd {
 		case TagResPQ:
 			return new(TLResPQ)
-		case TagPQInnerData:
-			return new(TLPQInnerData)
 		case TagServerDHInnerData:
 			return new(TLServerDHInnerData)
 		case TagClientDHInnerData:
@@ -31275,6 +31361,8 @@ var Schema = &tl.Schema{
 			return new(TLMsgCopy)
 		case TagMsgsAck:
 			return new(TLMsgsAck)
+		case TagBindAuthKeyInner:
+			return new(TLBindAuthKeyInner)
 		case TagMsgResendReq:
 			return new(TLMsgResendReq)
 		case TagMsgsStateReq:
@@ -31903,6 +31991,10 @@ var Schema = &tl.Schema{
 			return new(TLPhoneSetCallRating)
 		case TagPhoneSaveCallDebug:
 			return new(TLPhoneSaveCallDebug)
+		case TagPQInnerData:
+			return new(TLPQInnerData)
+		case TagPQInnerDataTemp:
+			return new(TLPQInnerDataTemp)
 		case TagServerDHParamsFail:
 			return new(TLServerDHParamsFail)
 		case TagServerDHParamsOK:
@@ -31923,12 +32015,6 @@ var Schema = &tl.Schema{
 			return new(TLDestroySessionOK)
 		case TagDestroySessionNone:
 			return new(TLDestroySessionNone)
-		case TagMessageEmpty:
-			return new(TLMessageEmpty)
-		case TagMessage:
-			return new(TLMessage)
-		case TagMessageService:
-			return new(TLMessageService)
 		case TagBadMsgNotification:
 			return new(TLBadMsgNotification)
 		case TagBadServerSalt:
@@ -32083,6 +32169,12 @@ var Schema = &tl.Schema{
 			return new(TLChatPhotoEmpty)
 		case TagChatPhoto:
 			return new(TLChatPhoto)
+		case TagMessageEmpty:
+			return new(TLMessageEmpty)
+		case TagMessage:
+			return new(TLMessage)
+		case TagMessageService:
+			return new(TLMessageService)
 		case TagMessageMediaEmpty:
 			return new(TLMessageMediaEmpty)
 		case TagMessageMediaPhoto:
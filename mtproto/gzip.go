@@ -0,0 +1,22 @@
+package mtproto
+
+import (
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// compressMsg wraps content messages larger than threshold bytes into
+// gzip_packed if that makes them smaller. A zero threshold disables this.
+func compressMsg(msg Msg, threshold int) Msg {
+	if threshold <= 0 || msg.Type != ContentMsg || len(msg.Payload) <= threshold {
+		return msg
+	}
+
+	packed, err := tl.GzipPackBytes(msg.Payload)
+	if err != nil {
+		return msg
+	}
+	if data := tl.Bytes(packed); len(data) < len(msg.Payload) {
+		msg.Payload = data
+	}
+	return msg
+}
@@ -0,0 +1,57 @@
+package mtproto
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func TestCompressMsg(t *testing.T) {
+	long := &TLMessagesSendMessage{
+		Peer:     &TLInputPeerSelf{},
+		Message:  strings.Repeat("all work and no play makes Jack a dull boy ", 100),
+		RandomID: 1,
+	}
+	short := &TLMessagesSendMessage{
+		Peer:     &TLInputPeerSelf{},
+		Message:  "hi",
+		RandomID: 2,
+	}
+
+	msg := compressMsg(MsgFromObj(long), 256)
+	if tl.CmdOfPayload(msg.Payload) != tl.TagGzipPacked {
+		t.Fatalf("long message not compressed")
+	}
+	if len(msg.Payload) >= len(tl.Bytes(long)) {
+		t.Errorf("compressed to %d bytes, expected less than %d", len(msg.Payload), len(tl.Bytes(long)))
+	}
+	o, err := Schema.ReadBoxedObject(msg.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tl.Bytes(o), tl.Bytes(long)) {
+		t.Errorf("decompressed to %v, expected %v", o, long)
+	}
+
+	if msg := compressMsg(MsgFromObj(short), 256); !bytes.Equal(msg.Payload, tl.Bytes(short)) {
+		t.Errorf("short message compressed")
+	}
+	if msg := compressMsg(MsgFromObj(long), 0); !bytes.Equal(msg.Payload, tl.Bytes(long)) {
+		t.Errorf("message compressed with zero threshold")
+	}
+
+	// incompressible data stays as is
+	noise := make([]byte, 512)
+	rand.New(rand.NewSource(1)).Read(noise)
+	random := &TLMessagesSendMessage{
+		Peer:     &TLInputPeerSelf{},
+		Message:  string(noise),
+		RandomID: 3,
+	}
+	if msg := compressMsg(MsgFromObj(random), 256); !bytes.Equal(msg.Payload, tl.Bytes(random)) {
+		t.Errorf("incompressible message replaced with gzip_packed")
+	}
+}
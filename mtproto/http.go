@@ -0,0 +1,196 @@
+package mtproto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// how long the server may hold an http_wait request before responding
+const defaultHTTPMaxWait = 25 * time.Second
+
+// PollingTransport is implemented by transports that can only receive data in
+// response to requests. The session sends http_wait whenever PollNeeded fires,
+// so that the server has a request to push messages through.
+type PollingTransport interface {
+	Transport
+	PollNeeded() <-chan struct{}
+	MaxWait() time.Duration
+}
+
+type HTTPTransportOptions struct {
+	MaxMsgLen int
+
+	// defaults to a client that honors the HTTP_PROXY environment variable
+	Client *http.Client
+
+	// used to establish connections when Client is not set
+	Dialer Dialer
+
+	// max time the server may hold a long-polling request, defaults to 25 seconds
+	MaxWait time.Duration
+}
+
+// HTTPTransport runs MTProto over HTTP POST requests to /api. Each outgoing
+// message is a request body, and each response body carries an incoming message.
+type HTTPTransport struct {
+	options HTTPTransportOptions
+	URL     string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	recvc  chan []byte
+	errc   chan error
+	pollc  chan struct{}
+	mut    sync.Mutex
+	active int
+}
+
+// NewHTTPTransport creates a transport posting to the given URL, e.g. http://149.154.167.50:80/api.
+func NewHTTPTransport(url string, options HTTPTransportOptions) *HTTPTransport {
+	if options.MaxMsgLen == 0 {
+		options.MaxMsgLen = 1024 * 1024 * 10
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{}
+		if options.Dialer != nil {
+			options.Client.Transport = &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				Dial:  options.Dialer.Dial,
+			}
+		}
+	}
+	if options.MaxWait == 0 {
+		options.MaxWait = defaultHTTPMaxWait
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HTTPTransport{
+		options: options,
+		URL:     url,
+		ctx:     ctx,
+		cancel:  cancel,
+		recvc:   make(chan []byte, 16),
+		errc:    make(chan error, 1),
+		pollc:   make(chan struct{}, 1),
+	}
+}
+
+func (tr *HTTPTransport) Close() {
+	tr.cancel()
+}
+
+func (tr *HTTPTransport) Send(data []byte) error {
+	req, err := http.NewRequest("POST", tr.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(tr.ctx)
+
+	tr.mut.Lock()
+	tr.active++
+	tr.mut.Unlock()
+
+	go tr.post(req)
+	return nil
+}
+
+func (tr *HTTPTransport) post(req *http.Request) {
+	raw, err := tr.roundTrip(req)
+
+	tr.mut.Lock()
+	tr.active--
+	idle := (tr.active == 0)
+	tr.mut.Unlock()
+
+	if err != nil {
+		if tr.ctx.Err() == nil {
+			select {
+			case tr.errc <- err:
+			default:
+			}
+		}
+		return
+	}
+
+	if len(raw) > 0 {
+		select {
+		case tr.recvc <- raw:
+		case <-tr.ctx.Done():
+			return
+		}
+	}
+
+	if idle {
+		select {
+		case tr.pollc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (tr *HTTPTransport) roundTrip(req *http.Request) ([]byte, error) {
+	resp, err := tr.options.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(tr.options.MaxMsgLen)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > tr.options.MaxMsgLen {
+		return nil, errors.New("message too large")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if len(raw) == 4 {
+			// error code, passed on to Recv
+			return raw, nil
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return int32Bytes(-404), nil
+		}
+		return nil, fmt.Errorf("HTTP %v", resp.Status)
+	}
+	return raw, nil
+}
+
+func (tr *HTTPTransport) Recv() ([]byte, int, error) {
+	select {
+	case raw := <-tr.recvc:
+		if len(raw) == 4 {
+			errcode := int(int32(tl.NewReader(raw).Cmd()))
+			return nil, errcode, nil
+		}
+		return raw, 0, nil
+	case err := <-tr.errc:
+		return nil, 0, err
+	case <-tr.ctx.Done():
+		return nil, 0, io.EOF
+	}
+}
+
+func (tr *HTTPTransport) PollNeeded() <-chan struct{} {
+	return tr.pollc
+}
+
+func (tr *HTTPTransport) MaxWait() time.Duration {
+	return tr.options.MaxWait
+}
+
+func int32Bytes(v int32) []byte {
+	w := tl.NewWriter()
+	w.WriteInt(int(v))
+	return w.Bytes()
+}
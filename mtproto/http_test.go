@@ -0,0 +1,90 @@
+package mtproto
+
+import (
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api" {
+			t.Errorf("got %v %v", r.Method, r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		switch hex.EncodeToString(body) {
+		case "0102030405060708":
+			w.Write(fromHex("1112131415161718"))
+		case "00000000":
+			// long poll that timed out without any messages
+		case "ffffffff":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(fromHex("6cfeffff"))
+		default:
+			t.Errorf("unexpected body %x", body)
+		}
+	}))
+	defer srv.Close()
+
+	tr := NewHTTPTransport(srv.URL+"/api", HTTPTransportOptions{})
+
+	err := tr.Send(fromHex("0102030405060708"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, errcode, err := tr.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, e := hex.EncodeToString(raw), "1112131415161718"; a != e || errcode != 0 {
+		t.Errorf("received %v (code %v), expected %v", a, errcode, e)
+	}
+	<-tr.PollNeeded()
+
+	// empty responses only ask for another poll
+	err = tr.Send(fromHex("00000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-tr.PollNeeded()
+
+	err = tr.Send(fromHex("ffffffff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, errcode, err = tr.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != nil || errcode != -404 {
+		t.Errorf("received %x (code %v), expected code -404", raw, errcode)
+	}
+
+	tr.Close()
+	_, _, err = tr.Recv()
+	if err != io.EOF {
+		t.Errorf("got %v after Close, expected EOF", err)
+	}
+}
+
+func TestHTTPTransportServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tr := NewHTTPTransport(srv.URL+"/api", HTTPTransportOptions{})
+	defer tr.Close()
+
+	err := tr.Send(fromHex("0102030405060708"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = tr.Recv()
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,114 @@
+package mtproto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+)
+
+// smallest possible MTProto packet is an unencrypted message with an empty payload
+const minPacketLen = 8 + 8 + 4
+
+func ReadIntermediateTCPMessageLen(r io.Reader) (int, error) {
+	n, err := binints.ReadUint32LE(r)
+	if err != nil {
+		return -1, err
+	}
+	if n > math.MaxInt32 {
+		return -1, errors.New("unexpected message length >2^31")
+	}
+	return int(n), nil
+}
+
+func ReadIntermediateTCPMessage(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
+	return readTCPMessage(r, ReadIntermediateTCPMessageLen, maxMsgLen, firstByteTimeout, msgTimeout)
+}
+
+func formatIntermediateTCPMessage(data []byte, padding []byte) []byte {
+	var buf bytes.Buffer
+
+	if len(data) == 0 {
+		panic("Cannot send empty message")
+	}
+
+	binints.WriteUint32LE(&buf, uint32(len(data)+len(padding)))
+	buf.Write(data)
+	buf.Write(padding)
+
+	return buf.Bytes()
+}
+
+// stripTCPPadding removes 0-15 bytes of random padding added by the padded
+// intermediate framing. It relies on the structure of MTProto packets:
+// unencrypted messages carry their own length, encrypted ones consist of
+// a 24-byte header followed by whole AES blocks, and anything shorter than
+// a message is a 4-byte error code.
+func stripTCPPadding(raw []byte) []byte {
+	if len(raw) < minPacketLen {
+		if len(raw) >= 4 {
+			return raw[:4]
+		}
+		return raw
+	}
+
+	if binints.DecodeUint64LE(raw[0:8]) == 0 {
+		n := minPacketLen + int(binints.DecodeUint32LE(raw[16:20]))
+		if n <= len(raw) {
+			return raw[:n]
+		}
+		return raw
+	}
+
+	return raw[:len(raw)-(len(raw)-24)%16]
+}
+
+type intermediateCodec struct {
+	padded bool
+	random io.Reader
+}
+
+func (c *intermediateCodec) Tag() []byte {
+	if c.padded {
+		return []byte{0xDD, 0xDD, 0xDD, 0xDD}
+	} else {
+		return []byte{0xEE, 0xEE, 0xEE, 0xEE}
+	}
+}
+
+func (c *intermediateCodec) ObfuscatedTag() []byte {
+	return c.Tag()
+}
+
+func (c *intermediateCodec) Format(data []byte) ([]byte, error) {
+	if !c.padded {
+		return formatIntermediateTCPMessage(data, nil), nil
+	}
+
+	var b [1]byte
+	_, err := io.ReadFull(c.random, b[:])
+	if err != nil {
+		return nil, err
+	}
+	padding := make([]byte, int(b[0]%16))
+	_, err = io.ReadFull(c.random, padding)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatIntermediateTCPMessage(data, padding), nil
+}
+
+func (c *intermediateCodec) Read(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
+	raw, err := ReadIntermediateTCPMessage(r, maxMsgLen, firstByteTimeout, msgTimeout)
+	if err != nil || raw == nil {
+		return raw, err
+	}
+	if c.padded {
+		raw = stripTCPPadding(raw)
+	}
+	return raw, nil
+}
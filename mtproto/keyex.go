@@ -2,7 +2,6 @@ package mtproto
 
 import (
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/subtle"
 	"encoding/hex"
@@ -12,6 +11,7 @@ import (
 	"io"
 	"log"
 	"math/big"
+	"time"
 )
 
 type keyExState int
@@ -34,11 +34,30 @@ type AuthResult struct {
 	ServerSalt [8]byte
 	TimeOffset int
 	SessionID  [8]byte
+
+	// ExpiresAt is the Unix time a temporary key expires at, zero for permanent keys
+	ExpiresAt int
+
+	// FutureSalts are the upcoming server salts, sorted by ValidSince
+	FutureSalts []FutureSalt
 }
 
 type KeyEx struct {
 	RandomReader io.Reader
-	PubKey       *rsa.PublicKey
+
+	// PubKeys are the trusted server keys, one of which the server must offer
+	PubKeys KeyRing
+
+	// ExpiresIn requests a temporary key valid for this many seconds
+	ExpiresIn int
+
+	// Now returns the local time to check server_time against, defaults to time.Now
+	Now func() time.Time
+
+	// trustedDH skips the dh_prime and g checks for these exact parameters;
+	// only the known-answer test needs it, its recorded exchange uses a g
+	// that the checks rightly reject
+	trustedDH *dhParams
 
 	state keyExState
 	err   error
@@ -135,8 +154,16 @@ func (kex *KeyEx) handle(o tl.Object) (tl.Object, error) {
 		case *TLDHGenOK:
 			return kex.handleDHGenOK(o)
 		case *TLDHGenFail:
+			err := kex.checkDHGenAnswer(o.Nonce, o.ServerNonce, 3, o.NewNonceHash3)
+			if err != nil {
+				return nil, err
+			}
 			return nil, errors.New("got dh_gen_fail")
 		case *TLDHGenRetry:
+			err := kex.checkDHGenAnswer(o.Nonce, o.ServerNonce, 2, o.NewNonceHash2)
+			if err != nil {
+				return nil, err
+			}
 			return nil, errors.New("got dh_gen_retry")
 		default:
 			return nil, ErrUnexpectedCommand
@@ -147,40 +174,18 @@ func (kex *KeyEx) handle(o tl.Object) (tl.Object, error) {
 	}
 }
 
-func (kex *KeyEx) handleNoncePair(r *tl.Reader) error {
-	var nonce [16]byte
-	if r.ReadUint128(nonce[:]) {
-		if 1 != subtle.ConstantTimeCompare(nonce[:], kex.nonce[:]) {
-			return errors.New("bad nonce")
-		}
-	}
-	if r.ReadUint128(nonce[:]) {
-		if 1 != subtle.ConstantTimeCompare(nonce[:], kex.nonce[:]) {
-			return errors.New("bad server nonce")
-		}
-	}
-	return nil
-}
-
 func (kex *KeyEx) handleResPQ(in *TLResPQ) (tl.Object, error) {
 	if 1 != subtle.ConstantTimeCompare(in.Nonce[:], kex.nonce[:]) {
-		return nil, errors.New("bad nonce")
+		return nil, ErrBadNonce
 	}
 
 	copy(kex.serverNonce[:], in.ServerNonce[:])
 
 	//log.Printf("res_pq: %+#v", *in)
 
-	expectedFingerprint := ComputePubKeyFingerprint(kex.PubKey)
-	var keyOK bool
-	for _, fingerprint := range in.ServerPublicKeyFingerprints {
-		if fingerprint == expectedFingerprint {
-			keyOK = true
-		}
-	}
-	if !keyOK {
-		log.Printf("res_pq public key fingerprints = %v, wanted %v", in.ServerPublicKeyFingerprints, expectedFingerprint)
-		return nil, errors.New("public key fingerprint mismatch")
+	pubKey, fingerprint, err := kex.PubKeys.Select(in.ServerPublicKeyFingerprints)
+	if err != nil {
+		return nil, err
 	}
 
 	if in.PQ.BitLen() > 64 {
@@ -191,7 +196,7 @@ func (kex *KeyEx) handleResPQ(in *TLResPQ) (tl.Object, error) {
 	p, q := factorize(pqn)
 	// log.Printf("mtproto/keyex: %v = %v (p) * %v (q)", pqn, p, q)
 
-	_, err := io.ReadFull(kex.RandomReader, kex.newNonce[:])
+	_, err = io.ReadFull(kex.RandomReader, kex.newNonce[:])
 	if err != nil {
 		panic(err)
 	}
@@ -201,22 +206,35 @@ func (kex *KeyEx) handleResPQ(in *TLResPQ) (tl.Object, error) {
 		panic(err)
 	}
 
-	inner := &TLPQInnerData{
-		PQ: in.PQ,
-		P:  big.NewInt(int64(p)),
-		Q:  big.NewInt(int64(q)),
+	var inner TLPQInnerDataType
+	if kex.ExpiresIn != 0 {
+		inner = &TLPQInnerDataTemp{
+			PQ:          in.PQ,
+			P:           big.NewInt(int64(p)),
+			Q:           big.NewInt(int64(q)),
+			Nonce:       kex.nonce,
+			ServerNonce: kex.serverNonce,
+			NewNonce:    kex.newNonce,
+			ExpiresIn:   kex.ExpiresIn,
+		}
+	} else {
+		inner = &TLPQInnerData{
+			PQ:          in.PQ,
+			P:           big.NewInt(int64(p)),
+			Q:           big.NewInt(int64(q)),
+			Nonce:       kex.nonce,
+			ServerNonce: kex.serverNonce,
+			NewNonce:    kex.newNonce,
+		}
 	}
-	copy(inner.Nonce[:], kex.nonce[:])
-	copy(inner.ServerNonce[:], kex.serverNonce[:])
-	copy(inner.NewNonce[:], kex.newNonce[:])
 
 	// TODO: fill randomPadding
 
 	m := &TLReqDHParams{
-		P:                    inner.P,
-		Q:                    inner.Q,
-		PublicKeyFingerprint: expectedFingerprint,
-		EncryptedData:        EncryptRSAWithHash(tl.Bytes(inner), randomPadding[:], kex.PubKey),
+		P:                    big.NewInt(int64(p)),
+		Q:                    big.NewInt(int64(q)),
+		PublicKeyFingerprint: fingerprint,
+		EncryptedData:        EncryptRSAWithHash(tl.Bytes(inner), randomPadding[:], pubKey),
 	}
 	copy(m.Nonce[:], kex.nonce[:])
 	copy(m.ServerNonce[:], kex.serverNonce[:])
@@ -226,13 +244,8 @@ func (kex *KeyEx) handleResPQ(in *TLResPQ) (tl.Object, error) {
 }
 
 func (kex *KeyEx) handleServerDHParamsOK(o *TLServerDHParamsOK) (tl.Object, error) {
-	if 1 != subtle.ConstantTimeCompare(o.Nonce[:], kex.nonce[:]) {
-		// log.Printf("server_dh_params_ok nonce = %v, wanted %v", o.Nonce[:], kex.nonce[:])
-		return nil, errors.New("bad nonce")
-	}
-	if 1 != subtle.ConstantTimeCompare(o.ServerNonce[:], kex.serverNonce[:]) {
-		// log.Printf("server_dh_params_ok server nonce = %v, wanted %v", o.Nonce[:], kex.serverNonce[:])
-		return nil, errors.New("bad server nonce")
+	if !kex.isOwnNoncePair(o.Nonce, o.ServerNonce) {
+		return nil, ErrBadNonce
 	}
 
 	deriveTempAESKey(kex.serverNonce[:], kex.newNonce[:], kex.tmpAESKey[:], kex.tmpAESIV[:])
@@ -254,31 +267,39 @@ func (kex *KeyEx) handleServerDHParamsOK(o *TLServerDHParamsOK) (tl.Object, erro
 		log.Printf("Decrypted: %v", hex.EncodeToString(answer))
 	}
 
-	// TODO: check hash here (need to determine the reader offset here)
-	_ = answerHash
-
 	rawinner, err := Schema.ReadLimitedBoxedObjectNoEOFCheck(answer, TagServerDHInnerData)
 	if err != nil {
 		return nil, err
 	}
 	inner := rawinner.(*TLServerDHInnerData)
 
-	if 1 != subtle.ConstantTimeCompare(inner.Nonce[:], kex.nonce[:]) {
-		return nil, errors.New("bad nonce")
+	// VERIFICATION
+
+	hash := sha1.Sum(tl.Bytes(inner))
+	if 1 != subtle.ConstantTimeCompare(hash[:], answerHash) {
+		return nil, ErrBadEncryptedAnswer
 	}
-	if 1 != subtle.ConstantTimeCompare(inner.ServerNonce[:], kex.serverNonce[:]) {
-		return nil, errors.New("bad server nonce")
+	if !kex.isOwnNoncePair(inner.Nonce, inner.ServerNonce) {
+		return nil, ErrBadNonce
 	}
 
-	kex.g = inner.G
-	kex.dhPrime = inner.DHPrime
-
-	// VERIFICATION
+	now := kex.now()
+	err = checkServerTime(inner.ServerTime, now)
+	if err != nil {
+		return nil, err
+	}
+	kex.auth.TimeOffset = int(inner.ServerTime.Unix() - now.Unix())
 
-	if !kex.dhPrime.ProbablyPrime(20) {
-		return nil, errors.New("DHPrime not prime")
+	err = kex.checkDHParams(inner.DHPrime, inner.G)
+	if err != nil {
+		return nil, err
 	}
-	// TODO: more checks required by MTProto protocol
+	if !isValidDHPublic(inner.GA, inner.DHPrime) {
+		return nil, ErrBadGA
+	}
+
+	kex.g = inner.G
+	kex.dhPrime = inner.DHPrime
 
 	// PROCESSING
 
@@ -294,6 +315,9 @@ func (kex *KeyEx) handleServerDHParamsOK(o *TLServerDHParamsOK) (tl.Object, erro
 
 	gb := new(big.Int)
 	gb.Exp(big.NewInt(int64(kex.g)), kex.b, kex.dhPrime)
+	if !isValidDHPublic(gb, kex.dhPrime) {
+		return nil, ErrBadGB
+	}
 
 	gab := new(big.Int)
 	gab.Exp(inner.GA, kex.b, kex.dhPrime)
@@ -333,23 +357,61 @@ func (kex *KeyEx) handleServerDHParamsOK(o *TLServerDHParamsOK) (tl.Object, erro
 }
 
 func (kex *KeyEx) handleDHGenOK(in *TLDHGenOK) (tl.Object, error) {
-	if 1 != subtle.ConstantTimeCompare(in.Nonce[:], kex.nonce[:]) {
-		// log.Printf("server_dh_params_ok nonce = %v, wanted %v", in.Nonce[:], kex.nonce[:])
-		return nil, errors.New("bad nonce")
-	}
-	if 1 != subtle.ConstantTimeCompare(in.ServerNonce[:], kex.serverNonce[:]) {
-		// log.Printf("server_dh_params_ok server nonce = %v, wanted %v", in.Nonce[:], kex.serverNonce[:])
-		return nil, errors.New("bad server nonce")
+	err := kex.checkDHGenAnswer(in.Nonce, in.ServerNonce, 1, in.NewNonceHash1)
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: check in.NewNonceHash1
-
 	log.Printf("✓ Key exchange complete")
 
+	if kex.ExpiresIn != 0 {
+		// expires_at is in server time, like everything the server checks
+		kex.auth.ExpiresAt = int(kex.now().Unix()) + kex.auth.TimeOffset + kex.ExpiresIn
+	}
+
 	kex.state = KeyExDone
 	return nil, nil
 }
 
+func (kex *KeyEx) checkDHParams(p *big.Int, g int) error {
+	if t := kex.trustedDH; t != nil && t.g == g && t.prime.Cmp(p) == 0 {
+		return nil
+	}
+	err := checkDHPrime(p)
+	if err != nil {
+		return err
+	}
+	return checkDHGenerator(p, g)
+}
+
+func (kex *KeyEx) now() time.Time {
+	if kex.Now != nil {
+		return kex.Now()
+	}
+	return time.Now()
+}
+
+func (kex *KeyEx) isOwnNoncePair(nonce, serverNonce [16]byte) bool {
+	return 1 == subtle.ConstantTimeCompare(nonce[:], kex.nonce[:]) && 1 == subtle.ConstantTimeCompare(serverNonce[:], kex.serverNonce[:])
+}
+
+// checkDHGenAnswer verifies the nonces and new_nonce_hashN of dh_gen_ok (n=1),
+// dh_gen_retry (n=2) or dh_gen_fail (n=3).
+func (kex *KeyEx) checkDHGenAnswer(nonce, serverNonce [16]byte, n byte, newNonceHash [16]byte) error {
+	if !kex.isOwnNoncePair(nonce, serverNonce) {
+		return ErrBadNonce
+	}
+
+	var auxHash [8]byte
+	binints.EncodeUint64LE(kex.authKeyAuxHash, auxHash[:])
+	var expected [16]byte
+	computeNewNonceHash(kex.newNonce[:], n, auxHash[:], expected[:])
+	if 1 != subtle.ConstantTimeCompare(newNonceHash[:], expected[:]) {
+		return ErrBadNewNonceHash
+	}
+	return nil
+}
+
 func deriveTempAESKey(serverNonce, newNonce []byte, key, iv []byte) {
 	if len(key) != 32 {
 		panic("len(key) != 32")
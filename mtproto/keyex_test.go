@@ -5,6 +5,9 @@ import (
 	"encoding/hex"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
 )
 
 const randomness = `
@@ -148,16 +151,29 @@ EC 7F B0 A0 EE D6 C2 20
 
 const expectedKeyStr = `AB96E207C631300986F30EF97DF55E179E63C112675F0CE502EE76D74BBEE6CBD1E95772818881E9F2FF54BD52C258787474F6A7BEA61EABE49D1D01D55F64FC07BC31685716EC8FB46FEACF9502E42CFD6B9F45A08E90AA5C2B5933AC767CBE1CD50D8E64F89727CA4A1A5D32C0DB80A9FCDBDDD4F8D5A1E774198F1A4299F927C484FEEC395F29647E43C3243986F93609E23538C21871DF50E00070B3B6A8FA9BC15628E8B43FF977409A61CEEC5A21CF7DFB5A4CC28F5257BC30CD8F2FB92FBF21E28924065F50E0BBD5E11A420300E2C136B80E9826C6C5609B5371B7850AA628323B6422F3A94F6DFDE4C3DC1EA60F7E11EE63122B3F39CBD1A8430157`
 
-func TestKeyExchange(t *testing.T) {
-	var keyex KeyEx
-	var framer Framer
+// newSampleKeyEx returns a KeyEx that replays the recorded exchange.
+func newSampleKeyEx(t *testing.T) *KeyEx {
+	keyex := &KeyEx{
+		RandomReader: bytes.NewReader(fromHex(randomness)),
+		Now: func() time.Time {
+			// the samples were recorded in July 2013
+			return time.Unix(0x51e57acb, 0)
+		},
+	}
 	var err error
-
-	keyex.RandomReader = bytes.NewReader(fromHex(randomness))
-	keyex.PubKey, err = ParsePublicKey(publicKey)
+	keyex.PubKeys, err = ParseKeyRing(publicKey)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return keyex
+}
+
+func TestKeyExchange(t *testing.T) {
+	var framer Framer
+
+	keyex := newSampleKeyEx(t)
+	// the samples use g=2, see TestKeyExchangeSampleGenerator
+	keyex.trustedDH = &dhParams{defaultDHPrime, 2}
 
 	// --- req 1
 
@@ -253,6 +269,33 @@ func TestKeyExchange(t *testing.T) {
 	}
 }
 
+// The samples use g=2 with a dh_prime that is 3 mod 8, so 2 generates the
+// whole group instead of the prime-order subgroup; this must be rejected.
+func TestKeyExchangeSampleGenerator(t *testing.T) {
+	var framer Framer
+	keyex := newSampleKeyEx(t)
+
+	keyex.Start()
+	if _, err := keyex.Handle(sampleObj(t, &framer, res1)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := keyex.Handle(sampleObj(t, &framer, res2))
+	if err != ErrBadDHGenerator {
+		t.Fatalf("got %v, expected %v", err, ErrBadDHGenerator)
+	}
+	if _, err := keyex.Result(); err != ErrBadDHGenerator {
+		t.Errorf("got result error %v, expected %v", err, ErrBadDHGenerator)
+	}
+}
+
+func sampleObj(t *testing.T, framer *Framer, sample string) tl.Object {
+	msg, err := framer.Parse(fromHex(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Schema.MustReadBoxedObject(msg.Payload)
+}
+
 func fromHex(s string) []byte {
 	data, err := hex.DecodeString(strings.Map(dropSpace, s))
 	if err != nil {
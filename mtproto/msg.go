@@ -29,6 +29,7 @@ type Msg struct {
 	Payload []byte
 	Type    MsgType
 	MsgID   uint64
+	SeqNo   int // only set by Parse
 }
 
 func MsgFromObj(o tl.Object) Msg {
@@ -41,7 +42,7 @@ func MsgFromObj(o tl.Object) Msg {
 		} else {
 			t = KeyExMsg
 		}
-		return Msg{tl.Bytes(o), t, 0}
+		return Msg{tl.Bytes(o), t, 0, 0}
 	}
 }
 
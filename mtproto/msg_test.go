@@ -1,8 +1,12 @@
 package mtproto
 
 import (
+	"bytes"
+	"encoding/hex"
+	"strings"
 	"testing"
 
+	"github.com/andreyvit/telegramapi/binints"
 	"github.com/andreyvit/telegramapi/tl"
 )
 
@@ -23,3 +27,170 @@ func TestMsgType(t *testing.T) {
 		}
 	}
 }
+
+// MTProto 2.0 vectors below were computed independently using Python's hashlib,
+// with auth_key = 00 01 02 ... FF and msg_key = 10 11 12 ... 1F.
+
+const mtproto2PlainText = `
+01 02 03 04 05 06 07 08 11 12 13 14 15 16 17 18
+4A 96 70 27 C4 7A E5 51 01 00 00 00 04 00 00 00
+26 30 B3 1F AA AA AA AA AA AA AA AA AA AA AA AA
+`
+
+func testAuth2() *AuthResult {
+	auth := &AuthResult{KeyID: 0x1122334455667788}
+	auth.Key = make([]byte, 256)
+	for i := range auth.Key {
+		auth.Key[i] = byte(i)
+	}
+	copy(auth.ServerSalt[:], fromHex("0102030405060708"))
+	copy(auth.SessionID[:], fromHex("1112131415161718"))
+	return auth
+}
+
+func TestDeriveAESKey2(t *testing.T) {
+	tests := []struct {
+		isClient bool
+		key, iv  string
+	}{
+		{true, "5D8A7A5D8DF6F2C1B0FC265CAFCE6FA489FB54FFF123DD9E8AB41EEB4076911B", "15E6797E79C0B6FCBA6BCA8DA1CCADCCA3317C46124CA726DC0BBD310C2DFDC3"},
+		{false, "74C42B6C20823EC4E1660041AE95701264C4797C9969642B108CD6B3F4B3DB82", "A119BF2E9527018C657BA17C40D58E962B3303F8F5A8DF1F46FD73050C8314AD"},
+	}
+	auth := testAuth2()
+	msgKey := fromHex("101112131415161718191A1B1C1D1E1F")
+	for _, tt := range tests {
+		var key, iv [32]byte
+		deriveAESKey2(auth.Key, msgKey, key[:], iv[:], tt.isClient)
+		if a, e := hex.EncodeToString(key[:]), strings.ToLower(tt.key); a != e {
+			t.Errorf("deriveAESKey2(isClient=%v) key is %v, expected %v", tt.isClient, a, e)
+		}
+		if a, e := hex.EncodeToString(iv[:]), strings.ToLower(tt.iv); a != e {
+			t.Errorf("deriveAESKey2(isClient=%v) iv is %v, expected %v", tt.isClient, a, e)
+		}
+	}
+}
+
+func TestFramerFormat2(t *testing.T) {
+	auth := testAuth2()
+	fr := &Framer{
+		Version:         MTProto2,
+		MsgIDOverride:   0x51e57ac42770964a,
+		RandomReader:    bytes.NewReader(bytes.Repeat([]byte{0xAA}, maxPadding2)),
+		PaddingOverride: 12,
+	}
+	fr.SetAuth(auth)
+
+	raw, _, err := fr.Format(MsgFromObj(&TLHelpGetNearestDC{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a, e := binints.DecodeUint64LE(raw[0:8]), auth.KeyID; a != e {
+		t.Errorf("auth_key_id is %x, expected %x", a, e)
+	}
+	msgKey := raw[8:24]
+	if a, e := hex.EncodeToString(msgKey), "69a8b46326674041dbc61a17dd58c0d5"; a != e {
+		t.Errorf("msg_key is %v, expected %v", a, e)
+	}
+
+	var key, iv [32]byte
+	deriveAESKey2(auth.Key, msgKey, key[:], iv[:], true)
+	decrypted, err := AESIGEDecrypt(nil, raw[24:], key[:], iv[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, e := hex.EncodeToString(decrypted), hex.EncodeToString(fromHex(mtproto2PlainText)); a != e {
+		t.Errorf("decrypted is %v, expected %v", a, e)
+	}
+}
+
+func TestFramerParse2(t *testing.T) {
+	auth := testAuth2()
+	msgKey := fromHex("032781ABDFBD828642E290CCC8E95155")
+
+	var key, iv [32]byte
+	deriveAESKey2(auth.Key, msgKey, key[:], iv[:], false)
+	encrypted, err := AESIGEPadEncrypt(nil, fromHex(mtproto2PlainText), key[:], iv[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := tl.NewWriter()
+	w.WriteUint64(auth.KeyID)
+	w.Write(msgKey)
+	w.Write(encrypted)
+	raw := w.Bytes()
+
+	fr := &Framer{Version: MTProto2}
+	fr.SetAuth(auth)
+
+	msg, err := fr.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgID != 0x51e57ac42770964a {
+		t.Errorf("msg_id is %x, expected %x", msg.MsgID, uint64(0x51e57ac42770964a))
+	}
+	if a, e := hex.EncodeToString(msg.Payload), "2630b31f"; a != e {
+		t.Errorf("payload is %v, expected %v", a, e)
+	}
+
+	raw[len(raw)-1] ^= 1
+	_, err = fr.Parse(raw)
+	if err != ErrMsgKeyMismatch {
+		t.Errorf("tampered message parsed with err = %v, expected %v", err, ErrMsgKeyMismatch)
+	}
+}
+
+func TestFramerParseSessionID(t *testing.T) {
+	clientAuth := testAuth2()
+	serverAuth := *clientAuth
+	serverAuth.SessionID[0] ^= 0xff
+
+	client := &Framer{}
+	client.SetAuth(clientAuth)
+	server := &Framer{Server: true}
+	server.SetAuth(&serverAuth)
+
+	raw, _, err := server.Format(Msg{fromHex("01020304"), ContentMsg, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Parse(raw)
+	if err != ErrSessionIDMismatch {
+		t.Errorf("message of another session parsed with err = %v, expected %v", err, ErrSessionIDMismatch)
+	}
+}
+
+func TestFramerPadding2(t *testing.T) {
+	client := &Framer{Version: MTProto2}
+	client.SetAuth(testAuth2())
+	server := &Framer{Version: MTProto2, Server: true}
+	server.SetAuth(testAuth2())
+
+	payload := fromHex("01020304")
+	sizes := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		raw, _, err := client.Format(Msg{payload, ContentMsg, 0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// auth_key_id, msg_key, then the header, payload and padding
+		pad := len(raw) - 24 - 32 - len(payload)
+		if pad < minPadding2 || pad > maxPadding2 || (len(raw)-24)%16 != 0 {
+			t.Errorf("padding is %d bytes, total %d", pad, len(raw))
+		}
+		sizes[pad] = true
+
+		msg, err := server.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg.Payload, payload) {
+			t.Errorf("payload is %x, expected %x", msg.Payload, payload)
+		}
+	}
+	if len(sizes) < 2 {
+		t.Errorf("padding length is always %v", sizes)
+	}
+}
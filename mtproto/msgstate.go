@@ -0,0 +1,212 @@
+package mtproto
+
+import (
+	"log"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+const (
+	// msg_ids older than this many seconds are rejected by the server, so
+	// neither side needs to remember them
+	msgStateTTL = 300
+
+	// msg_ids this many seconds ahead of the server clock cannot have been sent yet
+	maxMsgAhead = 30
+)
+
+// statuses reported by msgs_state_info and msgs_all_info
+const (
+	msgStateUnknown     = 1 // msg_id too old, the message may have been forgotten
+	msgStateNotReceived = 2
+	msgStateTooHigh     = 3 // msg_id too high, certainly not received yet
+	msgStateReceived    = 4
+
+	// flags added to msgStateReceived
+	msgStateAcked = 8  // content message we have acknowledged
+	msgStateNoAck = 16 // service message that needs no acknowledgement
+
+	msgStateMask = 7
+)
+
+// serverNow returns the current server Unix time as far as we know.
+func (sess *Session) serverNow() int {
+	sess.stateMut.Lock()
+	auth, _ := sess.framer.State()
+	offset := 0
+	if auth != nil {
+		offset = auth.TimeOffset
+	}
+	sess.stateMut.Unlock()
+	return int(time.Now().Unix()) + offset
+}
+
+// trackSent remembers a content message until the server acknowledges it,
+// so that it can be resent on request.
+func (sess *Session) trackSent(msgID uint64, q queuedMsg) {
+	if q.Msg.Type == ContentMsg {
+		sess.sent[msgID] = &q
+	}
+}
+
+// markAcked records that the server has received a message of ours, or
+// all messages of a container.
+func (sess *Session) markAcked(msgID uint64) {
+	for _, id := range sess.expandContainer(msgID) {
+		delete(sess.sent, id)
+		if infl := sess.inFlight[id]; infl != nil {
+			infl.Acked = true
+		}
+	}
+}
+
+// markReceived records an incoming message for answering msgs_state_req.
+func (sess *Session) markReceived(msgID uint64, typ MsgType) {
+	if typ == ContentMsg {
+		sess.received[msgID] = msgStateReceived
+	} else {
+		sess.received[msgID] = msgStateReceived | msgStateNoAck
+	}
+}
+
+// markAckSent records that we have acknowledged incoming messages.
+func (sess *Session) markAckSent(ids []uint64) {
+	for _, id := range ids {
+		if state, ok := sess.received[id]; ok && state&msgStateNoAck == 0 {
+			sess.received[id] = state | msgStateAcked
+		}
+	}
+}
+
+// forgetOldMsgs drops the messages that are too old to be asked about. It
+// only scans every few seconds since it runs on every flush.
+func (sess *Session) forgetOldMsgs() {
+	now := sess.serverNow()
+	if now < sess.nextForget {
+		return
+	}
+	sess.nextForget = now + msgStateTTL/10
+
+	cutoff := uint64(now-msgStateTTL) << 32
+	for id := range sess.sent {
+		if id < cutoff {
+			delete(sess.sent, id)
+		}
+	}
+	for id := range sess.received {
+		if id < cutoff {
+			delete(sess.received, id)
+		}
+	}
+}
+
+// msgState computes the msgs_state_info status of a message sent by the server.
+func (sess *Session) msgState(msgID uint64, now int) byte {
+	if state, ok := sess.received[msgID]; ok {
+		return state
+	}
+	t := int(msgID >> 32)
+	switch {
+	case t < now-msgStateTTL:
+		return msgStateUnknown
+	case t > now+maxMsgAhead:
+		return msgStateTooHigh
+	default:
+		return msgStateNotReceived
+	}
+}
+
+func (sess *Session) handleMsgsStateReq(msgID uint64, ids []uint64) []tl.Object {
+	now := sess.serverNow()
+	info := make([]byte, len(ids))
+	for i, id := range ids {
+		info[i] = sess.msgState(id, now)
+	}
+	return []tl.Object{&TLMsgsStateInfo{ReqMsgID: msgID, Info: info}}
+}
+
+// handleMsgResendReq resends the requested messages. If any of them has been
+// forgotten, their states are reported instead, as for msgs_state_req.
+func (sess *Session) handleMsgResendReq(msgID uint64, ids []uint64) []tl.Object {
+	missing := false
+	for _, id := range ids {
+		if !sess.resendSent(id) {
+			missing = true
+		}
+	}
+	if missing {
+		return sess.handleMsgsStateReq(msgID, ids)
+	}
+	return nil
+}
+
+// resendSent sends an unacknowledged message again under a new msg_id.
+// Requests go through resend, so those made by the session itself are not
+// resent.
+func (sess *Session) resendSent(msgID uint64) bool {
+	q := sess.sent[msgID]
+	if q == nil {
+		return false
+	}
+	if q.Infl != nil {
+		return sess.resend(msgID)
+	}
+	delete(sess.sent, msgID)
+
+	if sess.options.Verbose >= 1 {
+		log.Printf("mtproto.Session resending %s (was msgID %08x)", tl.Name(q.Obj), msgID)
+	}
+	sess.enqueue(q.Obj, nil)
+	return true
+}
+
+// handleMsgsAllInfo applies the states of our messages reported by the
+// server: received ones are acknowledged, lost ones are resent.
+func (sess *Session) handleMsgsAllInfo(o *TLMsgsAllInfo) {
+	for i, id := range o.MsgIDs {
+		if i >= len(o.Info) {
+			break
+		}
+		if o.Info[i]&msgStateMask == msgStateReceived {
+			sess.markAcked(id)
+		} else {
+			sess.resendSent(id)
+		}
+	}
+}
+
+// handleDetailedInfo acknowledges an answer that has already been sent to us,
+// or asks for it again if it never arrived.
+func (sess *Session) handleDetailedInfo(answerMsgID uint64) []tl.Object {
+	if _, ok := sess.received[answerMsgID]; ok {
+		sess.ack(answerMsgID)
+		return nil
+	}
+	if sess.options.Verbose >= 1 {
+		log.Printf("mtproto.Session requesting answer %08x again", answerMsgID)
+	}
+	return []tl.Object{&TLMsgResendReq{MsgIDs: []uint64{answerMsgID}}}
+}
+
+// handleInnerMsg processes a message unpacked from a msg_container or msg_copy.
+func (sess *Session) handleInnerMsg(msg *TLProtoMessage) ([]tl.Object, error) {
+	var typ MsgType
+	if (msg.Seqno & 1) == 0 {
+		typ = ServiceMsg
+	} else {
+		typ = ContentMsg
+	}
+	if err := sess.checkIncoming(msg.MsgID, msg.Seqno, typ); err != nil {
+		sess.rejectMsg(err)
+		return nil, nil
+	}
+	sess.markReceived(msg.MsgID, typ)
+
+	r, err := sess.invokeHandlersInternalReturnCmds(msg.MsgID, msg.Body)
+	if err == ErrCmdNotHandled {
+		sess.logDroppedIncomingMsg(msg.Body)
+		return nil, nil
+	}
+	return r, err
+}
@@ -0,0 +1,73 @@
+package mtprototest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// HTTPHandler serves the server over the HTTP transport, for testing
+// mtproto.HTTPTransport. All requests share a single connection. Each one is
+// answered with the next message the server has to send, waiting up to
+// maxWait for it like Telegram does for http_wait; an empty body means there
+// was nothing to send.
+func (s *Server) HTTPHandler(maxWait time.Duration) http.Handler {
+	h := &httpHandler{
+		tr:      s.Dial(),
+		maxWait: maxWait,
+		outc:    make(chan []byte, 1024),
+	}
+	go h.read()
+	return h
+}
+
+type httpHandler struct {
+	tr      mtproto.Transport
+	maxWait time.Duration
+	outc    chan []byte
+}
+
+func (h *httpHandler) read() {
+	for {
+		raw, errcode, err := h.tr.Recv()
+		if err != nil {
+			return
+		}
+		if raw == nil {
+			w := tl.NewWriter()
+			w.WriteInt(errcode)
+			raw = w.Bytes()
+		}
+		h.outc <- raw
+	}
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = h.tr.Send(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	timer := time.NewTimer(h.maxWait)
+	defer timer.Stop()
+	select {
+	case raw := <-h.outc:
+		if len(raw) == 4 {
+			// error codes come with 404, like -404 for an unknown auth key
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write(raw)
+	case <-timer.C:
+		// the long poll timed out
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,95 @@
+package mtprototest
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func TestHTTPLongPolling(t *testing.T) {
+	const maxWait = 100 * time.Millisecond
+
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 3}
+	})
+
+	var mut sync.Mutex
+	var polls int
+	srv.Inspect = func(msgID uint64, o tl.Object) {
+		mut.Lock()
+		defer mut.Unlock()
+		if c, ok := o.(*mtproto.TLMsgContainer); ok {
+			for _, m := range c.Messages {
+				if _, ok := m.Body.(*mtproto.TLHttpWait); ok {
+					polls++
+				}
+			}
+		} else if _, ok := o.(*mtproto.TLHttpWait); ok {
+			polls++
+		}
+	}
+	pollCount := func() int {
+		mut.Lock()
+		defer mut.Unlock()
+		return polls
+	}
+
+	hs := httptest.NewServer(srv.HTTPHandler(maxWait))
+	defer hs.Close()
+
+	pubKeys, err := mtproto.ParseKeyRing(srv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := mtproto.NewHTTPTransport(hs.URL+"/api", mtproto.HTTPTransportOptions{MaxWait: maxWait})
+	sess := mtproto.NewSession(tr, mtproto.SessionOptions{PubKeys: pubKeys})
+	updatesc := make(chan mtproto.TLUpdatesType, 1)
+	sess.OnUpdates(func(o mtproto.TLUpdatesType) {
+		updatesc <- o
+	})
+	go sess.Run()
+	defer sess.Shutdown()
+	sess.WaitReady()
+
+	// the server only knows the session after the first request
+	_, err = sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// polls that time out with nothing to deliver are followed by new ones
+	deadline := time.Now().Add(5 * time.Second)
+	for pollCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d polls sent, expected them to be renewed after timing out", pollCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// nothing else is outstanding, so the update comes back through a poll
+	srv.Push(&mtproto.TLUpdatesTooLong{})
+	select {
+	case o := <-updatesc:
+		if _, ok := o.(*mtproto.TLUpdatesTooLong); !ok {
+			t.Errorf("got %v, expected updatesTooLong", o)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("update not delivered")
+	}
+
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 3 {
+		t.Errorf("got %v, expected updates.state with pts 3", r)
+	}
+	if err := sess.Err(); err != nil {
+		t.Errorf("session failed: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package mtprototest
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// Pipe returns two connected in-memory transports. Sends never block, and
+// closing either end makes both ends return io.EOF.
+func Pipe() (mtproto.Transport, mtproto.Transport) {
+	p := &pipe{closec: make(chan struct{})}
+	a := &pipeEnd{pipe: p, in: newPacketQueue()}
+	b := &pipeEnd{pipe: p, in: newPacketQueue()}
+	a.out, b.out = b.in, a.in
+	return a, b
+}
+
+type pipe struct {
+	closec chan struct{}
+	once   sync.Once
+}
+
+type pipeEnd struct {
+	*pipe
+	in  *packetQueue
+	out *packetQueue
+}
+
+func (e *pipeEnd) Send(data []byte) error {
+	select {
+	case <-e.closec:
+		return io.ErrClosedPipe
+	default:
+	}
+	e.out.push(append([]byte(nil), data...))
+	return nil
+}
+
+func (e *pipeEnd) Recv() ([]byte, int, error) {
+	for {
+		if raw, ok := e.in.pop(); ok {
+			// 4-byte packets carry error codes, like on real transports
+			if len(raw) == 4 {
+				return nil, int(int32(tl.NewReader(raw).Cmd())), nil
+			}
+			return raw, 0, nil
+		}
+		select {
+		case <-e.in.notifyc:
+		case <-e.closec:
+			return nil, 0, io.EOF
+		}
+	}
+}
+
+func (e *pipeEnd) Close() {
+	e.once.Do(func() {
+		close(e.closec)
+	})
+}
+
+type packetQueue struct {
+	mut     sync.Mutex
+	packets [][]byte
+	notifyc chan struct{}
+}
+
+func newPacketQueue() *packetQueue {
+	return &packetQueue{notifyc: make(chan struct{}, 1)}
+}
+
+func (q *packetQueue) push(raw []byte) {
+	q.mut.Lock()
+	q.packets = append(q.packets, raw)
+	q.mut.Unlock()
+
+	select {
+	case q.notifyc <- struct{}{}:
+	default:
+	}
+}
+
+func (q *packetQueue) pop() ([]byte, bool) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	if len(q.packets) == 0 {
+		return nil, false
+	}
+	raw := q.packets[0]
+	q.packets = q.packets[1:]
+	return raw, true
+}
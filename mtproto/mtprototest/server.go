@@ -0,0 +1,527 @@
+// Package mtprototest provides an in-process fake MTProto server for tests.
+package mtprototest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// Handler answers a request. Returning nil leaves the request unanswered,
+// as if it got lost.
+type Handler func(req tl.Object) tl.Object
+
+// Server is a fake MTProto server. It performs the key exchange, decrypts
+// requests and dispatches them to the registered handlers.
+type Server struct {
+	Key          *rsa.PrivateKey
+	Version      mtproto.ProtocolVersion
+	RandomReader io.Reader
+	Verbose      int
+
+	// TimeOffset shifts the server clock relative to the local one. Messages
+	// whose msg_id is too far off the server clock are rejected with
+	// bad_msg_notification, like Telegram does.
+	TimeOffset time.Duration
+
+	// Inspect, if set, is called with every encrypted message the server
+	// receives, before containers are unpacked
+	Inspect func(msgID uint64, o tl.Object)
+
+	// AckRequests makes the server acknowledge every request before running
+	// its handler, like Telegram does for requests that take a while
+	AckRequests bool
+
+	mut      sync.Mutex
+	handlers map[uint32]Handler
+	authKeys map[uint64]*mtproto.AuthResult
+	bindings map[uint64]uint64 // temp key ID -> perm key ID
+	salts    map[uint64]bool   // salts that are currently accepted
+	conns    map[*serverConn]bool
+	muted    bool
+}
+
+// futureSaltPeriod is how often a new future salt becomes valid; each salt
+// stays valid for twice as long, so that consecutive salts overlap.
+const futureSaltPeriod = 30 * time.Minute
+
+func NewServer() *Server {
+	block, _ := pem.Decode([]byte(TestPrivateKey))
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Server{
+		Key:          key,
+		RandomReader: rand.Reader,
+		handlers:     make(map[uint32]Handler),
+		authKeys:     make(map[uint64]*mtproto.AuthResult),
+		bindings:     make(map[uint64]uint64),
+		salts:        make(map[uint64]bool),
+		conns:        make(map[*serverConn]bool),
+	}
+}
+
+// PublicKey returns the server key in the PEM format expected by mtproto.ParsePublicKey.
+func (s *Server) PublicKey() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&s.Key.PublicKey),
+	}))
+}
+
+// Handle registers a handler for requests with the given constructor tag, e.g. mtproto.TagHelpGetConfig.
+func (s *Server) Handle(cmd uint32, h Handler) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.handlers[cmd] = h
+}
+
+// Dial connects a new client and returns its end of the connection.
+func (s *Server) Dial() mtproto.Transport {
+	client, server := Pipe()
+
+	conn := &serverConn{
+		srv: s,
+		tr:  server,
+		framer: &mtproto.Framer{
+			Version:      s.Version,
+			RandomReader: s.RandomReader,
+			Server:       true,
+		},
+	}
+
+	s.mut.Lock()
+	s.conns[conn] = true
+	s.mut.Unlock()
+
+	go conn.run()
+	return client
+}
+
+// Push sends an unsolicited message, like updates, to every connected client.
+func (s *Server) Push(o tl.Object) {
+	for _, conn := range s.activeConns() {
+		if conn.hasSession() {
+			conn.send(o, mtproto.ContentMsg)
+		}
+	}
+}
+
+// Replay sends the last message of every connection again, like an attacker
+// replaying captured traffic would.
+func (s *Server) Replay() {
+	for _, conn := range s.activeConns() {
+		conn.mut.Lock()
+		raw := conn.lastSent
+		conn.mut.Unlock()
+		if raw != nil {
+			conn.tr.Send(raw)
+		}
+	}
+}
+
+// DropConnections closes all connections, forcing the clients to reconnect.
+// Auth keys are kept.
+func (s *Server) DropConnections() {
+	for _, conn := range s.activeConns() {
+		conn.tr.Close()
+	}
+}
+
+// TempKeys returns the IDs of the temporary keys bound to the given permanent key.
+func (s *Server) TempKeys(permKeyID uint64) []uint64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	var result []uint64
+	for temp, perm := range s.bindings {
+		if perm == permKeyID {
+			result = append(result, temp)
+		}
+	}
+	return result
+}
+
+// ExpireSalts makes the server reject every salt issued so far, so that
+// the next message from each client gets bad_server_salt.
+func (s *Server) ExpireSalts() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.salts = make(map[uint64]bool)
+}
+
+// Mute makes the server swallow all incoming messages without answering,
+// like a peer behind a half-open connection.
+func (s *Server) Mute() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.muted = true
+}
+
+func (s *Server) isMuted() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.muted
+}
+
+func (s *Server) isValidSalt(salt [8]byte) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.salts[binints.DecodeUint64LE(salt[:])]
+}
+
+func (s *Server) addSalt(salt uint64) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.salts[salt] = true
+}
+
+func (s *Server) newSalt() uint64 {
+	var buf [8]byte
+	_, err := io.ReadFull(s.RandomReader, buf[:])
+	if err != nil {
+		panic(err)
+	}
+	salt := binints.DecodeUint64LE(buf[:])
+	s.addSalt(salt)
+	return salt
+}
+
+// futureSalts answers get_future_salts.
+func (s *Server) futureSalts(reqMsgID uint64, num int) *mtproto.TLFutureSalts {
+	if num > 64 {
+		num = 64
+	}
+	now := time.Now().Add(s.TimeOffset)
+	result := &mtproto.TLFutureSalts{
+		ReqMsgID: reqMsgID,
+		Now:      int(now.Unix()),
+	}
+	for i := 0; i < num; i++ {
+		since := now.Add(time.Duration(i) * futureSaltPeriod)
+		result.Salts = append(result.Salts, &mtproto.TLFutureSalt{
+			ValidSince: int(since.Unix()),
+			ValidUntil: int(since.Add(2 * futureSaltPeriod).Unix()),
+			Salt:       s.newSalt(),
+		})
+	}
+	return result
+}
+
+func (s *Server) activeConns() []*serverConn {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	var result []*serverConn
+	for conn := range s.conns {
+		result = append(result, conn)
+	}
+	return result
+}
+
+// checkMsgTime returns the bad_msg_notification error code for a client
+// msg_id more than 300 seconds in the past or 30 seconds in the future.
+func (s *Server) checkMsgTime(msgID uint64) int {
+	now := time.Now().Add(s.TimeOffset).Unix()
+	t := int64(msgID >> 32)
+	if t < now-300 {
+		return 16
+	} else if t > now+30 {
+		return 17
+	}
+	return 0
+}
+
+func (s *Server) handler(cmd uint32) Handler {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.handlers[cmd]
+}
+
+type serverConn struct {
+	srv    *Server
+	tr     mtproto.Transport
+	kex    *mtproto.ServerKeyEx
+	mut    sync.Mutex
+	framer *mtproto.Framer
+
+	sessionID [8]byte
+	lastSent  []byte
+}
+
+func (c *serverConn) run() {
+	defer func() {
+		c.tr.Close()
+		c.srv.mut.Lock()
+		delete(c.srv.conns, c)
+		c.srv.mut.Unlock()
+	}()
+
+	for {
+		raw, _, err := c.tr.Recv()
+		if err != nil {
+			return
+		}
+
+		err = c.handle(raw)
+		if err != nil {
+			if c.srv.Verbose >= 1 {
+				log.Printf("mtprototest: dropping connection: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (c *serverConn) handle(raw []byte) error {
+	if c.srv.isMuted() {
+		return nil
+	}
+
+	if len(raw) >= 8 {
+		keyID := binints.DecodeUint64LE(raw[:8])
+		if keyID != 0 && !c.useAuthKey(keyID) {
+			// same as Telegram does for unknown auth keys
+			return c.tr.Send([]byte{0x6c, 0xfe, 0xff, 0xff})
+		}
+	}
+
+	c.mut.Lock()
+	msg, err := c.framer.Parse(raw)
+	auth, _ := c.framer.State()
+	c.mut.Unlock()
+	if err != nil {
+		return err
+	}
+
+	o, err := mtproto.Schema.ReadBoxedObject(msg.Payload)
+	if err != nil {
+		return err
+	}
+	if c.srv.Verbose >= 2 {
+		log.Printf("mtprototest: received %v", o)
+	}
+
+	if msg.Type == mtproto.KeyExMsg {
+		if _, ok := o.(*mtproto.TLReqPQ); ok {
+			// each req_pq starts a new exchange, e.g. for another temp key
+			c.kex = &mtproto.ServerKeyEx{
+				PrivKey:      c.srv.Key,
+				RandomReader: c.srv.RandomReader,
+			}
+		} else if c.kex == nil {
+			return mtproto.ErrUnexpectedCommand
+		}
+		reply, err := c.kex.Handle(o)
+		if err != nil {
+			return err
+		}
+		if c.kex.IsFinished() {
+			newAuth, err := c.kex.Result()
+			if err != nil {
+				return err
+			}
+			c.srv.mut.Lock()
+			c.srv.authKeys[newAuth.KeyID] = newAuth
+			c.srv.mut.Unlock()
+			c.srv.addSalt(binints.DecodeUint64LE(newAuth.ServerSalt[:]))
+		}
+		return c.send(reply, mtproto.KeyExMsg)
+	}
+
+	if code := c.srv.checkMsgTime(msg.MsgID); code != 0 {
+		return c.send(&mtproto.TLBadMsgNotification{
+			BadMsgID:  msg.MsgID,
+			ErrorCode: code,
+		}, mtproto.ServiceMsg)
+	}
+	if !c.srv.isValidSalt(auth.ServerSalt) {
+		return c.send(&mtproto.TLBadServerSalt{
+			BadMsgID:      msg.MsgID,
+			ErrorCode:     48,
+			NewServerSalt: c.srv.newSalt(),
+		}, mtproto.ServiceMsg)
+	}
+
+	if c.srv.Inspect != nil {
+		c.srv.Inspect(msg.MsgID, o)
+	}
+
+	c.mut.Lock()
+	newSession := (auth.SessionID != c.sessionID)
+	c.sessionID = auth.SessionID
+	c.mut.Unlock()
+	if newSession {
+		err = c.send(&mtproto.TLNewSessionCreated{
+			FirstMsgID: msg.MsgID,
+			UniqueID:   binints.DecodeUint64LE(c.sessionID[:]),
+			ServerSalt: binints.DecodeUint64LE(auth.ServerSalt[:]),
+		}, mtproto.ServiceMsg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.dispatch(msg.MsgID, o)
+}
+
+// useAuthKey switches to the given auth key, returning false if it is unknown.
+func (c *serverConn) useAuthKey(keyID uint64) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if auth, _ := c.framer.State(); auth != nil && auth.KeyID == keyID {
+		return true
+	}
+
+	c.srv.mut.Lock()
+	auth := c.srv.authKeys[keyID]
+	c.srv.mut.Unlock()
+	if auth == nil {
+		return false
+	}
+
+	copied := *auth
+	copied.TimeOffset = int(c.srv.TimeOffset / time.Second)
+	c.framer.SetAuth(&copied)
+	return true
+}
+
+func (c *serverConn) hasSession() bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.sessionID != [8]byte{}
+}
+
+func (c *serverConn) dispatch(msgID uint64, o tl.Object) error {
+	switch o := o.(type) {
+	case *mtproto.TLMsgContainer:
+		for _, m := range o.Messages {
+			err := c.dispatch(m.MsgID, m.Body)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *mtproto.TLMsgsAck, *mtproto.TLHttpWait, *mtproto.TLMsgsStateInfo, *mtproto.TLMsgResendReq:
+		return nil
+	case *mtproto.TLPing:
+		return c.send(&mtproto.TLPong{MsgID: msgID, PingID: o.PingID}, mtproto.ContentMsg)
+	case *mtproto.TLPingDelayDisconnect:
+		return c.send(&mtproto.TLPong{MsgID: msgID, PingID: o.PingID}, mtproto.ContentMsg)
+	case *mtproto.TLRPCDropAnswer:
+		// requests are answered synchronously, so it is always too late
+		return c.send(&mtproto.TLRPCResult{
+			ReqMsgID: msgID,
+			Result:   &mtproto.TLRPCAnswerUnknown{},
+		}, mtproto.ContentMsg)
+	case *mtproto.TLGetFutureSalts:
+		return c.send(c.srv.futureSalts(msgID, o.Num), mtproto.ServiceMsg)
+	case *mtproto.TLAuthBindTempAuthKey:
+		return c.send(&mtproto.TLRPCResult{
+			ReqMsgID: msgID,
+			Result:   c.bindTempAuthKey(msgID, o),
+		}, mtproto.ContentMsg)
+	default:
+		if c.srv.AckRequests {
+			err := c.send(&mtproto.TLMsgsAck{MsgIDs: []uint64{msgID}}, mtproto.ServiceMsg)
+			if err != nil {
+				return err
+			}
+		}
+		r := c.invoke(o)
+		if r == nil {
+			return nil
+		}
+		return c.send(&mtproto.TLRPCResult{
+			ReqMsgID: msgID,
+			Result:   r,
+		}, mtproto.ContentMsg)
+	}
+}
+
+func (c *serverConn) bindTempAuthKey(msgID uint64, req *mtproto.TLAuthBindTempAuthKey) tl.Object {
+	c.mut.Lock()
+	temp, _ := c.framer.State()
+	c.mut.Unlock()
+
+	c.srv.mut.Lock()
+	perm := c.srv.authKeys[req.PermAuthKeyID]
+	c.srv.mut.Unlock()
+
+	if perm == nil || perm.ExpiresAt != 0 || temp.ExpiresAt == 0 {
+		return &mtproto.TLRPCError{ErrorCode: 400, ErrorMessage: "ENCRYPTED_MESSAGE_INVALID"}
+	}
+
+	inner, err := mtproto.DecryptBindAuthKeyInner(req.EncryptedMessage, perm, msgID)
+	if err != nil || inner.Nonce != req.Nonce || inner.ExpiresAt != req.ExpiresAt ||
+		inner.TempAuthKeyID != temp.KeyID || inner.PermAuthKeyID != perm.KeyID ||
+		inner.TempSessionID != binints.DecodeUint64LE(temp.SessionID[:]) {
+		return &mtproto.TLRPCError{ErrorCode: 400, ErrorMessage: "ENCRYPTED_MESSAGE_INVALID"}
+	}
+
+	c.srv.mut.Lock()
+	c.srv.bindings[temp.KeyID] = perm.KeyID
+	c.srv.mut.Unlock()
+	return &mtproto.TLBool{Value: true}
+}
+
+// isUnboundTempKey reports whether the connection uses a temporary key that
+// cannot be used for regular requests yet.
+func (c *serverConn) isUnboundTempKey() bool {
+	c.mut.Lock()
+	auth, _ := c.framer.State()
+	c.mut.Unlock()
+
+	c.srv.mut.Lock()
+	defer c.srv.mut.Unlock()
+	return auth.ExpiresAt != 0 && c.srv.bindings[auth.KeyID] == 0
+}
+
+func (c *serverConn) invoke(o tl.Object) tl.Object {
+	if c.isUnboundTempKey() {
+		return &mtproto.TLRPCError{ErrorCode: 401, ErrorMessage: "AUTH_KEY_PERM_EMPTY"}
+	}
+
+	// unwrap the connection initialization wrappers
+	for {
+		switch q := o.(type) {
+		case *mtproto.TLInvokeWithLayer:
+			o = q.Query
+			continue
+		case *mtproto.TLInitConnection:
+			o = q.Query
+			continue
+		}
+		break
+	}
+
+	h := c.srv.handler(o.Cmd())
+	if h == nil {
+		if c.srv.Verbose >= 1 {
+			log.Printf("mtprototest: no handler for %v", mtproto.Schema.DescribeCmd(o.Cmd()))
+		}
+		return &mtproto.TLRPCError{ErrorCode: 400, ErrorMessage: "METHOD_NOT_IMPLEMENTED"}
+	}
+	return h(o)
+}
+
+func (c *serverConn) send(o tl.Object, typ mtproto.MsgType) error {
+	c.mut.Lock()
+	raw, _, err := c.framer.Format(mtproto.Msg{Payload: tl.Bytes(o), Type: typ})
+	c.lastSent = raw
+	c.mut.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.tr.Send(raw)
+}
@@ -0,0 +1,764 @@
+package mtprototest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func newTestSession(t *testing.T, srv *Server) *mtproto.Session {
+	pubKeys, err := mtproto.ParseKeyRing(srv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mtproto.NewSession(srv.Dial(), mtproto.SessionOptions{
+		PubKeys:         pubKeys,
+		ProtocolVersion: srv.Version,
+	})
+}
+
+func TestServerRPC(t *testing.T) {
+	for _, version := range []mtproto.ProtocolVersion{mtproto.MTProto1, mtproto.MTProto2} {
+		srv := NewServer()
+		srv.Version = version
+		srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+			return &mtproto.TLUpdatesState{Pts: 42, Seq: 1}
+		})
+
+		sess := newTestSession(t, srv)
+		go sess.Run()
+		sess.WaitReady()
+
+		r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 42 {
+			t.Errorf("v%v: got %v, expected updates.state with pts 42", version, r)
+		}
+
+		r, err = sess.Send(&mtproto.TLHelpGetConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, ok := r.(*mtproto.TLRPCError); !ok || e.ErrorCode != 400 {
+			t.Errorf("v%v: got %v, expected rpc_error", version, r)
+		}
+
+		sess.Shutdown()
+	}
+}
+
+func TestServerPush(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{}
+	})
+
+	sess := newTestSession(t, srv)
+	updatesc := make(chan mtproto.TLUpdatesType, 1)
+	sess.OnUpdates(func(o mtproto.TLUpdatesType) {
+		updatesc <- o
+	})
+	go sess.Run()
+	sess.WaitReady()
+
+	// the server only knows the session after the first request
+	_, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Push(&mtproto.TLUpdatesTooLong{})
+	select {
+	case o := <-updatesc:
+		if _, ok := o.(*mtproto.TLUpdatesTooLong); !ok {
+			t.Errorf("got %v, expected updatesTooLong", o)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("update not delivered")
+	}
+
+	sess.Shutdown()
+}
+
+func TestServerReconnect(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 7}
+	})
+
+	sess := newTestSession(t, srv)
+	done := make(chan struct{})
+	go func() {
+		sess.Run()
+		close(done)
+	}()
+	sess.WaitReady()
+	_, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, fs := sess.AuthState()
+
+	srv.DropConnections()
+	<-done
+
+	// the auth key survives the reconnection
+	sess = newTestSession(t, srv)
+	sess.RestoreAuthState(auth, fs)
+	go sess.Run()
+	sess.WaitReady()
+
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 7 {
+		t.Errorf("got %v, expected updates.state with pts 7", r)
+	}
+
+	sess.Shutdown()
+}
+
+func TestServerTempKey(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 3}
+	})
+
+	pubKeys, err := mtproto.ParseKeyRing(srv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := mtproto.NewSession(srv.Dial(), mtproto.SessionOptions{
+		PubKeys:    pubKeys,
+		TempKeyTTL: time.Second,
+	})
+	go sess.Run()
+	sess.WaitReady()
+
+	// the server refuses requests over unbound temp keys
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 3 {
+		t.Fatalf("got %v, expected updates.state with pts 3", r)
+	}
+
+	perm, _ := sess.AuthState()
+	if perm.ExpiresAt != 0 {
+		t.Errorf("AuthState returned a temp key, expected the permanent one")
+	}
+	if n := len(srv.TempKeys(perm.KeyID)); n != 1 {
+		t.Errorf("got %d temp keys, expected 1", n)
+	}
+
+	// the temp key gets renewed before it expires
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.TempKeys(perm.KeyID)) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("temp key not renewed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	r, err = sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 3 {
+		t.Errorf("got %v after renewal, expected updates.state with pts 3", r)
+	}
+
+	sess.Shutdown()
+}
+
+func TestServerTimeOffset(t *testing.T) {
+	srv := NewServer()
+	srv.TimeOffset = time.Hour
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 5}
+	})
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	// the first request gets bad_msg_notification 16 and is resent
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 5 {
+		t.Errorf("got %v, expected updates.state with pts 5", r)
+	}
+
+	sess.Shutdown()
+
+	auth, _ := sess.AuthState()
+	if d := auth.TimeOffset - 3600; d < -1 || d > 1 {
+		t.Errorf("TimeOffset = %d, expected 3600", auth.TimeOffset)
+	}
+}
+
+func TestServerSalts(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 9}
+	})
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	waitForSalts := func() *mtproto.AuthResult {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			auth, _ := sess.AuthState()
+			if len(auth.FutureSalts) > 0 {
+				return auth
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("future salts not fetched")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	auth := waitForSalts()
+	if salt := binints.DecodeUint64LE(auth.ServerSalt[:]); salt != auth.FutureSalts[0].Salt {
+		t.Errorf("using salt %x, expected the first future salt %x", salt, auth.FutureSalts[0].Salt)
+	}
+
+	// the request gets bad_server_salt and is resent on the same connection
+	srv.ExpireSalts()
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 9 {
+		t.Errorf("got %v, expected updates.state with pts 9", r)
+	}
+	if err := sess.Err(); err != nil {
+		t.Errorf("session failed: %v", err)
+	}
+
+	// fresh salts replace the stale ones
+	waitForSalts()
+
+	sess.Shutdown()
+}
+
+func TestServerKeepalive(t *testing.T) {
+	srv := NewServer()
+
+	pubKeys, err := mtproto.ParseKeyRing(srv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := mtproto.NewSession(srv.Dial(), mtproto.SessionOptions{
+		PubKeys:      pubKeys,
+		PingInterval: 50 * time.Millisecond,
+		PingTimeout:  200 * time.Millisecond,
+	})
+	done := make(chan struct{})
+	go func() {
+		sess.Run()
+		close(done)
+	}()
+	sess.WaitReady()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sess.RTT() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no RTT measured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// pings go unanswered
+	srv.Mute()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not detect the dead connection")
+	}
+	if err := sess.Err(); err != mtproto.ErrPingTimeout {
+		t.Errorf("got %v, expected ErrPingTimeout", err)
+	}
+}
+
+func TestServerContainers(t *testing.T) {
+	srv := NewServer()
+	srv.TimeOffset = time.Hour
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 1}
+	})
+
+	var mut sync.Mutex
+	var containers, acks int
+	srv.Inspect = func(msgID uint64, o tl.Object) {
+		mut.Lock()
+		defer mut.Unlock()
+		if c, ok := o.(*mtproto.TLMsgContainer); ok {
+			containers++
+			for _, m := range c.Messages {
+				if _, ok := m.Body.(*mtproto.TLMsgsAck); ok {
+					acks++
+				}
+			}
+		} else if _, ok := o.(*mtproto.TLMsgsAck); ok {
+			acks++
+		}
+	}
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	// the requests share a container, which gets bad_msg_notification 16
+	// because of the clock offset, so all of them must be resent
+	const n = 5
+	errc := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+			if err == nil {
+				if _, ok := r.(*mtproto.TLUpdatesState); !ok {
+					err = fmt.Errorf("got %v, expected updates.state", r)
+				}
+			}
+			errc <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// let the acks go out
+	time.Sleep(50 * time.Millisecond)
+	sess.Shutdown()
+
+	mut.Lock()
+	defer mut.Unlock()
+	if containers == 0 {
+		t.Errorf("no containers sent")
+	}
+	if acks == 0 || acks >= n {
+		t.Errorf("sent %d msgs_ack for %d results, expected them to be batched", acks, n)
+	}
+}
+
+func TestServerGzip(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagMessagesSendMessage, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: len(req.(*mtproto.TLMessagesSendMessage).Message)}
+	})
+
+	pubKeys, err := mtproto.ParseKeyRing(srv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := mtproto.NewSession(srv.Dial(), mtproto.SessionOptions{
+		PubKeys:       pubKeys,
+		GzipThreshold: 256,
+	})
+	go sess.Run()
+	sess.WaitReady()
+
+	text := strings.Repeat("lorem ipsum ", 1000)
+	r, err := sess.Send(&mtproto.TLMessagesSendMessage{
+		Peer:    &mtproto.TLInputPeerSelf{},
+		Message: text,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != len(text) {
+		t.Errorf("got %v, expected the server to receive %d characters", r, len(text))
+	}
+
+	sess.Shutdown()
+}
+
+func TestServerSendContext(t *testing.T) {
+	srv := NewServer()
+	release := make(chan struct{})
+	srv.Handle(mtproto.TagHelpGetConfig, func(req tl.Object) tl.Object {
+		<-release
+		return &mtproto.TLUpdatesState{}
+	})
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 4}
+	})
+	dropped := make(chan uint64, 1)
+	srv.Inspect = func(msgID uint64, o tl.Object) {
+		if c, ok := o.(*mtproto.TLMsgContainer); ok {
+			for _, m := range c.Messages {
+				srv.Inspect(m.MsgID, m.Body)
+			}
+		} else if d, ok := o.(*mtproto.TLRPCDropAnswer); ok {
+			dropped <- d.ReqMsgID
+		}
+	}
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := sess.SendContext(ctx, &mtproto.TLHelpGetConfig{})
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, expected context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	select {
+	case <-dropped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no rpc_drop_answer sent")
+	}
+
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 4 {
+		t.Errorf("got %v, expected updates.state with pts 4", r)
+	}
+
+	sess.Shutdown()
+}
+
+// A request cancelled before the key exchange finishes is never sent.
+func TestServerSendContextBeforeReady(t *testing.T) {
+	srv := NewServer()
+	var mut sync.Mutex
+	configs := 0
+	srv.Handle(mtproto.TagHelpGetConfig, func(req tl.Object) tl.Object {
+		mut.Lock()
+		configs++
+		mut.Unlock()
+		return &mtproto.TLUpdatesState{}
+	})
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{Pts: 5}
+	})
+
+	sess := newTestSession(t, srv)
+
+	// the request waits in the session's queue until Run is called
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := sess.SendContext(ctx, &mtproto.TLHelpGetConfig{})
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	go sess.Run()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("got %v, expected context.Canceled", err)
+	}
+
+	r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := r.(*mtproto.TLUpdatesState); !ok || st.Pts != 5 {
+		t.Errorf("got %v, expected updates.state with pts 5", r)
+	}
+
+	mut.Lock()
+	if configs != 0 {
+		t.Errorf("cancelled request reached the server")
+	}
+	mut.Unlock()
+
+	sess.Shutdown()
+}
+
+func TestServerFailsPendingRequests(t *testing.T) {
+	srv := NewServer()
+	received := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	srv.Handle(mtproto.TagHelpGetConfig, func(req tl.Object) tl.Object {
+		close(received)
+		<-release
+		return &mtproto.TLUpdatesState{}
+	})
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := sess.Send(&mtproto.TLHelpGetConfig{})
+		errc <- err
+	}()
+
+	<-received
+	srv.DropConnections()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("request succeeded, expected it to fail with the session")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request still pending after the session stopped")
+	}
+
+	// requests made after the session stopped fail right away
+	_, err := sess.Send(&mtproto.TLHelpGetConfig{})
+	if err != mtproto.ErrSessionClosed {
+		t.Errorf("got %v, expected ErrSessionClosed", err)
+	}
+}
+
+func TestServerMsgState(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{}
+	})
+
+	var mut sync.Mutex
+	var acked []uint64
+	stateInfos := make(chan *mtproto.TLMsgsStateInfo, 1)
+	resendReqs := make(chan *mtproto.TLMsgResendReq, 1)
+	srv.Inspect = func(msgID uint64, o tl.Object) {
+		switch o := o.(type) {
+		case *mtproto.TLMsgContainer:
+			for _, m := range o.Messages {
+				srv.Inspect(m.MsgID, m.Body)
+			}
+		case *mtproto.TLMsgsAck:
+			mut.Lock()
+			acked = append(acked, o.MsgIDs...)
+			mut.Unlock()
+		case *mtproto.TLMsgsStateInfo:
+			stateInfos <- o
+		case *mtproto.TLMsgResendReq:
+			resendReqs <- o
+		}
+	}
+
+	sess := newTestSession(t, srv)
+	var updates int
+	sess.OnUpdates(func(o mtproto.TLUpdatesType) {
+		mut.Lock()
+		updates++
+		mut.Unlock()
+	})
+	go sess.Run()
+	sess.WaitReady()
+
+	_, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// let the acks go out
+	time.Sleep(50 * time.Millisecond)
+
+	now := uint64(time.Now().Unix())
+	mut.Lock()
+	ids := append([]uint64(nil), acked...)
+	mut.Unlock()
+	if len(ids) == 0 {
+		t.Fatal("nothing acknowledged")
+	}
+	ids = append(ids, (now+3600)<<32|1, (now-3600)<<32|1, (now-10)<<32|1)
+
+	srv.Push(&mtproto.TLMsgsStateReq{MsgIDs: ids})
+	select {
+	case info := <-stateInfos:
+		n := len(ids) - 3
+		if len(info.Info) != len(ids) {
+			t.Fatalf("got %d states for %d msg_ids", len(info.Info), len(ids))
+		}
+		content := 0
+		for i := 0; i < n; i++ {
+			// content messages get +8 once acknowledged, service ones +16
+			if info.Info[i] == 4|8 {
+				content++
+			} else if info.Info[i] != 4|16 {
+				t.Errorf("state of acknowledged message is %d, expected received and acknowledged", info.Info[i])
+			}
+		}
+		if content == 0 {
+			t.Errorf("no content message reported as acknowledged: %v", info.Info[:n])
+		}
+		if actual, expected := fmt.Sprint(info.Info[n:]), "[3 1 2]"; actual != expected {
+			t.Errorf("states of other messages are %v, expected %v", actual, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no msgs_state_info sent")
+	}
+
+	// an answer we have got is only acknowledged, a lost one is requested again
+	lostID := (now-5)<<32 | 5
+	srv.Push(&mtproto.TLMsgNewDetailedInfo{AnswerMsgID: ids[0]})
+	srv.Push(&mtproto.TLMsgNewDetailedInfo{AnswerMsgID: lostID})
+	select {
+	case req := <-resendReqs:
+		if len(req.MsgIDs) != 1 || req.MsgIDs[0] != lostID {
+			t.Errorf("got msg_resend_req for %x, expected %x", req.MsgIDs, lostID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no msg_resend_req sent")
+	}
+
+	// a copy is handled like the original, but only once
+	update := &mtproto.TLUpdatesTooLong{}
+	cp := &mtproto.TLMsgCopy{OrigMessage: &mtproto.TLProtoMessage{
+		MsgID: (now-1)<<32 | 9,
+		Seqno: 1,
+		Bytes: len(tl.Bytes(update)),
+		Body:  update,
+	}}
+	srv.Push(cp)
+	srv.Push(cp)
+	_, err = sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mut.Lock()
+	if updates != 1 {
+		t.Errorf("got %d updates from msg_copy, expected 1", updates)
+	}
+	mut.Unlock()
+
+	sess.Shutdown()
+}
+
+func TestServerMsgResend(t *testing.T) {
+	srv := NewServer()
+	var mut sync.Mutex
+	calls := 0
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		mut.Lock()
+		defer mut.Unlock()
+		calls++
+		if calls <= 2 {
+			return nil
+		}
+		return &mtproto.TLUpdatesState{Pts: calls}
+	})
+	reqIDs := make(chan uint64, 3)
+	srv.Inspect = func(msgID uint64, o tl.Object) {
+		if c, ok := o.(*mtproto.TLMsgContainer); ok {
+			for _, m := range c.Messages {
+				srv.Inspect(m.MsgID, m.Body)
+			}
+		} else if _, ok := o.(*mtproto.TLUpdatesGetState); ok {
+			reqIDs <- msgID
+		}
+	}
+
+	sess := newTestSession(t, srv)
+	go sess.Run()
+	sess.WaitReady()
+
+	// get initConnection out of the way
+	_, err := sess.Send(&mtproto.TLHelpGetConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		r   tl.Object
+		err error
+	}
+	resultc := make(chan result, 1)
+	go func() {
+		r, err := sess.Send(&mtproto.TLUpdatesGetState{})
+		resultc <- result{r, err}
+	}()
+
+	nextReqID := func() uint64 {
+		select {
+		case id := <-reqIDs:
+			return id
+		case <-time.After(5 * time.Second):
+			t.Fatal("request not resent")
+			return 0
+		}
+	}
+
+	id := nextReqID()
+	srv.Push(&mtproto.TLMsgResendReq{MsgIDs: []uint64{id}})
+	id = nextReqID()
+	srv.Push(&mtproto.TLMsgsAllInfo{MsgIDs: []uint64{id}, Info: []byte{2}})
+	nextReqID()
+
+	select {
+	case res := <-resultc:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if st, ok := res.r.(*mtproto.TLUpdatesState); !ok || st.Pts != 3 {
+			t.Errorf("got %v, expected updates.state with pts 3", res.r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no answer after resending")
+	}
+
+	sess.Shutdown()
+}
+
+func TestServerReplay(t *testing.T) {
+	srv := NewServer()
+	srv.Handle(mtproto.TagUpdatesGetState, func(req tl.Object) tl.Object {
+		return &mtproto.TLUpdatesState{}
+	})
+
+	sess := newTestSession(t, srv)
+	var mut sync.Mutex
+	var updates int
+	sess.OnUpdates(func(o mtproto.TLUpdatesType) {
+		mut.Lock()
+		updates++
+		mut.Unlock()
+	})
+	go sess.Run()
+	sess.WaitReady()
+
+	_, err := sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Push(&mtproto.TLUpdatesTooLong{})
+	srv.Replay()
+
+	// the answer comes after the replayed frame
+	_, err = sess.Send(&mtproto.TLUpdatesGetState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mut.Lock()
+	if updates != 1 {
+		t.Errorf("got %d updates, expected the replayed one to be dropped", updates)
+	}
+	mut.Unlock()
+	if d := sess.Diagnostics(); d.Duplicates != 1 {
+		t.Errorf("diagnostics %+v, expected 1 duplicate", d)
+	}
+
+	sess.Shutdown()
+}
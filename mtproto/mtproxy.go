@@ -0,0 +1,84 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const proxySecretLen = 16
+
+var ErrInvalidProxySecret = errors.New("invalid MTProxy secret")
+
+// ProxySecret is a parsed MTProxy secret.
+type ProxySecret struct {
+	Key []byte
+
+	// dd-prefixed secret, requires the padded intermediate framing
+	Padded bool
+
+	// ee-prefixed secret, the connection is disguised as TLS to Domain
+	FakeTLS bool
+	Domain  string
+}
+
+// ParseProxySecret parses a hex- or base64-encoded MTProxy secret.
+func ParseProxySecret(s string) (*ProxySecret, error) {
+	s = strings.TrimSpace(s)
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		b, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, ErrInvalidProxySecret
+		}
+	}
+
+	switch {
+	case len(b) == proxySecretLen:
+		return &ProxySecret{Key: b}, nil
+	case len(b) == proxySecretLen+1 && b[0] == 0xDD:
+		return &ProxySecret{Key: b[1:], Padded: true}, nil
+	case len(b) > proxySecretLen+1 && b[0] == 0xEE:
+		return &ProxySecret{Key: b[1 : proxySecretLen+1], FakeTLS: true, Domain: string(b[proxySecretLen+1:])}, nil
+	default:
+		return nil, ErrInvalidProxySecret
+	}
+}
+
+// DialMTProxy connects to the given DC through an MTProto proxy.
+func DialMTProxy(endpoint string, dcID int, secret *ProxySecret, options TCPTransportOptions) (*TCPTransport, error) {
+	if options.RandomReader == nil {
+		options.RandomReader = rand.Reader
+	}
+
+	c, err := forwardDialer(options.Dialer).Dial("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Obfuscated = true
+	options.DCID = dcID
+	options.ObfuscationSecret = secret.Key
+	if secret.Padded || secret.FakeTLS {
+		options.Framing = PaddedIntermediateFraming
+	}
+
+	if secret.FakeTLS {
+		tc, err := fakeTLSHandshake(c, secret, options.RandomReader, time.Now())
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = tc
+	}
+
+	tr, err := NewTCPTransport(c, options)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tr, nil
+}
@@ -0,0 +1,241 @@
+package mtproto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseProxySecret(t *testing.T) {
+	tests := []struct {
+		input   string
+		key     string
+		padded  bool
+		fakeTLS bool
+		domain  string
+	}{
+		{"00112233445566778899aabbccddeeff", "00112233445566778899aabbccddeeff", false, false, ""},
+		{"dd00112233445566778899aabbccddeeff", "00112233445566778899aabbccddeeff", true, false, ""},
+		{"ee00112233445566778899aabbccddeeff6578616d706c652e636f6d", "00112233445566778899aabbccddeeff", false, true, "example.com"},
+		{"7gARIjNEVWZ3iJmqu8zd7v9leGFtcGxlLmNvbQ", "00112233445566778899aabbccddeeff", false, true, "example.com"},
+	}
+	for _, tt := range tests {
+		secret, err := ParseProxySecret(tt.input)
+		if err != nil {
+			t.Errorf("%v: %v", tt.input, err)
+			continue
+		}
+		if a, e := hex.EncodeToString(secret.Key), tt.key; a != e {
+			t.Errorf("%v: key %v, expected %v", tt.input, a, e)
+		}
+		if secret.Padded != tt.padded || secret.FakeTLS != tt.fakeTLS || secret.Domain != tt.domain {
+			t.Errorf("%v: got %+v", tt.input, secret)
+		}
+	}
+
+	for _, input := range []string{"", "0011", "ff00112233445566778899aabbccddeeff", "xyz!"} {
+		_, err := ParseProxySecret(input)
+		if err != ErrInvalidProxySecret {
+			t.Errorf("%q: got %v, expected %v", input, err, ErrInvalidProxySecret)
+		}
+	}
+}
+
+func TestMTProxy(t *testing.T) {
+	tests := []string{
+		"00112233445566778899aabbccddeeff",
+		"dd00112233445566778899aabbccddeeff",
+		"ee00112233445566778899aabbccddeeff6578616d706c652e636f6d",
+	}
+	for _, s := range tests {
+		secret, err := ParseProxySecret(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dc := startEchoDC(t)
+		proxy := startTestProxy(t, secret, secret.Key, 4, dc.Addr().String())
+
+		tr, err := DialMTProxy(proxy.Addr().String(), 4, secret, TCPTransportOptions{Framing: IntermediateFraming})
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+
+		packet := fromHex("0000000000000000 0102030405060708 04000000 aabbccdd")
+		err = tr.Send(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _, err := tr.Recv()
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+		if a, e := hex.EncodeToString(raw), hex.EncodeToString(packet); a != e {
+			t.Errorf("%v: received %v, expected %v", s, a, e)
+		}
+
+		tr.Close()
+		proxy.Close()
+		dc.Close()
+	}
+}
+
+func TestFakeTLSWrongSecret(t *testing.T) {
+	secret, err := ParseProxySecret("ee00112233445566778899aabbccddeeff6578616d706c652e636f6d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := startTestProxy(t, secret, fromHex("ffeeddccbbaa99887766554433221100"), 4, "")
+	defer proxy.Close()
+
+	_, err = DialMTProxy(proxy.Addr().String(), 4, secret, TCPTransportOptions{})
+	if err != ErrFakeTLSHandshake {
+		t.Errorf("got %v, expected %v", err, ErrFakeTLSHandshake)
+	}
+}
+
+// startEchoDC listens for intermediate-framed connections and echoes every packet back.
+func startEchoDC(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				tag := make([]byte, 4)
+				_, err := io.ReadFull(c, tag)
+				if err != nil {
+					return
+				}
+				for {
+					header := make([]byte, 4)
+					_, err := io.ReadFull(c, header)
+					if err != nil {
+						return
+					}
+					data := make([]byte, binary.LittleEndian.Uint32(header))
+					_, err = io.ReadFull(c, data)
+					if err != nil {
+						return
+					}
+					c.Write(append(header, data...))
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// startTestProxy is a stand-in MTProxy that relays connections for the given DC to dcEndpoint.
+func startTestProxy(t *testing.T, secret *ProxySecret, serverKey []byte, dcID int, dcEndpoint string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+
+				var cc net.Conn = c
+				if secret.FakeTLS {
+					cc, err = acceptTestFakeTLS(c, secret, serverKey)
+					if err != nil {
+						t.Errorf("fake TLS: %v", err)
+						return
+					}
+				}
+
+				oc, tag, actualDC, err := AcceptObfuscated(cc, secret.Key)
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					// client gave up
+					return
+				} else if err != nil {
+					t.Errorf("obfuscation: %v", err)
+					return
+				}
+				if actualDC != dcID {
+					t.Errorf("requested DC %v, expected %v", actualDC, dcID)
+					return
+				}
+				if (secret.Padded || secret.FakeTLS) && !bytes.Equal(tag, []byte{0xDD, 0xDD, 0xDD, 0xDD}) {
+					t.Errorf("got tag %x with a padded secret", tag)
+					return
+				}
+
+				dc, err := net.Dial("tcp", dcEndpoint)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer dc.Close()
+				dc.Write(tag)
+
+				go io.Copy(dc, oc)
+				io.Copy(oc, dc)
+			}()
+		}
+	}()
+	return l
+}
+
+// acceptTestFakeTLS verifies the ClientHello and replies with a response signed with serverKey.
+func acceptTestFakeTLS(c net.Conn, secret *ProxySecret, serverKey []byte) (net.Conn, error) {
+	hello, err := readTLSRecord(c)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(hello, []byte(secret.Domain)) {
+		return nil, ErrFakeTLSHandshake
+	}
+
+	clientRandom := make([]byte, 32)
+	copy(clientRandom, hello[fakeTLSRandomOffset:])
+	copy(hello[fakeTLSRandomOffset:fakeTLSRandomOffset+32], make([]byte, 32))
+
+	mac := hmac.New(sha256.New, secret.Key)
+	mac.Write(hello)
+	digest := mac.Sum(nil)
+	if !bytes.Equal(digest[:28], clientRandom[:28]) {
+		return nil, ErrFakeTLSHandshake
+	}
+	ts := binary.LittleEndian.Uint32(digest[28:32]) ^ binary.LittleEndian.Uint32(clientRandom[28:32])
+	if d := time.Now().Unix() - int64(ts); d < -60 || d > 60 {
+		return nil, ErrFakeTLSHandshake
+	}
+
+	var resp []byte
+	resp = append(resp, tlsRecordHandshake, 0x03, 0x03, 0x00, 0x2c, 0x02, 0x00, 0x00, 0x28, 0x03, 0x03)
+	resp = append(resp, make([]byte, 32)...)
+	resp = append(resp, 0x00, 0x13, 0x01, 0x00, 0x00, 0x00)
+	resp = append(resp, tlsChangeCipherSpec...)
+	resp = append(resp, tlsRecordApplicationData, 0x03, 0x03, 0x00, 0x04, 0x11, 0x22, 0x33, 0x44)
+
+	mac = hmac.New(sha256.New, serverKey)
+	mac.Write(clientRandom)
+	mac.Write(resp)
+	copy(resp[fakeTLSRandomOffset:], mac.Sum(nil))
+
+	_, err = c.Write(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeTLSConn{Conn: c, headerSent: true}, nil
+}
@@ -0,0 +1,174 @@
+package mtproto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
+)
+
+const obfuscatedHeaderLen = 64
+
+var ErrObfuscationNotSupported = errors.New("framing does not support obfuscation")
+
+var ErrInvalidObfuscatedHeader = errors.New("invalid obfuscated header")
+
+// first 4 bytes of the header must not look like any other protocol
+var forbiddenHeaderPrefixes = [][]byte{
+	[]byte("HEAD"),
+	[]byte("POST"),
+	[]byte("GET "),
+	[]byte("OPTI"),
+	{0x16, 0x03, 0x01, 0x02},
+	{0xDD, 0xDD, 0xDD, 0xDD},
+	{0xEE, 0xEE, 0xEE, 0xEE},
+}
+
+// ObfuscatedConn wraps a connection in the AES-256-CTR obfuscation layer
+// (a.k.a. obfuscated2). The 64-byte init header is sent along with the first write.
+type ObfuscatedConn struct {
+	net.Conn
+
+	header []byte
+	enc    cipher.Stream
+	dec    cipher.Stream
+}
+
+// ObfuscateConn starts an obfuscated connection using the given protocol tag
+// (see tcpCodec.ObfuscatedTag). If secret is not nil, it is mixed into the
+// keys, as required by MTProxy.
+func ObfuscateConn(c net.Conn, tag []byte, dcID int, secret []byte, random io.Reader) (*ObfuscatedConn, error) {
+	if len(tag) != 4 {
+		return nil, ErrObfuscationNotSupported
+	}
+
+	header := make([]byte, obfuscatedHeaderLen)
+	for {
+		_, err := io.ReadFull(random, header)
+		if err != nil {
+			return nil, err
+		}
+		if isValidObfuscatedHeader(header) {
+			break
+		}
+	}
+
+	copy(header[56:60], tag)
+	header[60] = byte(dcID)
+	header[61] = byte(dcID >> 8)
+
+	enc, err := obfuscationStream(header[8:40], header[40:56], secret)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := obfuscationStream(reversed(header[8:56])[0:32], reversed(header[8:56])[32:48], secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tail of the header is only sent encrypted
+	encrypted := make([]byte, obfuscatedHeaderLen)
+	enc.XORKeyStream(encrypted, header)
+	copy(header[56:], encrypted[56:])
+
+	return &ObfuscatedConn{
+		Conn:   c,
+		header: header,
+		enc:    enc,
+		dec:    dec,
+	}, nil
+}
+
+// AcceptObfuscated performs the server side of the obfuscation handshake,
+// returning the protocol tag and DC ID requested by the client.
+func AcceptObfuscated(c net.Conn, secret []byte) (*ObfuscatedConn, []byte, int, error) {
+	header := make([]byte, obfuscatedHeaderLen)
+	_, err := io.ReadFull(c, header)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	dec, err := obfuscationStream(header[8:40], header[40:56], secret)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	enc, err := obfuscationStream(reversed(header[8:56])[0:32], reversed(header[8:56])[32:48], secret)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	decrypted := make([]byte, obfuscatedHeaderLen)
+	dec.XORKeyStream(decrypted, header)
+
+	tag := decrypted[56:60]
+	if !bytes.Equal(tag, []byte{0xEF, 0xEF, 0xEF, 0xEF}) && !bytes.Equal(tag, []byte{0xEE, 0xEE, 0xEE, 0xEE}) && !bytes.Equal(tag, []byte{0xDD, 0xDD, 0xDD, 0xDD}) {
+		return nil, nil, 0, ErrInvalidObfuscatedHeader
+	}
+	dcID := int(int16(uint16(decrypted[60]) | uint16(decrypted[61])<<8))
+
+	return &ObfuscatedConn{
+		Conn: c,
+		enc:  enc,
+		dec:  dec,
+	}, tag, dcID, nil
+}
+
+func (c *ObfuscatedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.dec.XORKeyStream(b[:n], b[:n])
+	return n, err
+}
+
+func (c *ObfuscatedConn) Write(b []byte) (int, error) {
+	buf := make([]byte, len(c.header)+len(b))
+	copy(buf, c.header)
+	c.enc.XORKeyStream(buf[len(c.header):], b)
+	c.header = nil
+
+	_, err := c.Conn.Write(buf)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func isValidObfuscatedHeader(header []byte) bool {
+	if header[0] == 0xEF {
+		return false
+	}
+	for _, prefix := range forbiddenHeaderPrefixes {
+		if bytes.Equal(header[0:4], prefix) {
+			return false
+		}
+	}
+	if bytes.Equal(header[4:8], []byte{0, 0, 0, 0}) {
+		return false
+	}
+	return true
+}
+
+func obfuscationStream(key, iv, secret []byte) (cipher.Stream, error) {
+	if secret != nil {
+		h := sha256.New()
+		h.Write(key)
+		h.Write(secret)
+		key = h.Sum(nil)
+	}
+
+	ciph, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(ciph, iv), nil
+}
+
+func reversed(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, v := range b {
+		r[len(b)-1-i] = v
+	}
+	return r
+}
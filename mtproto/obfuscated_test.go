@@ -0,0 +1,129 @@
+package mtproto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+)
+
+func testObfuscationRandom() []byte {
+	random := make([]byte, 2*obfuscatedHeaderLen)
+	for i := range random {
+		random[i] = byte(i)
+	}
+	// the first header starts with 0xEF and must be rejected
+	random[0] = 0xEF
+	return random
+}
+
+func TestObfuscatedTCPTransport(t *testing.T) {
+	tests := []struct {
+		framing TCPFraming
+		tag     string
+		payload string
+		reply   string
+	}{
+		{AbridgedFraming, "efefefef", "02 01020304 05060708", "06"},
+		{IntermediateFraming, "eeeeeeee", "08000000 01020304 05060708", "18000000"},
+		{PaddedIntermediateFraming, "dddddddd", "08000000 01020304 05060708", "1a000000"},
+	}
+	for _, tt := range tests {
+		random := testObfuscationRandom()
+		client, server := net.Pipe()
+		tr, err := NewTCPTransport(client, TCPTransportOptions{
+			Framing:      tt.framing,
+			Obfuscated:   true,
+			RandomReader: io.MultiReader(bytes.NewReader(random), bytes.NewReader([]byte{0})),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			err := tr.Send(fromHex("01020304 05060708"))
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// the unencrypted part of the header is sent as is
+		header := make([]byte, 56)
+		_, err = io.ReadFull(server, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(header), hex.EncodeToString(random[64:120]); a != e {
+			t.Errorf("framing %v sent header %v, expected %v", tt.framing, a, e)
+		}
+
+		sc, tag, dcID, err := AcceptObfuscated(&prefixedConn{server, header}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(tag), tt.tag; a != e {
+			t.Errorf("framing %v sent tag %v, expected %v", tt.framing, a, e)
+		}
+		if dcID != 0 {
+			t.Errorf("framing %v sent DC %v, expected 0", tt.framing, dcID)
+		}
+
+		expected := fromHex(tt.payload)
+		actual := make([]byte, len(expected))
+		_, err = io.ReadFull(sc, actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(actual), hex.EncodeToString(expected); a != e {
+			t.Errorf("framing %v sent %v, expected %v", tt.framing, a, e)
+		}
+
+		// and the reply goes through the reverse keys
+		reply := "0000000000000000 0102030405060708 04000000 aabbccdd"
+		if tt.framing == PaddedIntermediateFraming {
+			reply += " 1122"
+		}
+		go sc.Write(fromHex(tt.reply + reply))
+		raw, _, err := tr.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(raw), hex.EncodeToString(fromHex(reply)[:24]); a != e {
+			t.Errorf("framing %v received %v, expected %v", tt.framing, a, e)
+		}
+
+		tr.Close()
+		server.Close()
+	}
+}
+
+func TestObfuscatedFullFramingNotSupported(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	_, err := NewTCPTransport(client, TCPTransportOptions{
+		Framing:      FullFraming,
+		Obfuscated:   true,
+		RandomReader: bytes.NewReader(testObfuscationRandom()),
+	})
+	if err != ErrObfuscationNotSupported {
+		t.Errorf("got %v, expected %v", err, ErrObfuscationNotSupported)
+	}
+}
+
+// prefixedConn returns the given bytes before reading from the connection.
+type prefixedConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (c *prefixedConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
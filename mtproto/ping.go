@@ -0,0 +1,68 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"io"
+	"log"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+)
+
+// RTT returns the round-trip time measured by the last keepalive ping, or
+// zero if none has been answered yet.
+func (sess *Session) RTT() time.Duration {
+	sess.stateMut.Lock()
+	defer sess.stateMut.Unlock()
+	return sess.rtt
+}
+
+func (sess *Session) pingTimeout() time.Duration {
+	if sess.options.PingTimeout > 0 {
+		return sess.options.PingTimeout
+	}
+	return sess.options.PingInterval
+}
+
+// ping sends ping_delay_disconnect, which also asks the server to close the
+// connection if the next ping does not arrive in time.
+func (sess *Session) ping() {
+	if !sess.connKeyExDone || sess.binding {
+		return
+	}
+
+	var buf [8]byte
+	_, err := io.ReadFull(rand.Reader, buf[:])
+	if err != nil {
+		panic(err)
+	}
+	sess.pingID = binints.DecodeUint64LE(buf[:])
+	sess.pingSentAt = time.Now()
+
+	timeout := sess.pingTimeout()
+	if sess.pongDeadline == nil {
+		sess.pongDeadline = time.After(timeout)
+	}
+
+	delay := (sess.options.PingInterval + timeout + time.Second - 1) / time.Second
+	sess.sendInternal(&TLPingDelayDisconnect{
+		PingID:          sess.pingID,
+		DisconnectDelay: int(delay),
+	}, nil)
+}
+
+func (sess *Session) handlePong(o *TLPong) {
+	if o.PingID != sess.pingID || sess.pingSentAt.IsZero() {
+		return
+	}
+	rtt := time.Since(sess.pingSentAt)
+	sess.pingSentAt = time.Time{}
+
+	sess.stateMut.Lock()
+	sess.rtt = rtt
+	sess.stateMut.Unlock()
+
+	if sess.options.Verbose >= 2 {
+		log.Printf("mtproto.Session RTT %v", rtt)
+	}
+}
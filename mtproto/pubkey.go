@@ -3,9 +3,11 @@ package mtproto
 import (
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/x509"
 	"encoding/asn1"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/andreyvit/telegramapi/binints"
@@ -14,11 +16,86 @@ import (
 
 const rsaBlockLen = 256
 
+// NoKnownServerKeyError is returned by the key exchange when none of the
+// fingerprints offered by the server belongs to a key in the key ring.
+type NoKnownServerKeyError struct {
+	Fingerprints []uint64
+}
+
+func (e *NoKnownServerKeyError) Error() string {
+	return fmt.Sprintf("server offered unknown public keys %016x, check the key ring", e.Fingerprints)
+}
+
+// KeyRing holds the server public keys trusted by the key exchange.
+type KeyRing []*rsa.PublicKey
+
+// ParseKeyRing parses all PEM blocks in the given strings, accepting both
+// PKCS#1 (RSA PUBLIC KEY) and PKIX/PKCS#8 (PUBLIC KEY) encodings.
+func ParseKeyRing(pems ...string) (KeyRing, error) {
+	var ring KeyRing
+	for _, s := range pems {
+		rest := []byte(s)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			key, err := parsePublicKeyBlock(block)
+			if err != nil {
+				return nil, err
+			}
+			ring = append(ring, key)
+		}
+	}
+	if len(ring) == 0 {
+		return nil, errors.New("failed to parse PEM block containing the public key")
+	}
+	return ring, nil
+}
+
+// DefaultKeyRing returns the built-in production and test server keys.
+func DefaultKeyRing() KeyRing {
+	ring, err := ParseKeyRing(ProductionPublicKeys, TestPublicKeys)
+	if err != nil {
+		panic(err)
+	}
+	return ring
+}
+
+// Select returns the first key of the ring matching one of the given fingerprints.
+func (ring KeyRing) Select(fingerprints []uint64) (*rsa.PublicKey, uint64, error) {
+	for _, key := range ring {
+		fingerprint := ComputePubKeyFingerprint(key)
+		for _, f := range fingerprints {
+			if f == fingerprint {
+				return key, fingerprint, nil
+			}
+		}
+	}
+	return nil, 0, &NoKnownServerKeyError{fingerprints}
+}
+
 func ParsePublicKey(s string) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode([]byte(s))
 	if block == nil {
 		return nil, errors.New("failed to parse PEM block containing the public key")
 	}
+	return parsePublicKeyBlock(block)
+}
+
+func parsePublicKeyBlock(block *pem.Block) (*rsa.PublicKey, error) {
+	if block.Type == "PUBLIC KEY" {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("not an RSA public key")
+		}
+		return key, nil
+	}
 
 	key := new(rsa.PublicKey)
 	_, err := asn1.Unmarshal(block.Bytes, key)
@@ -52,3 +129,13 @@ func EncryptRSA(data []byte, key *rsa.PublicKey) []byte {
 
 	return leftZeroPad(result.Bytes(), rsaBlockLen)
 }
+
+func DecryptRSA(data []byte, key *rsa.PrivateKey) []byte {
+	var c big.Int
+	c.SetBytes(data)
+
+	var result big.Int
+	result.Exp(&c, key.D, key.N)
+
+	return leftZeroPad(result.Bytes(), rsaBlockLen-1)
+}
@@ -1,7 +1,11 @@
 package mtproto
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
 	"math/big"
 	"testing"
 )
@@ -41,3 +45,47 @@ func TestParsePublicKey(t *testing.T) {
 		t.Errorf("N == %v, expected %v", key.N, n)
 	}
 }
+
+func TestParseKeyRing(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	ring, err := ParseKeyRing(publicKey + pkcs8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring) != 2 {
+		t.Fatalf("got %d keys, expected 2", len(ring))
+	}
+
+	fingerprint := ComputePubKeyFingerprint(&privKey.PublicKey)
+	key, f, err := ring.Select([]uint64{42, fingerprint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != fingerprint || key.N.Cmp(privKey.N) != 0 {
+		t.Errorf("selected key %016x, expected %016x", f, fingerprint)
+	}
+
+	_, _, err = ring.Select([]uint64{42})
+	if e, ok := err.(*NoKnownServerKeyError); !ok || len(e.Fingerprints) != 1 || e.Fingerprints[0] != 42 {
+		t.Errorf("got %v, expected NoKnownServerKeyError", err)
+	}
+}
+
+func TestDefaultKeyRing(t *testing.T) {
+	expected := []uint64{0xd09d1d85de64fd85, 0xc3b42b026ce86b21, 0xb25898df208d2603}
+	ring := DefaultKeyRing()
+	for _, f := range expected {
+		if _, _, err := ring.Select([]uint64{f}); err != nil {
+			t.Errorf("built-in key %016x: %v", f, err)
+		}
+	}
+}
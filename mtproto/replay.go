@@ -0,0 +1,119 @@
+package mtproto
+
+import (
+	"errors"
+	"log"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+var ErrBadMsgID = errors.New("invalid server msg_id")
+
+var ErrDuplicateMsgID = errors.New("duplicate msg_id")
+
+var ErrBadSeqNo = errors.New("seqno out of order")
+
+// Diagnostics counts the incoming messages that failed validation. Such
+// messages are dropped without being handled.
+type Diagnostics struct {
+	BadSessionID int // encrypted for another session
+	BadMsgID     int // even msg_id, or outside the time window
+	Duplicates   int // msg_id seen before, e.g. a replayed frame
+	BadSeqNo     int // content-related seqno out of order
+}
+
+// Diagnostics returns the counters of dropped incoming messages.
+func (sess *Session) Diagnostics() Diagnostics {
+	sess.stateMut.Lock()
+	defer sess.stateMut.Unlock()
+	return sess.diag
+}
+
+// checkIncoming validates the msg_id and seqno of a server message. The
+// msg_id must be odd, fall within the time window around the server clock and
+// not repeat one received recently; recent msg_ids are remembered for as long
+// as the window lasts. Content-related seqnos must grow along with msg_ids.
+func (sess *Session) checkIncoming(msgID uint64, seqNo int, typ MsgType) error {
+	if (msgID & 1) == 0 {
+		return ErrBadMsgID
+	}
+
+	now := sess.serverNow()
+	t := int(msgID >> 32)
+	if t < now-msgStateTTL || t > now+maxMsgAhead {
+		return ErrBadMsgID
+	}
+
+	if _, ok := sess.received[msgID]; ok {
+		return ErrDuplicateMsgID
+	}
+
+	if typ == ContentMsg {
+		if sess.lastContentMsgID != 0 && (msgID > sess.lastContentMsgID) != (seqNo > sess.lastContentSeqNo) {
+			return ErrBadSeqNo
+		}
+		if msgID > sess.lastContentMsgID {
+			sess.lastContentMsgID = msgID
+			sess.lastContentSeqNo = seqNo
+		}
+	}
+	return nil
+}
+
+// clockCorrection returns the msg_id of a bad_msg_notification 16/17 found
+// in o or in the container o, or 0. The server time is only taken from such
+// notifications (and from the key exchange), not from arbitrary messages: a
+// replayed old frame would move our clock back and let itself and other
+// stale frames through the msg_id window. The notification must be about a
+// message we are still waiting on, which a replayed one cannot be.
+func (sess *Session) clockCorrection(msgID uint64, o tl.Object) uint64 {
+	switch o := o.(type) {
+	case *TLMsgContainer:
+		for _, msg := range o.Messages {
+			if id := sess.clockCorrection(msg.MsgID, msg.Body); id != 0 {
+				return id
+			}
+		}
+	case *TLBadMsgNotification:
+		if (o.ErrorCode == 16 || o.ErrorCode == 17) && sess.isPendingMsg(o.BadMsgID) {
+			return msgID
+		}
+	}
+	return 0
+}
+
+// isPendingMsg reports whether msgID is a message or container of ours
+// that has not been answered or acknowledged yet.
+func (sess *Session) isPendingMsg(msgID uint64) bool {
+	if _, ok := sess.containers[msgID]; ok {
+		return true
+	}
+	return sess.inFlight[msgID] != nil || sess.sent[msgID] != nil
+}
+
+// resetSeqNo forgets the seqno of the last content-related message once the
+// server starts a new session, which numbers its messages from scratch.
+func (sess *Session) resetSeqNo() {
+	sess.lastContentMsgID = 0
+	sess.lastContentSeqNo = 0
+}
+
+// rejectMsg counts a message that failed validation.
+func (sess *Session) rejectMsg(err error) {
+	sess.stateMut.Lock()
+	switch err {
+	case ErrSessionIDMismatch:
+		sess.diag.BadSessionID++
+	case ErrBadMsgID:
+		sess.diag.BadMsgID++
+	case ErrDuplicateMsgID:
+		sess.diag.Duplicates++
+	case ErrBadSeqNo:
+		sess.diag.BadSeqNo++
+	}
+	sess.stateMut.Unlock()
+
+	if sess.options.Verbose >= 1 {
+		log.Printf("mtproto.Session dropping incoming message: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package mtproto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func TestCheckIncoming(t *testing.T) {
+	sess := NewSession(nil, SessionOptions{})
+	sess.framer.SetAuth(testAuth2())
+
+	now := uint64(time.Now().Unix()) << 32
+	hour := uint64(3600) << 32
+	tests := []struct {
+		msgID uint64
+		seqNo int
+		typ   MsgType
+		err   error
+	}{
+		{now | 1, 1, ContentMsg, nil},
+		{now | 1, 1, ContentMsg, ErrDuplicateMsgID},
+		{now | 4, 2, ServiceMsg, ErrBadMsgID},
+		{now | 5, 2, ServiceMsg, nil},
+		{now | 9, 1, ContentMsg, ErrBadSeqNo},
+		{now | 13, 3, ContentMsg, nil},
+		{now | 11, 5, ContentMsg, ErrBadSeqNo},
+		{(now - hour) | 1, 5, ContentMsg, ErrBadMsgID},
+		{(now + hour) | 1, 5, ContentMsg, ErrBadMsgID},
+	}
+
+	for i, tt := range tests {
+		err := sess.checkIncoming(tt.msgID, tt.seqNo, tt.typ)
+		if err != tt.err {
+			t.Errorf("#%d: checkIncoming(%x, %d) = %v, expected %v", i, tt.msgID, tt.seqNo, err, tt.err)
+		}
+		if err == nil {
+			sess.markReceived(tt.msgID, tt.typ)
+		}
+	}
+}
+
+func TestClockCorrection(t *testing.T) {
+	sess := NewSession(nil, SessionOptions{})
+	sess.framer.SetAuth(testAuth2())
+
+	// a replayed old frame, even the first one received, must not move the clock
+	old := uint64(time.Now().Unix()-3600)<<32 | 1
+	if err := sess.checkIncoming(old, 1, ContentMsg); err != ErrBadMsgID {
+		t.Errorf("checkIncoming(old msg_id) = %v, expected %v", err, ErrBadMsgID)
+	}
+
+	pending := uint64(time.Now().Unix()) << 32
+	sess.inFlight[pending] = &rpcInFlight{}
+	tests := []struct {
+		o        tl.Object
+		expected uint64
+	}{
+		{&TLBadMsgNotification{BadMsgID: pending, ErrorCode: 16}, old},
+		{&TLBadMsgNotification{BadMsgID: pending, ErrorCode: 17}, old},
+		{&TLBadMsgNotification{BadMsgID: pending, ErrorCode: 32}, 0},
+		{&TLBadMsgNotification{BadMsgID: pending + 4, ErrorCode: 16}, 0},
+		{&TLMsgContainer{Messages: []*TLProtoMessage{
+			{MsgID: old + 2, Body: &TLPong{}},
+			{MsgID: old + 4, Body: &TLBadMsgNotification{BadMsgID: pending, ErrorCode: 16}},
+		}}, old + 4},
+		{&TLPong{}, 0},
+	}
+	for i, tt := range tests {
+		if id := sess.clockCorrection(old, tt.o); id != tt.expected {
+			t.Errorf("#%d: clockCorrection(%v) = %x, expected %x", i, tt.o, id, tt.expected)
+		}
+	}
+}
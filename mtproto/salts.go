@@ -0,0 +1,164 @@
+package mtproto
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// FutureSalt is a server salt along with the server Unix time range it is valid in.
+type FutureSalt struct {
+	Salt       uint64
+	ValidSince int
+	ValidUntil int
+}
+
+const (
+	// how many salts to ask get_future_salts for (the server caps this at 64)
+	futureSaltsToRequest = 32
+
+	// ask for more salts when fewer than this many are left
+	minFutureSalts = 4
+
+	saltRetryDelay = time.Minute
+)
+
+// selectSalt picks the newest salt that is already valid at the given server
+// time. The salts must be sorted by ValidSince; the returned rest starts with
+// the selected salt and drops the ones before it.
+func selectSalt(salts []FutureSalt, now int) (cur *FutureSalt, rest []FutureSalt) {
+	k := -1
+	for i, s := range salts {
+		if s.ValidSince > now {
+			break
+		}
+		if s.ValidUntil > now {
+			k = i
+		}
+	}
+	if k < 0 {
+		// drop the expired ones
+		for len(salts) > 0 && salts[0].ValidUntil <= now {
+			salts = salts[1:]
+		}
+		return nil, salts
+	}
+	return &salts[k], salts[k:]
+}
+
+// nextSaltSwitch returns the number of seconds until selectSalt would pick
+// another salt, or 0 if there is nothing to switch to.
+func nextSaltSwitch(salts []FutureSalt, now int) int {
+	next := 0
+	consider := func(t int) {
+		if t > now && (next == 0 || t < next) {
+			next = t
+		}
+	}
+	for _, s := range salts {
+		consider(s.ValidSince)
+		consider(s.ValidUntil)
+	}
+	if next == 0 {
+		return 0
+	}
+	return next - now
+}
+
+// updateSalt switches to the newest future salt that is already valid and
+// schedules the next switch. More salts are requested when running low.
+func (sess *Session) updateSalt() {
+	if !sess.connKeyExDone || sess.binding {
+		return
+	}
+
+	sess.stateMut.Lock()
+	auth, _ := sess.framer.State()
+	now := int(time.Now().Unix()) + auth.TimeOffset
+	cur, rest := selectSalt(auth.FutureSalts, now)
+	if cur != nil {
+		binints.EncodeUint64LE(cur.Salt, auth.ServerSalt[:])
+	}
+	auth.FutureSalts = rest
+	wait := nextSaltSwitch(rest, now)
+	sess.stateMut.Unlock()
+
+	sess.saltc = nil
+	if wait > 0 {
+		sess.saltc = time.After(time.Duration(wait) * time.Second)
+	}
+
+	if len(rest) < minFutureSalts && !sess.fetchingSalts {
+		sess.fetchFutureSalts()
+	}
+}
+
+func (sess *Session) fetchFutureSalts() {
+	if sess.options.Verbose >= 2 {
+		log.Printf("mtproto.Session requesting future salts")
+	}
+	sess.fetchingSalts = true
+	sess.sendMsgInternal(&TLGetFutureSalts{Num: futureSaltsToRequest}, false, &rpcInFlight{
+		Callback: sess.handleFutureSalts,
+	})
+}
+
+func (sess *Session) handleFutureSalts(obj tl.Object, err error) {
+	sess.fetchingSalts = false
+
+	fs, ok := obj.(*TLFutureSalts)
+	if err == nil && !ok {
+		err = fmt.Errorf("unexpected reply to get_future_salts: %v", obj)
+	}
+	if err != nil {
+		// not fatal, the current salt keeps working until bad_server_salt
+		log.Printf("WARNING: get_future_salts failed: %v", err)
+		sess.saltc = time.After(saltRetryDelay)
+		return
+	}
+
+	salts := make([]FutureSalt, 0, len(fs.Salts))
+	for _, s := range fs.Salts {
+		salts = append(salts, FutureSalt{s.Salt, s.ValidSince, s.ValidUntil})
+	}
+	sort.Slice(salts, func(i, j int) bool {
+		return salts[i].ValidSince < salts[j].ValidSince
+	})
+
+	sess.stateMut.Lock()
+	auth, _ := sess.framer.State()
+	auth.FutureSalts = salts
+	sess.stateMut.Unlock()
+
+	sess.updateSalt()
+	sess.notifyStateChanged()
+}
+
+// handleBadServerSalt adopts the salt suggested by the server and resends
+// the rejected messages on the same connection.
+func (sess *Session) handleBadServerSalt(o *TLBadServerSalt) {
+	if sess.options.Verbose >= 1 {
+		log.Printf("mtproto.Session bad server salt for msgID %08x", o.BadMsgID)
+	}
+
+	sess.stateMut.Lock()
+	auth, _ := sess.framer.State()
+	binints.EncodeUint64LE(o.NewServerSalt, auth.ServerSalt[:])
+	// the salts we have are evidently out of date
+	auth.FutureSalts = nil
+	sess.stateMut.Unlock()
+
+	// everything still unacknowledged has been rejected along with it
+	for _, id := range sess.expandContainer(o.BadMsgID) {
+		if !sess.resendSent(id) && sess.inFlight[id] != nil {
+			sess.finishPendingRPC(id, nil, ErrInvalidMsg)
+		}
+	}
+
+	sess.updateSalt()
+	sess.notifyStateChanged()
+}
@@ -0,0 +1,74 @@
+package mtproto
+
+import (
+	"testing"
+
+	"github.com/andreyvit/telegramapi/binints"
+)
+
+func TestSelectSalt(t *testing.T) {
+	salts := []FutureSalt{
+		{1, 100, 200},
+		{2, 150, 250},
+		{3, 200, 300},
+	}
+
+	tests := []struct {
+		now  int
+		salt uint64
+		rest int
+		next int
+	}{
+		{50, 0, 3, 50},
+		{100, 1, 3, 50},
+		{160, 2, 2, 40},
+		{200, 3, 1, 100},
+		{299, 3, 1, 1},
+		{300, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		cur, rest := selectSalt(salts, tt.now)
+		var salt uint64
+		if cur != nil {
+			salt = cur.Salt
+		}
+		next := nextSaltSwitch(rest, tt.now)
+		if salt != tt.salt || len(rest) != tt.rest || next != tt.next {
+			t.Errorf("at %d: got salt %d, %d left, next switch in %d; expected salt %d, %d left, next switch in %d", tt.now, salt, len(rest), next, tt.salt, tt.rest, tt.next)
+		}
+	}
+}
+
+func TestBadServerSaltResendsContainer(t *testing.T) {
+	sess := NewSession(nil, SessionOptions{})
+	sess.RestoreAuthState(testAuth2(), FramerState{})
+	// keep updateSalt from asking for future salts over the nil transport
+	sess.fetchingSalts = true
+
+	// a container with a request and a message that expects no answer
+	req, other := &TLHelpGetConfig{}, &TLHelpGetNearestDC{}
+	replyc := make(chan reply, 1)
+	infl := &rpcInFlight{Reply: replyc, Obj: req, ContainerID: 100}
+	sess.inFlight[101] = infl
+	sess.containers[100] = []uint64{101, 102}
+	sess.trackSent(101, queuedMsg{req, MsgFromObj(req), infl})
+	sess.trackSent(102, queuedMsg{other, MsgFromObj(other), nil})
+
+	sess.handleBadServerSalt(&TLBadServerSalt{BadMsgID: 100, NewServerSalt: 0x55})
+
+	auth, _ := sess.framer.State()
+	if salt := binints.DecodeUint64LE(auth.ServerSalt[:]); salt != 0x55 {
+		t.Errorf("using salt %x, expected %x", salt, 0x55)
+	}
+	if len(sess.queue) != 2 || sess.queue[0].Obj != req || sess.queue[0].Infl != infl || sess.queue[1].Obj != other {
+		t.Errorf("got %d queued messages, expected the request and the other message", len(sess.queue))
+	}
+	if len(sess.sent) != 0 {
+		t.Errorf("%d messages still tracked under their old msg_ids", len(sess.sent))
+	}
+	select {
+	case r := <-replyc:
+		t.Errorf("request failed with %v, expected it to be resent", r.Err)
+	default:
+	}
+}
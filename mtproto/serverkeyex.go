@@ -0,0 +1,306 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// 2048-bit safe prime used by Telegram servers
+var defaultDHPrime, _ = new(big.Int).SetString("c71caeb9c6b1c9048e6c522f70f13f73980d40238e3e21c14934d037563d930f48198a0aa7c14058229493d22530f4dbfa336f6e0ac925139543aed44cce7c3720fd51f69458705ac68cd4fe6b6b13abdc9746512969328454f18faf8c595f642477fe96bb2a941d5bcd1d4ac8cc49880708fa9b378e3c4f3a9060bee67cf9a4a4a695811051907e162753b56b0f6b410dba74d8a84b2a14b3144e0ef1284754fd17ed950d5965b4b9dd46582db1178d169c6bc465b0d6ff9ca3928fef5b9ae4e418fc15e83ebea0f87fa9ff5eed70050ded2849f47bf959d956850ce929851f0d8115f635b105ee2e4e15d04b2454bf6f4fadf034b10403119cd8e3b92fcc5b", 16)
+
+const defaultDHG = 3
+
+// size of the primes making up the pq challenge
+const pqPrimeBits = 31
+
+var ErrUnknownFingerprint = errors.New("unknown public key fingerprint")
+
+var ErrBadPQInnerData = errors.New("invalid p_q_inner_data")
+
+var ErrBadClientDHInnerData = errors.New("invalid client_DH_inner_data")
+
+// ServerKeyEx is the server side of the key exchange: it answers req_pq,
+// req_DH_params and set_client_DH_params, producing the same AuthResult as KeyEx.
+type ServerKeyEx struct {
+	RandomReader io.Reader
+	PrivKey      *rsa.PrivateKey
+
+	// DH group, defaults to the one used by Telegram
+	DHPrime *big.Int
+	G       int
+
+	state keyExState
+	err   error
+
+	nonce       [16]byte
+	newNonce    [32]byte
+	serverNonce [16]byte
+	p, q        *big.Int
+
+	tmpAESKey [32]byte
+	tmpAESIV  [32]byte
+
+	a *big.Int
+
+	// non-zero when the client asked for a temporary key
+	expiresIn int
+
+	auth AuthResult
+}
+
+func (kex *ServerKeyEx) Result() (*AuthResult, error) {
+	switch kex.state {
+	case KeyExDone:
+		return &kex.auth, nil
+	case KeyExFailed:
+		return nil, kex.err
+	default:
+		return nil, ErrKeyExchangeNotFinished
+	}
+}
+
+func (kex *ServerKeyEx) IsFinished() bool {
+	switch kex.state {
+	case KeyExDone, KeyExFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (kex *ServerKeyEx) Handle(o tl.Object) (tl.Object, error) {
+	omsg, err := kex.handle(o)
+	if err != nil {
+		kex.state = KeyExFailed
+		kex.err = err
+	}
+	return omsg, err
+}
+
+func (kex *ServerKeyEx) handle(o tl.Object) (tl.Object, error) {
+	switch kex.state {
+	case KeyExInit:
+		switch o := o.(type) {
+		case *TLReqPQ:
+			return kex.handleReqPQ(o)
+		default:
+			return nil, ErrUnexpectedCommand
+		}
+
+	case KeyExReqPQ:
+		switch o := o.(type) {
+		case *TLReqDHParams:
+			return kex.handleReqDHParams(o)
+		default:
+			return nil, ErrUnexpectedCommand
+		}
+
+	case KeyExReqDHParams:
+		switch o := o.(type) {
+		case *TLSetClientDHParams:
+			return kex.handleSetClientDHParams(o)
+		default:
+			return nil, ErrUnexpectedCommand
+		}
+
+	default:
+		return nil, ErrUnexpectedCommand
+	}
+}
+
+func (kex *ServerKeyEx) handleReqPQ(in *TLReqPQ) (tl.Object, error) {
+	if kex.RandomReader == nil {
+		kex.RandomReader = rand.Reader
+	}
+	if kex.DHPrime == nil {
+		kex.DHPrime = defaultDHPrime
+	}
+	if kex.G == 0 {
+		kex.G = defaultDHG
+	}
+
+	kex.nonce = in.Nonce
+	_, err := io.ReadFull(kex.RandomReader, kex.serverNonce[:])
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		kex.p, err = rand.Prime(kex.RandomReader, pqPrimeBits)
+		if err != nil {
+			return nil, err
+		}
+		kex.q, err = rand.Prime(kex.RandomReader, pqPrimeBits)
+		if err != nil {
+			return nil, err
+		}
+		switch kex.p.Cmp(kex.q) {
+		case 1:
+			kex.p, kex.q = kex.q, kex.p
+		case 0:
+			continue
+		}
+		break
+	}
+
+	kex.state = KeyExReqPQ
+	return &TLResPQ{
+		Nonce:                       kex.nonce,
+		ServerNonce:                 kex.serverNonce,
+		PQ:                          new(big.Int).Mul(kex.p, kex.q),
+		ServerPublicKeyFingerprints: []uint64{ComputePubKeyFingerprint(&kex.PrivKey.PublicKey)},
+	}, nil
+}
+
+func (kex *ServerKeyEx) handleReqDHParams(in *TLReqDHParams) (tl.Object, error) {
+	if 1 != subtle.ConstantTimeCompare(in.Nonce[:], kex.nonce[:]) || 1 != subtle.ConstantTimeCompare(in.ServerNonce[:], kex.serverNonce[:]) {
+		return nil, ErrBadNonce
+	}
+	if in.PublicKeyFingerprint != ComputePubKeyFingerprint(&kex.PrivKey.PublicKey) {
+		return nil, ErrUnknownFingerprint
+	}
+	if len(in.EncryptedData) != rsaBlockLen {
+		return nil, ErrBadPQInnerData
+	}
+
+	data, dataHash := DecryptRSAWithHash(in.EncryptedData, kex.PrivKey)
+	rawinner, err := Schema.ReadLimitedBoxedObjectNoEOFCheck(data, TagPQInnerData, TagPQInnerDataTemp)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum(tl.Bytes(rawinner))
+	if 1 != subtle.ConstantTimeCompare(hash[:], dataHash) {
+		return nil, ErrBadPQInnerData
+	}
+
+	var inner TLPQInnerData
+	switch o := rawinner.(type) {
+	case *TLPQInnerData:
+		inner = *o
+	case *TLPQInnerDataTemp:
+		if o.ExpiresIn <= 0 {
+			return nil, ErrBadPQInnerData
+		}
+		inner = TLPQInnerData{
+			PQ:          o.PQ,
+			P:           o.P,
+			Q:           o.Q,
+			Nonce:       o.Nonce,
+			ServerNonce: o.ServerNonce,
+			NewNonce:    o.NewNonce,
+		}
+		kex.expiresIn = o.ExpiresIn
+	}
+
+	if 1 != subtle.ConstantTimeCompare(inner.Nonce[:], kex.nonce[:]) || 1 != subtle.ConstantTimeCompare(inner.ServerNonce[:], kex.serverNonce[:]) {
+		return nil, ErrBadNonce
+	}
+	if inner.P.Cmp(kex.p) != 0 || inner.Q.Cmp(kex.q) != 0 || in.P.Cmp(kex.p) != 0 || in.Q.Cmp(kex.q) != 0 {
+		return nil, ErrBadPQInnerData
+	}
+	kex.newNonce = inner.NewNonce
+
+	deriveTempAESKey(kex.serverNonce[:], kex.newNonce[:], kex.tmpAESKey[:], kex.tmpAESIV[:])
+
+	var abytes [256]byte
+	_, err = io.ReadFull(kex.RandomReader, abytes[:])
+	if err != nil {
+		return nil, err
+	}
+	kex.a = new(big.Int).SetBytes(abytes[:])
+
+	answer := &TLServerDHInnerData{
+		G:          kex.G,
+		DHPrime:    kex.DHPrime,
+		GA:         new(big.Int).Exp(big.NewInt(int64(kex.G)), kex.a, kex.DHPrime),
+		ServerTime: time.Now(),
+	}
+	copy(answer.Nonce[:], kex.nonce[:])
+	copy(answer.ServerNonce[:], kex.serverNonce[:])
+
+	encrypted, err := AESIGEPadEncryptWithHash(nil, tl.Bytes(answer), kex.tmpAESKey[:], kex.tmpAESIV[:], kex.RandomReader)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &TLServerDHParamsOK{
+		EncryptedAnswer: encrypted,
+	}
+	copy(reply.Nonce[:], kex.nonce[:])
+	copy(reply.ServerNonce[:], kex.serverNonce[:])
+
+	kex.state = KeyExReqDHParams
+	return reply, nil
+}
+
+func (kex *ServerKeyEx) handleSetClientDHParams(in *TLSetClientDHParams) (tl.Object, error) {
+	if 1 != subtle.ConstantTimeCompare(in.Nonce[:], kex.nonce[:]) || 1 != subtle.ConstantTimeCompare(in.ServerNonce[:], kex.serverNonce[:]) {
+		return nil, ErrBadNonce
+	}
+
+	data, dataHash, err := AESIGEDecryptWithHash(nil, in.EncryptedData, kex.tmpAESKey[:], kex.tmpAESIV[:])
+	if err != nil {
+		return nil, err
+	}
+	rawinner, err := Schema.ReadLimitedBoxedObjectNoEOFCheck(data, TagClientDHInnerData)
+	if err != nil {
+		return nil, err
+	}
+	inner := rawinner.(*TLClientDHInnerData)
+
+	hash := sha1.Sum(tl.Bytes(inner))
+	if 1 != subtle.ConstantTimeCompare(hash[:], dataHash) {
+		return nil, ErrBadClientDHInnerData
+	}
+	if 1 != subtle.ConstantTimeCompare(inner.Nonce[:], kex.nonce[:]) || 1 != subtle.ConstantTimeCompare(inner.ServerNonce[:], kex.serverNonce[:]) {
+		return nil, ErrBadNonce
+	}
+	// retries are not supported, so this is always the first attempt
+	if inner.RetryID != 0 {
+		return nil, ErrBadClientDHInnerData
+	}
+
+	if !isValidDHPublic(inner.GB, kex.DHPrime) {
+		return nil, ErrBadGB
+	}
+
+	gab := new(big.Int).Exp(inner.GB, kex.a, kex.DHPrime)
+	kex.auth.Key = leftZeroPad(gab.Bytes(), 256)
+
+	authKeyHash := sha1.Sum(kex.auth.Key)
+	kex.auth.KeyID = binints.DecodeUint64LE(authKeyHash[12:])
+	for i := 0; i < 8; i++ {
+		kex.auth.ServerSalt[i] = kex.newNonce[i] ^ kex.serverNonce[i]
+	}
+	if kex.expiresIn != 0 {
+		kex.auth.ExpiresAt = int(time.Now().Unix()) + kex.expiresIn
+	}
+
+	reply := &TLDHGenOK{}
+	copy(reply.Nonce[:], kex.nonce[:])
+	copy(reply.ServerNonce[:], kex.serverNonce[:])
+	computeNewNonceHash(kex.newNonce[:], 1, authKeyHash[:8], reply.NewNonceHash1[:])
+
+	kex.state = KeyExDone
+	return reply, nil
+}
+
+// computeNewNonceHash computes new_nonce_hashN = substr(SHA1(new_nonce + N + auth_key_aux_hash), 4, 16).
+func computeNewNonceHash(newNonce []byte, n byte, authKeyAuxHash []byte, hash []byte) {
+	var src [41]byte
+	copy(src[:32], newNonce)
+	src[32] = n
+	copy(src[33:], authKeyAuxHash)
+	sum := sha1.Sum(src[:])
+	copy(hash, sum[4:20])
+}
@@ -0,0 +1,171 @@
+package mtproto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+func runTestKeyEx(t *testing.T, client *KeyEx, server *ServerKeyEx) {
+	o := client.Start()
+	for !client.IsFinished() {
+		// go through the wire format like real messages
+		req, err := Schema.ReadBoxedObject(tl.Bytes(o))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, err := server.Handle(req)
+		if err != nil {
+			t.Fatalf("server failed: %v", err)
+		}
+		reply, err = Schema.ReadBoxedObject(tl.Bytes(reply))
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err = client.Handle(reply)
+		if err != nil {
+			t.Fatalf("client failed: %v", err)
+		}
+	}
+}
+
+func TestServerKeyEx(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &KeyEx{PubKeys: KeyRing{&privKey.PublicKey}}
+	server := &ServerKeyEx{PrivKey: privKey}
+	runTestKeyEx(t, client, server)
+
+	clientAuth, err := client.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverAuth, err := server.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(clientAuth.Key, serverAuth.Key) || clientAuth.KeyID != serverAuth.KeyID || clientAuth.ServerSalt != serverAuth.ServerSalt {
+		t.Errorf("auth mismatch: client key ID %x salt %x, server key ID %x salt %x", clientAuth.KeyID, clientAuth.ServerSalt, serverAuth.KeyID, serverAuth.ServerSalt)
+	}
+}
+
+// A temp key expires in server time even if the local clock is off.
+func TestServerKeyExTempKeySkewedClock(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skew := 2 * time.Hour
+	client := &KeyEx{
+		PubKeys:   KeyRing{&privKey.PublicKey},
+		ExpiresIn: 3600,
+		Now: func() time.Time {
+			return time.Now().Add(-skew)
+		},
+	}
+	server := &ServerKeyEx{PrivKey: privKey}
+	runTestKeyEx(t, client, server)
+
+	clientAuth, err := client.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverAuth, err := server.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := clientAuth.TimeOffset - int(skew/time.Second); d < -2 || d > 2 {
+		t.Errorf("time offset is %d, expected %d", clientAuth.TimeOffset, int(skew/time.Second))
+	}
+	if d := clientAuth.ExpiresAt - serverAuth.ExpiresAt; d < -2 || d > 2 {
+		t.Errorf("client expires_at is %d, server has %d", clientAuth.ExpiresAt, serverAuth.ExpiresAt)
+	}
+}
+
+func TestServerKeyExUnknownFingerprint(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &ServerKeyEx{PrivKey: privKey}
+	res, err := server.Handle(&TLReqPQ{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Handle(&TLReqDHParams{
+		ServerNonce:          res.(*TLResPQ).ServerNonce,
+		PublicKeyFingerprint: 42,
+	})
+	if err != ErrUnknownFingerprint {
+		t.Errorf("got %v, expected %v", err, ErrUnknownFingerprint)
+	}
+	if _, err := server.Result(); err != ErrUnknownFingerprint {
+		t.Errorf("got result error %v, expected %v", err, ErrUnknownFingerprint)
+	}
+}
+
+func TestServerFramer(t *testing.T) {
+	for _, version := range []ProtocolVersion{MTProto1, MTProto2} {
+		clientAuth := testAuth2()
+		serverAuth := *clientAuth
+		serverAuth.SessionID = [8]byte{}
+
+		client := &Framer{Version: version}
+		client.SetAuth(clientAuth)
+		server := &Framer{Version: version, Server: true}
+		server.SetAuth(&serverAuth)
+
+		raw, msgID, err := client.Format(Msg{fromHex("01020304"), ContentMsg, 0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := server.Parse(raw)
+		if err != nil {
+			t.Fatalf("v%v: server failed to parse: %v", version, err)
+		}
+		if hex.EncodeToString(msg.Payload) != "01020304" || msg.MsgID != msgID || msg.Type != ContentMsg {
+			t.Errorf("v%v: server parsed %+v", version, msg)
+		}
+		if serverAuth.SessionID != clientAuth.SessionID {
+			t.Errorf("v%v: server session ID %x, expected %x", version, serverAuth.SessionID, clientAuth.SessionID)
+		}
+
+		raw, msgID, err = server.Format(Msg{fromHex("05060708"), ContentMsg, 0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgID%2 != 1 {
+			t.Errorf("v%v: server msg ID %x is not odd", version, msgID)
+		}
+		msg, err = client.Parse(raw)
+		if err != nil {
+			t.Fatalf("v%v: client failed to parse: %v", version, err)
+		}
+		if hex.EncodeToString(msg.Payload) != "05060708" || msg.MsgID != msgID {
+			t.Errorf("v%v: client parsed %+v", version, msg)
+		}
+
+		// messages encrypted in one direction must not be accepted in the other
+		raw, _, err = client.Format(Msg{fromHex("01020304"), ContentMsg, 0, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version == MTProto2 {
+			if _, err := client.Parse(raw); err == nil {
+				t.Errorf("v%v: client accepted its own message", version)
+			}
+		}
+	}
+}
@@ -2,16 +2,16 @@ package mtproto
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
-	"crypto/rsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"github.com/andreyvit/telegramapi/binints"
 	"github.com/andreyvit/telegramapi/tl/knownschemas"
 	"io"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/andreyvit/telegramapi/tl"
 )
@@ -26,10 +26,25 @@ type Transport interface {
 }
 
 type SessionOptions struct {
-	PubKey  *rsa.PublicKey
-	AppID   string
-	APIHash string
-	Verbose int
+	PubKeys         KeyRing
+	AppID           string
+	APIHash         string
+	ProtocolVersion ProtocolVersion
+	Verbose         int
+
+	// TempKeyTTL enables perfect forward secrecy: traffic is encrypted with
+	// temporary keys of this lifetime, bound to the permanent key
+	TempKeyTTL time.Duration
+
+	// PingInterval enables keepalive pings, which also measure the RTT
+	PingInterval time.Duration
+	// PingTimeout fails the session with ErrPingTimeout when nothing arrives
+	// for this long after a ping; defaults to PingInterval
+	PingTimeout time.Duration
+
+	// GzipThreshold enables gzip_packed for requests larger than this many
+	// bytes; they are only sent compressed when that makes them smaller
+	GzipThreshold int
 }
 
 type Handler func(msgID uint64, o tl.Object) ([]tl.Object, error)
@@ -40,20 +55,59 @@ var ErrReconnectRequired = errors.New("reconnect required")
 
 var ErrInvalidMsg = errors.New("invalid message")
 
+var ErrPingTimeout = errors.New("ping timed out")
+
+// ErrSessionClosed completes requests that were in flight when the session
+// stopped without an error, e.g. after Shutdown.
+var ErrSessionClosed = errors.New("session closed")
+
+// ErrAnswerLost completes requests that the server had acknowledged when the
+// session stopped. They may or may not have been executed, so they are only
+// safe to send again if repeating them is harmless, e.g. for reads, or if the
+// server deduplicates them by random_id.
+var ErrAnswerLost = errors.New("session stopped after the request was received, answer lost")
+
 type Session struct {
 	options   SessionOptions
 	transport Transport
 	framer    *Framer
-	keyex     *KeyEx
+	keyex     *KeyEx // nil unless a key exchange is in progress
 	handlers  []Handler
 
+	permAuth *AuthResult
+	binding  bool
+	renewc   <-chan time.Time
+
+	saltc         <-chan time.Time
+	fetchingSalts bool
+
+	pingID       uint64
+	pingSentAt   time.Time
+	pongDeadline <-chan time.Time
+	rtt          time.Duration
+
 	connKeyExDone bool
 	connInitSent  bool
 	inFlight      map[uint64]*rpcInFlight
 
-	failc  chan error
-	sendc  chan outgoingMsg
-	closec chan struct{}
+	queue       []queuedMsg
+	pendingAcks []uint64
+	flushc      <-chan time.Time
+	containers  map[uint64][]uint64 // container msg_id -> requests inside
+
+	sent       map[uint64]*queuedMsg // our content messages not acknowledged yet
+	received   map[uint64]byte       // recent incoming msg_ids -> msgs_state_info status
+	nextForget int
+
+	lastContentMsgID uint64
+	lastContentSeqNo int
+	diag             Diagnostics
+
+	failc   chan error
+	sendc   chan outgoingMsg
+	cancelc chan chan<- reply
+	closec  chan struct{}
+	donec   chan struct{}
 	// eventc chan uint32
 	closing bool
 
@@ -64,6 +118,7 @@ type Session struct {
 	dc int
 
 	onstatechanged func()
+	onupdates      func(o TLUpdatesType)
 
 	err error
 }
@@ -71,6 +126,7 @@ type Session struct {
 type outgoingMsg struct {
 	Obj   tl.Object
 	Reply chan<- reply
+	Ctx   context.Context
 }
 
 type reply struct {
@@ -79,24 +135,34 @@ type reply struct {
 }
 
 type rpcInFlight struct {
-	MsgID uint64
-	Reply chan<- reply
+	MsgID       uint64
+	ContainerID uint64
+	Obj         tl.Object // kept for resending
+	Reply       chan<- reply
+
+	// Callback replaces Reply for requests made by the session itself
+	Callback func(obj tl.Object, err error)
+
+	// Acked is set once the server confirms receiving the request
+	Acked bool
 }
 
 func NewSession(transport Transport, options SessionOptions) *Session {
 	s := &Session{
 		options:   options,
 		transport: transport,
-		framer:    &Framer{},
-		keyex: &KeyEx{
-			PubKey: options.PubKey,
-		},
-
-		inFlight: make(map[uint64]*rpcInFlight),
-
-		failc:  make(chan error, 1),
-		sendc:  make(chan outgoingMsg, 1),
-		closec: make(chan struct{}),
+		framer:    &Framer{Version: options.ProtocolVersion},
+
+		inFlight:   make(map[uint64]*rpcInFlight),
+		containers: make(map[uint64][]uint64),
+		sent:       make(map[uint64]*queuedMsg),
+		received:   make(map[uint64]byte),
+
+		failc:   make(chan error, 1),
+		sendc:   make(chan outgoingMsg, 1),
+		cancelc: make(chan chan<- reply),
+		closec:  make(chan struct{}),
+		donec:   make(chan struct{}),
 		// eventc: make(chan uint32, 10),
 	}
 	s.stateCond = sync.NewCond(&s.stateMut)
@@ -111,6 +177,14 @@ func (sess *Session) OnStateChanged(f func()) {
 	sess.onstatechanged = f
 }
 
+// OnUpdates registers a function to be called for every update pushed by the
+// server. It is called on the session goroutine and must not block.
+func (sess *Session) OnUpdates(f func(o TLUpdatesType)) {
+	sess.stateMut.Lock()
+	defer sess.stateMut.Unlock()
+	sess.onupdates = f
+}
+
 func (sess *Session) DC() int {
 	sess.stateMut.Lock()
 	defer sess.stateMut.Unlock()
@@ -152,12 +226,45 @@ func (sess *Session) RunJob(f func() error) {
 }
 
 func (sess *Session) Send(o tl.Object) (tl.Object, error) {
-	replyc := make(chan reply)
-	sess.sendc <- outgoingMsg{o, replyc}
-	reply := <-replyc
-	return reply.Obj, reply.Err
+	return sess.SendContext(context.Background(), o)
+}
+
+// SendContext is Send that gives up when ctx is done. The request is then
+// forgotten, and the server is asked to drop the answer via rpc_drop_answer.
+// If the session stops, the request fails with the session error.
+func (sess *Session) SendContext(ctx context.Context, o tl.Object) (tl.Object, error) {
+	replyc := make(chan reply, 1)
+	select {
+	case sess.sendc <- outgoingMsg{o, replyc, ctx}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-sess.donec:
+		return nil, sess.stopErr()
+	}
+
+	select {
+	case r := <-replyc:
+		return r.Obj, r.Err
+	case <-ctx.Done():
+		select {
+		case sess.cancelc <- replyc:
+		case <-sess.donec:
+		}
+		return nil, ctx.Err()
+	case <-sess.donec:
+		// pending requests have been answered by now, but this one may
+		// have never left sendc
+		select {
+		case r := <-replyc:
+			return r.Obj, r.Err
+		default:
+			return nil, sess.stopErr()
+		}
+	}
 }
 
+// Err returns the error the session has failed with. Before Run returns,
+// it must only be called on the session goroutine, e.g. from handlers.
 func (sess *Session) Err() error {
 	return sess.err
 }
@@ -171,16 +278,38 @@ func (sess *Session) Run() {
 
 	go sess.listen(incomingc)
 
+	var pollc <-chan struct{}
+	if pt, ok := sess.transport.(PollingTransport); ok {
+		pollc = pt.PollNeeded()
+	}
+
+	var pingc <-chan time.Time
+	if sess.options.PingInterval > 0 {
+		ticker := time.NewTicker(sess.options.PingInterval)
+		defer ticker.Stop()
+		pingc = ticker.C
+	}
+
 	if sess.options.Verbose >= 3 {
 		log.Printf("mtproto.Session running...")
 	}
 
-	if !sess.connKeyExDone {
-		sess.startKeyEx()
+	if sess.permAuth == nil {
+		sess.startKeyEx(0)
+	} else if sess.options.TempKeyTTL != 0 {
+		sess.startTempKeyEx()
+	} else {
+		sess.updateSalt()
 	}
 
 loop:
 	for sess.err == nil {
+		sendc := sess.sendc
+		if sess.binding || !sess.connKeyExDone {
+			// requests would fail until there is a (bound) key
+			sendc = nil
+		}
+
 		select {
 		case raw, ok := <-incomingc:
 			if ok {
@@ -191,8 +320,28 @@ loop:
 				}
 				break loop
 			}
-		case msg := <-sess.sendc:
-			sess.sendInternal(msg.Obj, msg.Reply)
+		case msg := <-sendc:
+			// requests cancelled while still in sendc cannot be found by cancel
+			if msg.Ctx.Err() == nil {
+				sess.sendInternal(msg.Obj, msg.Reply)
+			}
+		case replyc := <-sess.cancelc:
+			sess.cancel(replyc)
+		case <-pollc:
+			sess.poll()
+		case <-sess.renewc:
+			sess.renewc = nil
+			sess.startTempKeyEx()
+		case <-sess.flushc:
+			sess.flush()
+		case <-sess.saltc:
+			sess.saltc = nil
+			sess.updateSalt()
+		case <-pingc:
+			sess.ping()
+		case <-sess.pongDeadline:
+			sess.pongDeadline = nil
+			sess.failInternal(ErrPingTimeout)
 		case err := <-sess.failc:
 			sess.failInternal(err)
 			// case pseudocmd := <-sess.eventc:
@@ -208,6 +357,12 @@ loop:
 	if sess.options.Verbose >= 2 {
 		log.Printf("mtproto.Session quitting, err: %v", sess.err)
 	}
+
+	if !sess.closing {
+		sess.transport.Close()
+	}
+	sess.failPendingRPCs()
+	close(sess.donec)
 }
 
 func (sess *Session) listen(incomingc chan<- []byte) {
@@ -221,6 +376,10 @@ func (sess *Session) listen(incomingc chan<- []byte) {
 				log.Printf("mtproto.Session Recv'd EOF")
 			}
 			break
+		} else if ierr, ok := err.(*IntegrityError); ok {
+			log.Printf("** mtproto.Session received a corrupted packet, dropping connection: %v", ierr)
+			sess.failc <- ierr
+			break
 		} else if err != nil {
 			if sess.options.Verbose >= 1 {
 				log.Printf("mtproto.Session Recv failed: %v", err)
@@ -247,7 +406,15 @@ func (sess *Session) Fail(err error) {
 	if err == nil {
 		panic("Fail(nil)")
 	}
-	sess.failc <- err
+	select {
+	case sess.failc <- err:
+	case <-sess.donec:
+	}
+}
+
+// Done returns a channel that is closed once Run returns.
+func (sess *Session) Done() <-chan struct{} {
+	return sess.donec
 }
 
 func (sess *Session) failInternal(err error) {
@@ -268,7 +435,8 @@ func (sess *Session) sendInternal(o tl.Object, replyc chan<- reply) {
 		return
 	}
 
-	if sess.connKeyExDone && !sess.connInitSent {
+	// only requests can be wrapped, not acks and other service messages
+	if sess.connKeyExDone && !sess.connInitSent && IsContentMsg(o) {
 		o = &TLInvokeWithLayer{
 			Layer: knownschemas.TelegramLayer,
 			Query: &TLInitConnection{
@@ -283,7 +451,21 @@ func (sess *Session) sendInternal(o tl.Object, replyc chan<- reply) {
 		sess.connInitSent = true
 	}
 
-	msg := MsgFromObj(o)
+	var infl *rpcInFlight
+	if replyc != nil {
+		infl = &rpcInFlight{Reply: replyc}
+	}
+	sess.enqueue(o, infl)
+}
+
+// sendMsgInternal sends o as is, either encrypted or plain, and registers
+// infl (if any) to receive the reply.
+func (sess *Session) sendMsgInternal(o tl.Object, plain bool, infl *rpcInFlight) {
+	sess.sendMsg(o, MsgFromObj(o), plain, infl)
+}
+
+// sendMsg is sendMsgInternal for an already serialized o.
+func (sess *Session) sendMsg(o tl.Object, msg Msg, plain bool, infl *rpcInFlight) {
 
 	// if sess.options.Verbose >= 2 {
 	// 	log.Printf("mtproto.Session sending %s (%v bytes, %v)", o, len(msg.Payload), msg.Type)
@@ -291,8 +473,15 @@ func (sess *Session) sendInternal(o tl.Object, replyc chan<- reply) {
 	// 	log.Printf("mtproto.Session sending %s (%v bytes, %v)", tl.Name(o), len(msg.Payload), msg.Type)
 	// }
 
+	var raw []byte
+	var msgID uint64
+	var err error
 	sess.stateMut.Lock()
-	raw, msgID, err := sess.framer.Format(msg)
+	if plain {
+		raw, msgID, err = sess.framer.FormatPlain(msg)
+	} else {
+		raw, msgID, err = sess.framer.Format(msg)
+	}
 	sess.stateMut.Unlock()
 	if err != nil {
 		sess.failInternal(err)
@@ -305,8 +494,13 @@ func (sess *Session) sendInternal(o tl.Object, replyc chan<- reply) {
 		log.Printf("mtproto.Session sending %s (%v bytes, %v, msgID %08x)", tl.Name(o), len(msg.Payload), msg.Type, msgID)
 	}
 
-	if replyc != nil {
-		sess.startPendingRPC(msgID, replyc)
+	if infl != nil {
+		infl.MsgID = msgID
+		infl.Obj = o
+		sess.startPendingRPC(infl)
+	}
+	if !plain {
+		sess.trackSent(msgID, queuedMsg{o, msg, infl})
 	}
 
 	err = sess.transport.Send(raw)
@@ -316,13 +510,12 @@ func (sess *Session) sendInternal(o tl.Object, replyc chan<- reply) {
 	}
 }
 
-func (sess *Session) startPendingRPC(msgID uint64, replyc chan<- reply) {
-	if sess.inFlight[msgID] != nil {
+func (sess *Session) startPendingRPC(infl *rpcInFlight) {
+	if sess.inFlight[infl.MsgID] != nil {
 		panic("duplicate msgID")
 	}
 
-	infl := &rpcInFlight{msgID, replyc}
-	sess.inFlight[msgID] = infl
+	sess.inFlight[infl.MsgID] = infl
 }
 
 func (sess *Session) finishPendingRPC(msgID uint64, obj tl.Object, err error) {
@@ -332,14 +525,141 @@ func (sess *Session) finishPendingRPC(msgID uint64, obj tl.Object, err error) {
 		return
 	}
 	delete(sess.inFlight, msgID)
+	delete(sess.sent, msgID)
+	if infl.ContainerID != 0 {
+		sess.releaseContainer(infl.ContainerID)
+	}
+
+	infl.complete(obj, err)
+}
+
+func (infl *rpcInFlight) complete(obj tl.Object, err error) {
+	if infl.Callback != nil {
+		infl.Callback(obj, err)
+	} else {
+		infl.Reply <- reply{obj, err}
+	}
+}
+
+// cancel forgets the request that replies to replyc, asking the server to
+// drop the answer if the request has been sent already.
+func (sess *Session) cancel(replyc chan<- reply) {
+	for i, q := range sess.queue {
+		if q.Infl != nil && q.Infl.Reply == replyc {
+			sess.queue = append(sess.queue[:i:i], sess.queue[i+1:]...)
+			return
+		}
+	}
+
+	for msgID, infl := range sess.inFlight {
+		if infl.Reply != replyc {
+			continue
+		}
+		delete(sess.inFlight, msgID)
+		delete(sess.sent, msgID)
+		if infl.ContainerID != 0 {
+			sess.releaseContainer(infl.ContainerID)
+		}
+
+		if sess.options.Verbose >= 1 {
+			log.Printf("mtproto.Session cancelled %s (msgID %08x)", tl.Name(infl.Obj), msgID)
+		}
+		sess.enqueue(&TLRPCDropAnswer{ReqMsgID: msgID}, &rpcInFlight{
+			Callback: func(obj tl.Object, err error) {
+				if sess.options.Verbose >= 2 {
+					log.Printf("mtproto.Session dropped answer to %08x: %v %v", msgID, obj, err)
+				}
+			},
+		})
+		return
+	}
+}
+
+// failPendingRPCs completes the outstanding requests once the session stops.
+// Requests the server has acknowledged fail with ErrAnswerLost, the rest
+// with the session error. Requests made by the session itself are simply
+// forgotten.
+func (sess *Session) failPendingRPCs() {
+	err := sess.stopErr()
+	for _, q := range sess.queue {
+		if q.Infl != nil && q.Infl.Reply != nil {
+			q.Infl.Reply <- reply{nil, err}
+		}
+	}
+	sess.queue = nil
+	for _, infl := range sess.inFlight {
+		if infl.Reply == nil {
+			continue
+		}
+		if infl.Acked {
+			infl.Reply <- reply{nil, ErrAnswerLost}
+		} else {
+			infl.Reply <- reply{nil, err}
+		}
+	}
+	sess.inFlight = make(map[uint64]*rpcInFlight)
+}
+
+func (sess *Session) stopErr() error {
+	if sess.err != nil {
+		return sess.err
+	}
+	return ErrSessionClosed
+}
+
+// resend sends an in-flight request again under a new msg_id and seqno.
+// Requests made by the session itself are not resent.
+func (sess *Session) resend(msgID uint64) bool {
+	infl := sess.inFlight[msgID]
+	if infl == nil || infl.Callback != nil {
+		return false
+	}
+	delete(sess.inFlight, msgID)
+	delete(sess.sent, msgID)
+	if infl.ContainerID != 0 {
+		sess.releaseContainer(infl.ContainerID)
+		infl.ContainerID = 0
+	}
+
+	if sess.options.Verbose >= 1 {
+		log.Printf("mtproto.Session resending %s (was msgID %08x)", tl.Name(infl.Obj), msgID)
+	}
+	sess.enqueue(infl.Obj, infl)
+	return true
+}
+
+// syncTime updates the server time offset from the msg_id of a server
+// message, whose upper 32 bits are the server Unix time.
+func (sess *Session) syncTime(msgID uint64) {
+	offset := int(int64(msgID>>32) - time.Now().Unix())
+
+	sess.stateMut.Lock()
+	auth, _ := sess.framer.State()
+	changed := (auth != nil && auth.TimeOffset != offset)
+	if changed {
+		auth.TimeOffset = offset
+		if sess.permAuth != nil {
+			sess.permAuth.TimeOffset = offset
+		}
+	}
+	sess.stateMut.Unlock()
 
-	infl.Reply <- reply{obj, err}
+	if changed && sess.options.Verbose >= 2 {
+		log.Printf("mtproto.Session server time offset is now %d sec", offset)
+	}
 }
 
-func (sess *Session) ack(msgID uint64) {
-	// TODO: batch these messages
-	sess.sendInternal(&TLMsgsAck{
-		MsgIDs: []uint64{msgID},
+// poll gives the server a long-polling request to push messages through.
+func (sess *Session) poll() {
+	// http_wait must be encrypted, and must not be wrapped into initConnection
+	if !sess.connKeyExDone || !sess.connInitSent {
+		return
+	}
+	maxWait := sess.transport.(PollingTransport).MaxWait()
+	sess.sendInternal(&TLHttpWait{
+		MaxDelay:  0,
+		WaitAfter: 0,
+		MaxWait:   int(maxWait / time.Millisecond),
 	}, nil)
 }
 
@@ -354,7 +674,10 @@ func (sess *Session) doHandle(raw []byte) error {
 	sess.stateMut.Lock()
 	msg, err := sess.framer.Parse(raw)
 	sess.stateMut.Unlock()
-	if err != nil {
+	if err == ErrSessionIDMismatch {
+		sess.rejectMsg(err)
+		return nil
+	} else if err != nil {
 		if sess.options.Verbose >= 2 {
 			log.Printf("mtproto.Session failed to parse incoming data (%v bytes): %v - error: %v", len(raw), hex.EncodeToString(raw), err)
 		} else if sess.options.Verbose >= 1 {
@@ -373,6 +696,22 @@ func (sess *Session) doHandle(raw []byte) error {
 		return err
 	}
 
+	if msg.Type != KeyExMsg {
+		// a clock correction must be applied before the msg_id window check,
+		// which it would fail otherwise
+		if id := sess.clockCorrection(msg.MsgID, o); id != 0 {
+			sess.syncTime(id)
+		}
+		if err := sess.checkIncoming(msg.MsgID, msg.SeqNo, msg.Type); err != nil {
+			sess.rejectMsg(err)
+			return nil
+		}
+		sess.markReceived(msg.MsgID, msg.Type)
+	}
+
+	// the connection is alive
+	sess.pongDeadline = nil
+
 	if sess.options.Verbose >= 2 {
 		log.Printf("mtproto.Session received %v (%v bytes, %v)", o, len(msg.Payload), msg.Type)
 	} else if sess.options.Verbose >= 1 {
@@ -438,13 +777,17 @@ func (sess *Session) processResult(msgs []tl.Object, err error) {
 	}
 }
 
-func (sess *Session) startKeyEx() {
-	omsg := sess.keyex.Start()
-	sess.processResult([]tl.Object{omsg}, nil)
+// startKeyEx begins a key exchange, which produces a temporary key if expiresIn is non-zero.
+func (sess *Session) startKeyEx(expiresIn int) {
+	sess.keyex = &KeyEx{
+		PubKeys:   sess.options.PubKeys,
+		ExpiresIn: expiresIn,
+	}
+	sess.sendMsgInternal(sess.keyex.Start(), true, nil)
 }
 
 func (sess *Session) handleKeyEx(msgID uint64, o tl.Object) ([]tl.Object, error) {
-	if sess.connKeyExDone {
+	if sess.keyex == nil || !isKeyExReply(o) {
 		return nil, ErrCmdNotHandled
 	}
 
@@ -453,14 +796,40 @@ func (sess *Session) handleKeyEx(msgID uint64, o tl.Object) ([]tl.Object, error)
 		return nil, err
 	}
 	if omsg != nil {
-		return []tl.Object{omsg}, nil
+		sess.sendMsgInternal(omsg, true, nil)
+		return []tl.Object{}, nil
+	}
+
+	auth, err := sess.keyex.Result()
+	if err != nil {
+		return nil, err
+	}
+	sess.keyex = nil
+
+	if auth.ExpiresAt != 0 {
+		sess.bindTempKey(auth)
+		return []tl.Object{}, nil
+	}
+
+	sess.stateMut.Lock()
+	sess.permAuth = auth
+	sess.stateMut.Unlock()
+
+	if sess.options.TempKeyTTL != 0 {
+		sess.startTempKeyEx()
 	} else {
-		auth, err := sess.keyex.Result()
-		if err != nil {
-			return nil, err
-		}
 		sess.applyAuth(auth)
-		return []tl.Object{}, nil
+		sess.updateSalt()
+	}
+	return []tl.Object{}, nil
+}
+
+func isKeyExReply(o tl.Object) bool {
+	switch o.(type) {
+	case *TLResPQ, *TLServerDHParamsOK, *TLServerDHParamsFail, *TLDHGenOK, *TLDHGenFail, *TLDHGenRetry:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -473,82 +842,122 @@ func (sess *Session) handleRPCResult(msgID uint64, o tl.Object) ([]tl.Object, er
 	case *TLMsgContainer:
 		var replies []tl.Object
 		for _, msg := range o.Messages {
-			// TODO: verify msg.MsgId
-			// TODO: verify msg.Seqno
-			r, err := sess.invokeHandlersInternalReturnCmds(msg.MsgID, msg.Body)
-			if err == ErrCmdNotHandled {
-				sess.logDroppedIncomingMsg(msg.Body)
-			} else if err != nil {
+			r, err := sess.handleInnerMsg(msg)
+			if err != nil {
 				return nil, err
 			}
 			replies = append(replies, r...)
 		}
 		return replies, nil
+	case *TLMsgCopy:
+		if _, ok := sess.received[o.OrigMessage.MsgID]; ok {
+			// already got the original
+			return nil, nil
+		}
+		return sess.handleInnerMsg(o.OrigMessage)
 	case *TLNewSessionCreated:
 		if sess.options.Verbose >= 2 {
 			log.Printf("NOTICE: %v", o)
 		}
+		sess.resetSeqNo()
 		sess.ack(msgID)
 		return nil, nil
 	case *TLMsgsAck:
-		for _, msgID := range o.MsgIDs {
-			// TODO: ack
-			if sess.options.Verbose >= 2 {
-				log.Printf("TODO: ack'ed %08x", msgID)
-			}
+		for _, id := range o.MsgIDs {
+			sess.markAcked(id)
+		}
+		return nil, nil
+	case *TLMsgsStateReq:
+		return sess.handleMsgsStateReq(msgID, o.MsgIDs), nil
+	case *TLMsgResendReq:
+		return sess.handleMsgResendReq(msgID, o.MsgIDs), nil
+	case *TLMsgsAllInfo:
+		sess.handleMsgsAllInfo(o)
+		return nil, nil
+	case *TLMsgsStateInfo:
+		// the server could not resend an answer we asked for
+		if sess.options.Verbose >= 1 {
+			log.Printf("mtproto.Session got msgs_state_info for %08x: %v", o.ReqMsgID, o.Info)
 		}
 		return nil, nil
 	case *TLBadServerSalt:
-		sess.stateMut.Lock()
-		auth, _ := sess.framer.State()
-		sess.stateMut.Unlock()
-		binints.EncodeUint64LE(o.NewServerSalt, auth.ServerSalt[:])
-		sess.applyAuth(auth)
-		return nil, ErrReconnectRequired
-	case *TLBadMsgNotification:
-		log.Printf("WARNING: bad msg %08x: err code %d, seq no %d", o.BadMsgID, o.ErrorCode, o.BadMsgSeqno)
-		sess.finishPendingRPC(o.BadMsgID, nil, ErrInvalidMsg)
+		sess.handleBadServerSalt(o)
 		return nil, nil
-	case *TLUpdates:
-		sess.ack(msgID)
+	case *TLFutureSalts:
+		sess.finishPendingRPC(o.ReqMsgID, o, nil)
+		return nil, nil
+	case *TLPong:
+		sess.handlePong(o)
 		return nil, nil
-	case *TLUpdateShort:
+	case *TLBadMsgNotification:
+		retry := false
+		switch o.ErrorCode {
+		case 16, 17:
+			// msg_id too low or too high, i.e. our clock is off; doHandle
+			// has synced it already
+			retry = true
+		default:
+			log.Printf("WARNING: bad msg %08x: err code %d, seq no %d", o.BadMsgID, o.ErrorCode, o.BadMsgSeqno)
+		}
+		for _, id := range sess.expandContainer(o.BadMsgID) {
+			if !retry || !sess.resend(id) {
+				if sess.inFlight[id] != nil {
+					sess.finishPendingRPC(id, nil, ErrInvalidMsg)
+				}
+			}
+		}
+		return nil, nil
+	case TLUpdatesType:
 		sess.ack(msgID)
+		sess.stateMut.Lock()
+		f := sess.onupdates
+		sess.stateMut.Unlock()
+		if f != nil {
+			f(o)
+		}
 		return nil, nil
 	case *TLMsgDetailedInfo:
-		sess.ack(o.AnswerMsgID)
-		return nil, nil
+		// the request has been received
+		sess.markAcked(o.MsgID)
+		return sess.handleDetailedInfo(o.AnswerMsgID), nil
 	case *TLMsgNewDetailedInfo:
-		sess.ack(o.AnswerMsgID)
-		return nil, nil
+		return sess.handleDetailedInfo(o.AnswerMsgID), nil
 	default:
 		return nil, ErrCmdNotHandled
 	}
 }
 
+// AuthState returns the state to persist. With TempKeyTTL set, this is the
+// permanent key; temporary keys are never persisted.
 func (sess *Session) AuthState() (*AuthResult, FramerState) {
 	sess.stateMut.Lock()
 	defer sess.stateMut.Unlock()
 
-	return sess.framer.State()
+	auth, fs := sess.framer.State()
+	if sess.options.TempKeyTTL != 0 && sess.permAuth != nil {
+		auth = sess.permAuth
+	}
+	if auth != nil {
+		// the session keeps updating its own copy
+		copied := *auth
+		auth = &copied
+	}
+	return auth, fs
 }
 
 func (sess *Session) RestoreAuthState(auth *AuthResult, fs FramerState) {
 	sess.stateMut.Lock()
 	sess.framer.Restore(fs)
+	sess.permAuth = auth
 	sess.stateMut.Unlock()
 
-	sess.applyAuth(auth)
+	if sess.options.TempKeyTTL == 0 {
+		sess.applyAuth(auth)
+	}
 }
 
 func (sess *Session) applyAuth(auth *AuthResult) {
-	var zero [8]byte
-	if bytes.Equal(zero[:], auth.SessionID[:]) {
-		_, err := io.ReadFull(rand.Reader, auth.SessionID[:])
-		if err != nil {
-			panic(err)
-		}
-	}
+	initSessionID(auth)
 
 	sess.stateMut.Lock()
 
@@ -557,6 +966,13 @@ func (sess *Session) applyAuth(auth *AuthResult) {
 	sess.connKeyExDone = true
 	sess.isReady = true
 	sess.stateCond.Broadcast()
+	sess.stateMut.Unlock()
+
+	sess.notifyStateChanged()
+}
+
+func (sess *Session) notifyStateChanged() {
+	sess.stateMut.Lock()
 	f := sess.onstatechanged
 	sess.stateMut.Unlock()
 
@@ -565,8 +981,21 @@ func (sess *Session) applyAuth(auth *AuthResult) {
 	}
 }
 
+func initSessionID(auth *AuthResult) {
+	var zero [8]byte
+	if bytes.Equal(zero[:], auth.SessionID[:]) {
+		_, err := io.ReadFull(rand.Reader, auth.SessionID[:])
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
 func (sess *Session) Shutdown() {
-	sess.closec <- struct{}{}
+	select {
+	case sess.closec <- struct{}{}:
+	case <-sess.donec:
+	}
 }
 
 func (sess *Session) Wait() {
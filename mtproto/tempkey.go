@@ -0,0 +1,147 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/andreyvit/telegramapi/binints"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+var ErrBindMsgIDMismatch = errors.New("bind_auth_key_inner msg_id does not match the request")
+
+// EncryptBindAuthKeyInner encrypts the message of auth.bindTempAuthKey with the
+// permanent key. This uses MTProto 1.0 with a random salt and session ID, and
+// msgID must be the msg_id of the auth.bindTempAuthKey request itself.
+func EncryptBindAuthKeyInner(inner *TLBindAuthKeyInner, perm *AuthResult, msgID uint64, random io.Reader) ([]byte, error) {
+	auth := &AuthResult{
+		Key:   perm.Key,
+		KeyID: perm.KeyID,
+	}
+	_, err := io.ReadFull(random, auth.ServerSalt[:])
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.ReadFull(random, auth.SessionID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &Framer{
+		Version:       MTProto1,
+		RandomReader:  random,
+		MsgIDOverride: msgID,
+	}
+	fr.SetAuth(auth)
+
+	// service messages get seq_no 0 on a fresh framer
+	raw, _, err := fr.Format(Msg{tl.Bytes(inner), ServiceMsg, 0, 0})
+	return raw, err
+}
+
+// DecryptBindAuthKeyInner reverses EncryptBindAuthKeyInner on the server side.
+func DecryptBindAuthKeyInner(encrypted []byte, perm *AuthResult, msgID uint64) (*TLBindAuthKeyInner, error) {
+	auth := *perm
+	fr := &Framer{
+		Version: MTProto1,
+		Server:  true,
+	}
+	fr.SetAuth(&auth)
+
+	msg, err := fr.Parse(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if msg.MsgID != msgID {
+		return nil, ErrBindMsgIDMismatch
+	}
+
+	o, err := Schema.ReadLimitedBoxedObject(msg.Payload, TagBindAuthKeyInner)
+	if err != nil {
+		return nil, err
+	}
+	return o.(*TLBindAuthKeyInner), nil
+}
+
+func (sess *Session) startTempKeyEx() {
+	ttl := (sess.options.TempKeyTTL + time.Second - 1) / time.Second
+	if sess.options.Verbose >= 2 {
+		log.Printf("mtproto.Session generating a temp key for %v", sess.options.TempKeyTTL)
+	}
+	sess.startKeyEx(int(ttl))
+}
+
+// bindTempKey switches to the new temp key and binds it to the permanent key.
+// Requests are held back until the server confirms the binding.
+func (sess *Session) bindTempKey(temp *AuthResult) {
+	initSessionID(temp)
+
+	var nonce [8]byte
+	_, err := io.ReadFull(rand.Reader, nonce[:])
+	if err != nil {
+		panic(err)
+	}
+
+	sess.stateMut.Lock()
+	perm := sess.permAuth
+	sess.framer.SetAuth(temp)
+	sess.framer.SeqNo = 0
+	msgID := sess.framer.ReserveMsgID()
+	sess.stateMut.Unlock()
+
+	inner := &TLBindAuthKeyInner{
+		Nonce:         binints.DecodeUint64LE(nonce[:]),
+		TempAuthKeyID: temp.KeyID,
+		PermAuthKeyID: perm.KeyID,
+		TempSessionID: binints.DecodeUint64LE(temp.SessionID[:]),
+		ExpiresAt:     temp.ExpiresAt,
+	}
+	encrypted, err := EncryptBindAuthKeyInner(inner, perm, msgID, rand.Reader)
+	if err != nil {
+		sess.failInternal(err)
+		return
+	}
+
+	sess.binding = true
+	sess.sendMsgInternal(&TLAuthBindTempAuthKey{
+		PermAuthKeyID:    perm.KeyID,
+		Nonce:            inner.Nonce,
+		ExpiresAt:        inner.ExpiresAt,
+		EncryptedMessage: encrypted,
+	}, false, &rpcInFlight{
+		Callback: func(obj tl.Object, err error) {
+			sess.handleBindResult(temp, obj, err)
+		},
+	})
+}
+
+func (sess *Session) handleBindResult(temp *AuthResult, obj tl.Object, err error) {
+	sess.binding = false
+	if err == nil {
+		if b, ok := obj.(*TLBool); !ok || !b.Value {
+			err = fmt.Errorf("auth.bindTempAuthKey failed: %v", obj)
+		}
+	}
+	if err != nil {
+		sess.failInternal(err)
+		return
+	}
+
+	if sess.options.Verbose >= 2 {
+		log.Printf("mtproto.Session bound temp key %08x to %08x", temp.KeyID, sess.permAuth.KeyID)
+	}
+
+	// renew with a tenth of the lifetime to spare; ExpiresAt is in server
+	// time, so our clock being off does not matter
+	left := time.Duration(temp.ExpiresAt-sess.serverNow()) * time.Second
+	sess.renewc = time.After(left - sess.options.TempKeyTTL/10)
+
+	if !sess.connKeyExDone {
+		sess.applyAuth(temp)
+	}
+	sess.updateSalt()
+}
@@ -0,0 +1,71 @@
+package mtproto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestServerKeyExTemp(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &KeyEx{PubKeys: KeyRing{&privKey.PublicKey}, ExpiresIn: 3600}
+	server := &ServerKeyEx{PrivKey: privKey}
+	runTestKeyEx(t, client, server)
+
+	clientAuth, err := client.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverAuth, err := server.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientAuth.KeyID != serverAuth.KeyID {
+		t.Errorf("key ID mismatch: client %x, server %x", clientAuth.KeyID, serverAuth.KeyID)
+	}
+	if clientAuth.ExpiresAt == 0 || serverAuth.ExpiresAt == 0 {
+		t.Errorf("temp key has no expiration: client %v, server %v", clientAuth.ExpiresAt, serverAuth.ExpiresAt)
+	}
+}
+
+func TestBindAuthKeyInner(t *testing.T) {
+	key := make([]byte, 256)
+	_, err := rand.Read(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perm := &AuthResult{
+		Key:   key,
+		KeyID: 0x1122334455667788,
+	}
+	inner := &TLBindAuthKeyInner{
+		Nonce:         42,
+		TempAuthKeyID: 0x0102030405060708,
+		PermAuthKeyID: perm.KeyID,
+		TempSessionID: 7,
+		ExpiresAt:     1500000000,
+	}
+	const msgID = 0x5a0b1c2d00000004
+
+	encrypted, err := EncryptBindAuthKeyInner(inner, perm, msgID, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptBindAuthKeyInner(encrypted, perm, msgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decrypted != *inner {
+		t.Errorf("got %v, expected %v", decrypted, inner)
+	}
+
+	_, err = DecryptBindAuthKeyInner(encrypted, perm, msgID+4)
+	if err != ErrBindMsgIDMismatch {
+		t.Errorf("got %v, expected %v", err, ErrBindMsgIDMismatch)
+	}
+}
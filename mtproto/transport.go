@@ -2,6 +2,7 @@ package mtproto
 
 import (
 	"bytes"
+	"crypto/rand"
 	"errors"
 	"io"
 	"log"
@@ -19,6 +20,8 @@ type TCPReader interface {
 	SetReadDeadline(t time.Time) error
 }
 
+var ErrReadTimeout = errors.New("read timed out")
+
 func ReadAbridgedTCPMessageLen(r io.Reader) (int, error) {
 	var sizebuf [3]byte
 	_, err := io.ReadFull(r, sizebuf[0:1])
@@ -41,15 +44,20 @@ func ReadAbridgedTCPMessageLen(r io.Reader) (int, error) {
 }
 
 func ReadAbridgedTCPMessage(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
-	if firstByteTimeout > 0 {
-		r.SetReadDeadline(time.Now().Add(firstByteTimeout))
-	}
+	return readTCPMessage(r, ReadAbridgedTCPMessageLen, maxMsgLen, firstByteTimeout, msgTimeout)
+}
+
+func readTCPMessage(r TCPReader, readLen func(r io.Reader) (int, error), maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
+	setReadTimeout(r, firstByteTimeout)
 
-	msglen, err := ReadAbridgedTCPMessageLen(r)
-	if _, ok := err.(net.Error); ok {
+	msglen, err := readLen(r)
+	if isTimeout(err) {
+		return nil, ErrReadTimeout
+	} else if _, ok := err.(net.Error); ok {
+		// the connection has been closed
 		return nil, nil
 	} else if err != nil {
-		log.Printf("mtproto.TCPTransport: failed to read TCP message length: %v", msglen, err)
+		log.Printf("mtproto.TCPTransport: failed to read TCP message length: %v", err)
 		return nil, err
 	}
 
@@ -58,11 +66,12 @@ func ReadAbridgedTCPMessage(r TCPReader, maxMsgLen int, firstByteTimeout time.Du
 	}
 
 	data := make([]byte, msglen)
-	if msgTimeout > 0 {
-		r.SetReadDeadline(time.Now().Add(msgTimeout))
-	}
+	setReadTimeout(r, msgTimeout)
 	_, err = io.ReadFull(r, data)
-	if err != nil {
+	if isTimeout(err) {
+		log.Printf("mtproto.TCPTransport: timed out reading TCP message (%d)", msglen)
+		return nil, ErrReadTimeout
+	} else if err != nil {
 		log.Printf("mtproto.TCPTransport: failed to read TCP message (%d): %v", msglen, err)
 		return nil, err
 	}
@@ -71,6 +80,21 @@ func ReadAbridgedTCPMessage(r TCPReader, maxMsgLen int, firstByteTimeout time.Du
 	return data, nil
 }
 
+// setReadTimeout sets the read deadline, or clears the one left over from
+// the previous read when timeout is zero.
+func setReadTimeout(r TCPReader, timeout time.Duration) {
+	if timeout > 0 {
+		r.SetReadDeadline(time.Now().Add(timeout))
+	} else {
+		r.SetReadDeadline(time.Time{})
+	}
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
 func formatTCPMessage(data []byte, isFirst bool) []byte {
 	var buf bytes.Buffer
 
@@ -99,31 +123,132 @@ func formatTCPMessage(data []byte, isFirst bool) []byte {
 	return buf.Bytes()
 }
 
+// TCPFraming selects how MTProto packets are delimited on a TCP connection.
+type TCPFraming int
+
+const (
+	AbridgedFraming TCPFraming = iota
+	IntermediateFraming
+	PaddedIntermediateFraming
+	FullFraming
+)
+
+// tcpCodec implements a particular TCP framing.
+type tcpCodec interface {
+	// Tag returns the bytes that announce the framing at the start of the connection.
+	Tag() []byte
+	// ObfuscatedTag returns the 4-byte tag to embed into the obfuscated init header,
+	// or nil if the framing cannot be obfuscated.
+	ObfuscatedTag() []byte
+	Format(data []byte) ([]byte, error)
+	Read(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error)
+}
+
+func newTCPCodec(options TCPTransportOptions) tcpCodec {
+	switch options.Framing {
+	case AbridgedFraming:
+		return abridgedCodec{}
+	case IntermediateFraming:
+		return &intermediateCodec{}
+	case PaddedIntermediateFraming:
+		return &intermediateCodec{padded: true, random: options.RandomReader}
+	case FullFraming:
+		return &fullCodec{}
+	default:
+		panic("invalid TCP framing")
+	}
+}
+
+type abridgedCodec struct{}
+
+func (abridgedCodec) Tag() []byte {
+	return []byte{0xEF}
+}
+
+func (abridgedCodec) ObfuscatedTag() []byte {
+	return []byte{0xEF, 0xEF, 0xEF, 0xEF}
+}
+
+func (abridgedCodec) Format(data []byte) ([]byte, error) {
+	return formatTCPMessage(data, false), nil
+}
+
+func (abridgedCodec) Read(r TCPReader, maxMsgLen int, firstByteTimeout time.Duration, msgTimeout time.Duration) ([]byte, error) {
+	return ReadAbridgedTCPMessage(r, maxMsgLen, firstByteTimeout, msgTimeout)
+}
+
 type TCPTransportOptions struct {
 	MaxMsgLen int
+	Framing   TCPFraming
+
+	// wrap the framing into AES-CTR obfuscation
+	Obfuscated bool
+	// DC ID to embed into the obfuscated header, required by MTProxy
+	DCID int
+	// MTProxy secret to mix into the obfuscation keys
+	ObfuscationSecret []byte
+
+	// source of random padding and obfuscation keys, defaults to crypto/rand
+	RandomReader io.Reader
+
+	// used to establish connections, defaults to a plain net.Dialer
+	Dialer Dialer
+
+	// FirstByteTimeout makes Recv fail with ErrReadTimeout when no message
+	// starts arriving for this long; zero waits forever
+	FirstByteTimeout time.Duration
+	// MsgTimeout limits how long receiving a message may take once it has started
+	MsgTimeout time.Duration
 }
 
 type TCPTransport struct {
 	options TCPTransportOptions
 	Conn    net.Conn
+	codec   tcpCodec
 
 	firstSent bool
 }
 
 func DialTCP(endpoint string, options TCPTransportOptions) (*TCPTransport, error) {
-	c, err := net.Dial("tcp", endpoint)
+	c, err := forwardDialer(options.Dialer).Dial("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	tr, err := NewTCPTransport(c, options)
 	if err != nil {
+		c.Close()
 		return nil, err
 	}
+	return tr, nil
+}
 
+// NewTCPTransport runs MTProto over an already established connection.
+func NewTCPTransport(c net.Conn, options TCPTransportOptions) (*TCPTransport, error) {
 	if options.MaxMsgLen == 0 {
 		options.MaxMsgLen = 1024 * 1024 * 10
 	}
+	if options.RandomReader == nil {
+		options.RandomReader = rand.Reader
+	}
 
-	return &TCPTransport{
+	tr := &TCPTransport{
 		options: options,
 		Conn:    c,
-	}, nil
+		codec:   newTCPCodec(options),
+	}
+
+	if options.Obfuscated {
+		oc, err := ObfuscateConn(c, tr.codec.ObfuscatedTag(), options.DCID, options.ObfuscationSecret, options.RandomReader)
+		if err != nil {
+			return nil, err
+		}
+		tr.Conn = oc
+		// the framing is announced inside the obfuscated header
+		tr.firstSent = true
+	}
+
+	return tr, nil
 }
 
 func (tr *TCPTransport) Close() {
@@ -131,15 +256,21 @@ func (tr *TCPTransport) Close() {
 }
 
 func (tr *TCPTransport) Send(data []byte) error {
-	data = formatTCPMessage(data, !tr.firstSent)
-	tr.firstSent = true
+	data, err := tr.codec.Format(data)
+	if err != nil {
+		return err
+	}
+	if !tr.firstSent {
+		data = append(tr.codec.Tag(), data...)
+		tr.firstSent = true
+	}
 	// log.Printf("mtproto.TCPTransport: sending %d bytes", len(data))
-	_, err := tr.Conn.Write(data)
+	_, err = tr.Conn.Write(data)
 	return err
 }
 
 func (tr *TCPTransport) Recv() ([]byte, int, error) {
-	raw, err := ReadAbridgedTCPMessage(tr.Conn, tr.options.MaxMsgLen, 0, 0)
+	raw, err := tr.codec.Read(tr.Conn, tr.options.MaxMsgLen, tr.options.FirstByteTimeout, tr.options.MsgTimeout)
 	if err != nil {
 		return nil, 0, err
 	}
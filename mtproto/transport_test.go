@@ -0,0 +1,179 @@
+package mtproto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPFramings(t *testing.T) {
+	tests := []struct {
+		framing  TCPFraming
+		random   []byte
+		expected string
+	}{
+		{AbridgedFraming, nil, "ef 02 01020304 05060708"},
+		{IntermediateFraming, nil, "eeeeeeee 08000000 01020304 05060708"},
+		{PaddedIntermediateFraming, []byte{0x13, 0xAA, 0xBB, 0xCC}, "dddddddd 0b000000 01020304 05060708 aabbcc"},
+		{FullFraming, nil, "14000000 00000000 01020304 05060708 e06e4584"},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		tr, err := NewTCPTransport(client, TCPTransportOptions{
+			Framing:      tt.framing,
+			RandomReader: bytes.NewReader(tt.random),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fromHex(tt.expected)
+		go func() {
+			err := tr.Send(fromHex("01020304 05060708"))
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		actual := make([]byte, len(expected))
+		_, err = io.ReadFull(server, actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(actual), hex.EncodeToString(expected); a != e {
+			t.Errorf("framing %v sent %v, expected %v", tt.framing, a, e)
+		}
+
+		tr.Close()
+		server.Close()
+	}
+}
+
+func TestPaddedIntermediateRecv(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		errcode  int
+	}{
+		// unencrypted message with 3 bytes of padding
+		{"1b000000 0000000000000000 0102030405060708 04000000 aabbccdd 112233", "0000000000000000 0102030405060708 04000000 aabbccdd", 0},
+		// encrypted message with 5 bytes of padding
+		{"2d000000 0102030405060708 00000000000000000000000000000000 00000000000000000000000000000000 1122334455", "0102030405060708 00000000000000000000000000000000 00000000000000000000000000000000", 0},
+		// error code with 2 bytes of padding
+		{"06000000 93feffff 1122", "", -365},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		tr, err := NewTCPTransport(client, TCPTransportOptions{Framing: PaddedIntermediateFraming})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go server.Write(fromHex(tt.input))
+		raw, errcode, err := tr.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, e := hex.EncodeToString(raw), hex.EncodeToString(fromHex(tt.expected)); a != e {
+			t.Errorf("received %v, expected %v", a, e)
+		}
+		if errcode != tt.errcode {
+			t.Errorf("received error code %v, expected %v", errcode, tt.errcode)
+		}
+
+		tr.Close()
+		server.Close()
+	}
+}
+
+func TestFullFramingRecv(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		field    string
+	}{
+		{"14000000 00000000 01020304 05060708 e06e4584  14000000 01000000 01020304 05060708 8f22e01f", "01020304 05060708", ""},
+		{"14000000 00000000 01020304 05060708 e06e4584  14000000 01000000 01020304 050607ff 8f22e01f", "01020304 05060708", "crc32"},
+		{"14000000 00000000 01020304 05060708 e06e4584  14000000 00000000 01020304 05060708 e06e4584", "01020304 05060708", "seqno"},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		tr, err := NewTCPTransport(client, TCPTransportOptions{Framing: FullFraming})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go server.Write(fromHex(tt.input))
+		for i := 0; i < 2; i++ {
+			raw, _, err := tr.Recv()
+			if ierr, ok := err.(*IntegrityError); ok && i == 1 {
+				if ierr.Field != tt.field {
+					t.Errorf("got integrity error in %v, expected %q", ierr.Field, tt.field)
+				}
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			} else if i == 1 && tt.field != "" {
+				t.Errorf("no integrity error, expected one in %v", tt.field)
+			}
+			if a, e := hex.EncodeToString(raw), hex.EncodeToString(fromHex(tt.expected)); a != e {
+				t.Errorf("received %v, expected %v", a, e)
+			}
+		}
+
+		tr.Close()
+		server.Close()
+	}
+}
+
+func TestTCPRecvTimeouts(t *testing.T) {
+	client, server := net.Pipe()
+	tr, err := NewTCPTransport(client, TCPTransportOptions{
+		FirstByteTimeout: 50 * time.Millisecond,
+		MsgTimeout:       50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing arrives
+	_, _, err = tr.Recv()
+	if err != ErrReadTimeout {
+		t.Errorf("got %v, expected ErrReadTimeout", err)
+	}
+
+	// the message stalls after the length byte
+	go server.Write(fromHex("02 01020304"))
+	_, _, err = tr.Recv()
+	if err != ErrReadTimeout {
+		t.Errorf("got %v for a stalled message, expected ErrReadTimeout", err)
+	}
+
+	// the deadline is pushed back for every message
+	client2, server2 := net.Pipe()
+	tr, err = NewTCPTransport(client2, TCPTransportOptions{FirstByteTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(100 * time.Millisecond)
+			server2.Write(fromHex("02 01020304 05060708"))
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		raw, _, err := tr.Recv()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if a, e := hex.EncodeToString(raw), "0102030405060708"; a != e {
+			t.Errorf("received %v, expected %v", a, e)
+		}
+	}
+
+	tr.Close()
+	server.Close()
+	server2.Close()
+}
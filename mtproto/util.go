@@ -5,3 +5,10 @@ import (
 )
 
 var ErrUnexpectedCommand = errors.New("unexpected command")
+
+func paddingTo(n, bs int) int {
+	if r := n % bs; r != 0 {
+		return bs - r
+	}
+	return 0
+}
@@ -0,0 +1,322 @@
+package mtproto
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// max size of control frame payloads per RFC 6455
+	wsMaxControlLen = 125
+)
+
+var ErrWebSocketHandshake = errors.New("WebSocket handshake failed")
+
+var ErrInvalidWebSocketFrame = errors.New("invalid WebSocket frame")
+
+type WebSocketOptions struct {
+	// Origin header to send, e.g. https://web.telegram.org
+	Origin string
+
+	// used for wss:// URLs, ServerName defaults to the URL host
+	TLSConfig *tls.Config
+
+	// Framing must be IntermediateFraming (the default) or PaddedIntermediateFraming;
+	// the connection is always obfuscated
+	Transport TCPTransportOptions
+}
+
+// DialWebSocket connects to a ws:// or wss:// URL, e.g. wss://venus.web.telegram.org/apiws,
+// and runs obfuscated intermediate framing over binary frames, like the web clients do.
+func DialWebSocket(rawurl string, dcID int, options WebSocketOptions) (*TCPTransport, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	var secure bool
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		secure = true
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme %q", u.Scheme)
+	}
+
+	topts := options.Transport
+	if topts.Framing != PaddedIntermediateFraming {
+		topts.Framing = IntermediateFraming
+	}
+	topts.Obfuscated = true
+	topts.DCID = dcID
+	if topts.RandomReader == nil {
+		topts.RandomReader = rand.Reader
+	}
+
+	var c net.Conn
+	c, err = forwardDialer(topts.Dialer).Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if secure {
+		var cfg *tls.Config
+		if options.TLSConfig != nil {
+			cfg = options.TLSConfig.Clone()
+		} else {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tc := tls.Client(c, cfg)
+		err = tc.Handshake()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = tc
+	}
+
+	wc, err := webSocketHandshake(c, u, options.Origin, topts.RandomReader)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	tr, err := NewTCPTransport(wc, topts)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tr, nil
+}
+
+func webSocketHandshake(c net.Conn, u *url.URL, origin string, random io.Reader) (*wsConn, error) {
+	nonce := make([]byte, 16)
+	_, err := io.ReadFull(random, nonce)
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Protocol", "binary")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+
+	err = req.Write(c)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return nil, ErrWebSocketHandshake
+	}
+
+	return &wsConn{Conn: c, br: br, client: true}, nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key)
+	io.WriteString(h, wsAcceptGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// wsConn exposes the payload of binary WebSocket frames as a byte stream.
+// Each Write is sent as a single frame.
+type wsConn struct {
+	net.Conn
+	br *bufio.Reader
+
+	// clients must mask their frames
+	client bool
+
+	rbuf   []byte
+	closed bool
+
+	wmut sync.Mutex
+}
+
+func (c *wsConn) Read(b []byte) (int, error) {
+	for len(c.rbuf) == 0 {
+		if c.closed {
+			return 0, io.EOF
+		}
+
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case wsOpBinary, wsOpContinuation:
+			c.rbuf = payload
+		case wsOpPing:
+			err = c.writeFrame(wsOpPong, payload)
+			if err != nil {
+				return 0, err
+			}
+		case wsOpPong:
+			break
+		case wsOpClose:
+			c.closed = true
+			c.writeFrame(wsOpClose, payload)
+		default:
+			return 0, ErrInvalidWebSocketFrame
+		}
+	}
+
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+func (c *wsConn) Write(b []byte) (int, error) {
+	err := c.writeFrame(wsOpBinary, b)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *wsConn) readFrame() (int, []byte, error) {
+	var header [2]byte
+	_, err := io.ReadFull(c.br, header[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	op := int(header[0] & 0x0F)
+	masked := (header[1] & 0x80) != 0
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > uint64(1024*1024*16) || (op >= wsOpClose && n > wsMaxControlLen) {
+		return 0, nil, ErrInvalidWebSocketFrame
+	}
+
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(c.br, mask[:])
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(c.br, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		maskBytes(payload, mask)
+	}
+	return op, payload, nil
+}
+
+func (c *wsConn) writeFrame(op int, payload []byte) error {
+	c.wmut.Lock()
+	defer c.wmut.Unlock()
+
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|byte(op))
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	n := len(payload)
+	switch {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, maskBit|127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		buf = append(buf, ext[:]...)
+	}
+
+	if c.client {
+		// the mask keys come from crypto/rand, which is safe for concurrent
+		// use, unlike the RandomReader the transport shares with its codec
+		var mask [4]byte
+		_, err := io.ReadFull(rand.Reader, mask[:])
+		if err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(buf[start:], mask)
+	} else {
+		buf = append(buf, payload...)
+	}
+
+	_, err := c.Conn.Write(buf)
+	return err
+}
+
+func maskBytes(b []byte, mask [4]byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
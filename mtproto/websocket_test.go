@@ -0,0 +1,121 @@
+package mtproto
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// webSocketEchoHandler accepts obfuscated intermediate framing over WebSocket
+// and echoes every packet back.
+func webSocketEchoHandler(t *testing.T, dcID int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apiws" || r.Header.Get("Origin") != "https://web.telegram.org" || r.Header.Get("Sec-WebSocket-Protocol") != "binary" {
+			t.Errorf("unexpected request %v %v", r.URL.Path, r.Header)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Protocol: binary\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+
+		wc := &wsConn{Conn: c, br: brw.Reader}
+
+		// make sure the client handles control frames in between data
+		wc.writeFrame(wsOpPing, []byte("hi"))
+
+		oc, tag, actualDC, err := AcceptObfuscated(wc, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if a, e := hex.EncodeToString(tag), "eeeeeeee"; a != e {
+			t.Errorf("got tag %v, expected %v", a, e)
+		}
+		if actualDC != dcID {
+			t.Errorf("requested DC %v, expected %v", actualDC, dcID)
+		}
+
+		for {
+			header := make([]byte, 4)
+			_, err := io.ReadFull(oc, header)
+			if err != nil {
+				return
+			}
+			data := make([]byte, binary.LittleEndian.Uint32(header))
+			_, err = io.ReadFull(oc, data)
+			if err != nil {
+				return
+			}
+			oc.Write(append(header, data...))
+		}
+	})
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	plain := httptest.NewServer(webSocketEchoHandler(t, 2))
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(webSocketEchoHandler(t, 2))
+	defer secure.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(secure.Certificate())
+
+	tests := []struct {
+		url string
+		tls *tls.Config
+	}{
+		{strings.Replace(plain.URL, "http://", "ws://", 1) + "/apiws", nil},
+		{strings.Replace(secure.URL, "https://", "wss://", 1) + "/apiws", &tls.Config{RootCAs: roots}},
+	}
+	for _, tt := range tests {
+		tr, err := DialWebSocket(tt.url, 2, WebSocketOptions{
+			Origin:    "https://web.telegram.org",
+			TLSConfig: tt.tls,
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", tt.url, err)
+		}
+
+		packet := fromHex("0000000000000000 0102030405060708 04000000 aabbccdd")
+		err = tr.Send(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _, err := tr.Recv()
+		if err != nil {
+			t.Fatalf("%v: %v", tt.url, err)
+		}
+		if a, e := hex.EncodeToString(raw), hex.EncodeToString(packet); a != e {
+			t.Errorf("%v: received %v, expected %v", tt.url, a, e)
+		}
+
+		tr.Close()
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := DialWebSocket(strings.Replace(srv.URL, "http://", "ws://", 1)+"/apiws", 2, WebSocketOptions{})
+	if err != ErrWebSocketHandshake {
+		t.Errorf("got %v, expected %v", err, ErrWebSocketHandshake)
+	}
+}
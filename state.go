@@ -16,6 +16,15 @@ func readAuth(o *mtproto.AuthResult, fs *mtproto.FramerState, r *tl.Reader, ver
 		r.ReadFull(o.SessionID[:])
 		o.TimeOffset = r.ReadInt()
 	}
+	if o.KeyID != 0 && ver >= 7 {
+		o.FutureSalts = make([]mtproto.FutureSalt, r.ReadInt())
+		for i := range o.FutureSalts {
+			s := &o.FutureSalts[i]
+			s.Salt = r.ReadUint64()
+			s.ValidSince = r.ReadInt()
+			s.ValidUntil = r.ReadInt()
+		}
+	}
 
 	fs.SeqNo = r.ReadUint32()
 }
@@ -27,6 +36,12 @@ func writeAuth(o *mtproto.AuthResult, fs *mtproto.FramerState, w *tl.Writer) {
 		w.Write(o.ServerSalt[:])
 		w.Write(o.SessionID[:])
 		w.WriteInt(o.TimeOffset)
+		w.WriteInt(len(o.FutureSalts))
+		for _, s := range o.FutureSalts {
+			w.WriteUint64(s.Salt)
+			w.WriteInt(s.ValidSince)
+			w.WriteInt(s.ValidUntil)
+		}
 	}
 
 	w.WriteUint32(fs.SeqNo)
@@ -68,7 +83,7 @@ func (o *DCState) Clone() *DCState {
 func (o *DCState) Read(r *tl.Reader, ver int) {
 	o.ID = r.ReadInt()
 	o.PrimaryAddr.Read(r, 1)
-	readAuth(&o.Auth, &o.FramerState, r, 1)
+	readAuth(&o.Auth, &o.FramerState, r, ver)
 }
 
 func (o *DCState) Write(w *tl.Writer) {
@@ -77,6 +92,50 @@ func (o *DCState) Write(w *tl.Writer) {
 	writeAuth(&o.Auth, &o.FramerState, w)
 }
 
+type UpdatesState struct {
+	Pts  int
+	Qts  int
+	Date int
+	Seq  int
+}
+
+func (o *UpdatesState) Read(r *tl.Reader, ver int) {
+	o.Pts = r.ReadInt()
+	o.Qts = r.ReadInt()
+	o.Date = r.ReadInt()
+	o.Seq = r.ReadInt()
+}
+
+func (o *UpdatesState) Write(w *tl.Writer) {
+	w.WriteInt(o.Pts)
+	w.WriteInt(o.Qts)
+	w.WriteInt(o.Date)
+	w.WriteInt(o.Seq)
+}
+
+type ChannelState struct {
+	ID         int
+	AccessHash uint64
+	Pts        int
+}
+
+func (o *ChannelState) Clone() *ChannelState {
+	c := *o
+	return &c
+}
+
+func (o *ChannelState) Read(r *tl.Reader, ver int) {
+	o.ID = r.ReadInt()
+	o.AccessHash = r.ReadUint64()
+	o.Pts = r.ReadInt()
+}
+
+func (o *ChannelState) Write(w *tl.Writer) {
+	w.WriteInt(o.ID)
+	w.WriteUint64(o.AccessHash)
+	w.WriteInt(o.Pts)
+}
+
 type State struct {
 	PreferredDC int
 
@@ -90,13 +149,21 @@ type State struct {
 	FirstName string
 	LastName  string
 	Username  string
+
+	Updates  UpdatesState
+	Channels map[int]*ChannelState
 }
 
 func (o *State) Clone() *State {
 	c := *o
+	c.DCs = make(map[int]*DCState, len(o.DCs))
 	for id, dc := range o.DCs {
 		c.DCs[id] = dc.Clone()
 	}
+	c.Channels = make(map[int]*ChannelState, len(o.Channels))
+	for id, ch := range o.Channels {
+		c.Channels[id] = ch.Clone()
+	}
 	return &c
 }
 
@@ -108,6 +175,9 @@ func (o *State) initialize() {
 	if o.DCs == nil {
 		o.DCs = make(map[int]*DCState)
 	}
+	if o.Channels == nil {
+		o.Channels = make(map[int]*ChannelState)
+	}
 }
 
 func (o *State) findPreferredDC() *DCState {
@@ -126,7 +196,7 @@ func (o *State) findPreferredDC() *DCState {
 }
 
 func (o *State) WriteBareTo(w *tl.Writer) {
-	w.WriteInt(4)
+	w.WriteInt(7)
 	w.WriteInt(o.PreferredDC)
 
 	w.WriteInt(len(o.DCs))
@@ -141,11 +211,17 @@ func (o *State) WriteBareTo(w *tl.Writer) {
 	w.WriteString(o.FirstName)
 	w.WriteString(o.LastName)
 	w.WriteString(o.Username)
+	o.Updates.Write(w)
+
+	w.WriteInt(len(o.Channels))
+	for _, ch := range o.Channels {
+		ch.Write(w)
+	}
 }
 
 func (o *State) ReadBareFrom(r *tl.Reader) {
 	ver := r.ReadInt()
-	if ver < 1 || ver > 4 {
+	if ver < 1 || ver > 7 {
 		r.Fail(errors.New("Unsupported version"))
 	}
 
@@ -155,7 +231,7 @@ func (o *State) ReadBareFrom(r *tl.Reader) {
 	n := r.ReadInt()
 	for i := 0; i < n; i++ {
 		dc := new(DCState)
-		dc.Read(r, 1)
+		dc.Read(r, ver)
 		o.DCs[dc.ID] = dc
 	}
 
@@ -172,4 +248,17 @@ func (o *State) ReadBareFrom(r *tl.Reader) {
 		o.LastName = r.ReadString()
 		o.Username = r.ReadString()
 	}
+	if ver >= 5 {
+		o.Updates.Read(r, 1)
+	}
+
+	o.Channels = make(map[int]*ChannelState)
+	if ver >= 6 {
+		n := r.ReadInt()
+		for i := 0; i < n; i++ {
+			ch := new(ChannelState)
+			ch.Read(r, 1)
+			o.Channels[ch.ID] = ch
+		}
+	}
 }
@@ -3,6 +3,7 @@ package tl
 import (
 	"bytes"
 	"compress/gzip"
+	"fmt"
 	"io"
 )
 
@@ -28,6 +29,48 @@ func DecodeObject(r *Reader) *Reader {
 	}
 }
 
+// GzipPacked is gzip_packed, which can stand in for any object and carries
+// its compressed boxed serialization.
+type GzipPacked struct {
+	PackedData []byte
+}
+
+func (o *GzipPacked) Cmd() uint32 {
+	return TagGzipPacked
+}
+
+func (o *GzipPacked) ReadBareFrom(r *Reader) {
+	o.PackedData = r.ReadBlob()
+}
+
+func (o *GzipPacked) WriteBareTo(w *Writer) {
+	w.WriteBlob(o.PackedData)
+}
+
+func (o *GzipPacked) String() string {
+	return fmt.Sprintf("gzip_packed(%d bytes)", len(o.PackedData))
+}
+
+// GzipPack compresses the boxed serialization of o into gzip_packed.
+func GzipPack(o Object) (*GzipPacked, error) {
+	return GzipPackBytes(Bytes(o))
+}
+
+// GzipPackBytes compresses an already serialized boxed object into gzip_packed.
+func GzipPackBytes(data []byte) (*GzipPacked, error) {
+	var buf bytes.Buffer
+	compressor := gzip.NewWriter(&buf)
+	_, err := compressor.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = compressor.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &GzipPacked{buf.Bytes()}, nil
+}
+
 func gunzip(compressed []byte) ([]byte, error) {
 	decompressor, err := gzip.NewReader(bytes.NewReader(compressed))
 	if err != nil {
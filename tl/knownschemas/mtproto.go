@@ -14,6 +14,7 @@ int256 8*[ int ] = Int256;
 resPQ#05162463 nonce:int128 server_nonce:int128 pq:bytes server_public_key_fingerprints:Vector<long> = ResPQ;
 
 p_q_inner_data#83c95aec pq:bytes p:bytes q:bytes nonce:int128 server_nonce:int128 new_nonce:int256 = P_Q_inner_data;
+p_q_inner_data_temp#3c6a84d4 pq:bytes p:bytes q:bytes nonce:int128 server_nonce:int128 new_nonce:int256 expires_in:int = P_Q_inner_data;
 
 
 server_DH_params_fail#79cb045d nonce:int128 server_nonce:int128 new_nonce_hash:int128 = Server_DH_Params;
@@ -46,12 +47,14 @@ new_session_created#9ec20908 first_msg_id:long unique_id:long server_salt:long =
 
 msg_container#73f1f8dc messages:vector<%Message> = MessageContainer;
 message msg_id:long seqno:int bytes:int body:Object = Message;
-msg_copy#e06046b2 orig_message:Message = MessageCopy;
+msg_copy#e06046b2 orig_message:%Message = MessageCopy;
 
 gzip_packed#3072cfa1 packed_data:bytes = Object;
 
 msgs_ack#62d6b459 msg_ids:Vector<long> = MsgsAck;
 
+bind_auth_key_inner#75a3f765 nonce:long temp_auth_key_id:long perm_auth_key_id:long temp_session_id:long expires_at:int = BindAuthKeyInner;
+
 bad_msg_notification#a7eff811 bad_msg_id:long bad_msg_seqno:int error_code:int = BadMsgNotification;
 bad_server_salt#edab447b bad_msg_id:long bad_msg_seqno:int error_code:int new_server_salt:long = BadMsgNotification;
 
@@ -78,11 +78,11 @@ func (d Def) CanonicalString() string {
 
 func (d *Def) Alter(alter *Alterations) {
 	d.CombName.Alter(alter)
-	for _, arg := range d.GenericArgs {
-		arg.Alter(alter)
+	for i := range d.GenericArgs {
+		d.GenericArgs[i].Alter(alter)
 	}
-	for _, arg := range d.Args {
-		arg.Alter(alter)
+	for i := range d.Args {
+		d.Args[i].Alter(alter)
 	}
 	d.ResultType.Alter(alter)
 }
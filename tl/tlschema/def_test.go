@@ -20,6 +20,11 @@ func TestAlterDef(t *testing.T) {
 			map[string]string{"message": "proto_message", "Message": "ProtoMessage"},
 			"ctor msg_container#73f1f8dc messages:vector<%ProtoMessage> = MessageContainer",
 		},
+		{
+			"msg_copy#e06046b2 orig_message:%Message = MessageCopy",
+			map[string]string{"message": "proto_message", "Message": "ProtoMessage"},
+			"ctor msg_copy#e06046b2 orig_message:%ProtoMessage = MessageCopy",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,600 @@
+package telegramapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+// how long to wait for a missing update to arrive before asking for the difference
+const updatesGapTimeout = 500 * time.Millisecond
+
+// how long to wait before retrying a failed getState/getDifference call
+const updatesSyncRetryDelay = 5 * time.Second
+
+var errUpdatesStopped = errors.New("updates processing stopped")
+
+// Update is a single incoming update, together with the users and chats
+// that came along with it.
+type Update struct {
+	Update mtproto.TLUpdateType
+	Users  []mtproto.TLUserType
+	Chats  []mtproto.TLChatType
+}
+
+type pendingUpdate struct {
+	pts      int
+	ptsCount int
+	update   *Update
+}
+
+type pendingSeq struct {
+	seqStart int
+	seq      int
+	date     int
+	updates  []mtproto.TLUpdateType
+	users    []mtproto.TLUserType
+	chats    []mtproto.TLChatType
+}
+
+// updatesEngine applies incoming updates in pts/qts/seq order, fills gaps
+// using updates.getDifference and hands the results over to the delegate.
+//
+// Everything except the queue is owned by the updates goroutine.
+type updatesEngine struct {
+	conn *Conn
+
+	// sends getState/getDifference requests, normally Conn.SendContext
+	fetch func(ctx context.Context, o tl.Object) (tl.Object, error)
+
+	mut      sync.Mutex
+	queue    []mtproto.TLUpdatesType
+	syncReq  bool
+	resetReq bool
+
+	signalc chan struct{}
+	quitc   chan struct{}
+	done    sync.WaitGroup
+
+	// cancelled on stop to abort requests in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	state      UpdatesState
+	synced     bool
+	needSync   bool
+	dirty      bool
+	pendingPts []*pendingUpdate
+	pendingQts []*pendingUpdate
+	pendingSeq []*pendingSeq
+
+	channels     map[int]*channelUpdates
+	accessHashes map[int]uint64
+}
+
+func newUpdatesEngine(conn *Conn) *updatesEngine {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &updatesEngine{
+		conn:    conn,
+		fetch:   conn.SendContext,
+		signalc: make(chan struct{}, 1),
+		quitc:   make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+
+		channels:     make(map[int]*channelUpdates),
+		accessHashes: make(map[int]uint64),
+	}
+}
+
+func (e *updatesEngine) start() {
+	e.conn.stateMut.Lock()
+	e.state = e.conn.state.Updates
+	for id, cs := range e.conn.state.Channels {
+		e.channels[id] = &channelUpdates{ChannelState: *cs}
+		e.accessHashes[id] = cs.AccessHash
+	}
+	e.conn.stateMut.Unlock()
+
+	e.done.Add(1)
+	go e.run()
+}
+
+func (e *updatesEngine) stop() {
+	e.cancel()
+	close(e.quitc)
+	e.done.Wait()
+}
+
+func (e *updatesEngine) signal() {
+	select {
+	case e.signalc <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue is called by the session (and by Conn.Send for RPC results that
+// carry updates); it never blocks.
+func (e *updatesEngine) enqueue(o mtproto.TLUpdatesType) {
+	e.mut.Lock()
+	e.queue = append(e.queue, o)
+	e.mut.Unlock()
+	e.signal()
+}
+
+// requestSync asks for updates.getState (if no state is known yet) or
+// updates.getDifference to be called. If reset is true, the known state is
+// discarded first, which is what we want after logging in.
+func (e *updatesEngine) requestSync(reset bool) {
+	e.mut.Lock()
+	e.syncReq = true
+	if reset {
+		e.resetReq = true
+	}
+	e.mut.Unlock()
+	e.signal()
+}
+
+func (e *updatesEngine) run() {
+	defer e.done.Done()
+
+	var timerc <-chan time.Time
+	for {
+		select {
+		case <-e.signalc:
+		case <-timerc:
+			timerc = nil
+			e.expireGaps()
+		case <-e.quitc:
+			return
+		}
+
+		e.mut.Lock()
+		if e.resetReq {
+			e.resetReq = false
+			e.state = UpdatesState{}
+			e.synced = false
+			e.clearPending()
+			e.channels = make(map[int]*channelUpdates)
+		}
+		if e.syncReq {
+			e.syncReq = false
+			e.needSync = true
+		}
+		e.mut.Unlock()
+
+		if e.needSync {
+			err := e.sync()
+			if err == errUpdatesStopped {
+				return
+			} else if err != nil {
+				log.Printf("** WARNING: failed to fetch updates state: %v", err)
+				timerc = time.After(updatesSyncRetryDelay)
+				continue
+			}
+			e.needSync = false
+			e.synced = true
+			e.replayPending()
+		}
+
+		if !e.synced {
+			continue
+		}
+
+		err := e.syncChannels()
+		if err == errUpdatesStopped {
+			return
+		} else if err != nil {
+			log.Printf("** WARNING: failed to fetch channel updates: %v", err)
+			timerc = time.After(updatesSyncRetryDelay)
+			continue
+		}
+
+		e.mut.Lock()
+		queue := e.queue
+		e.queue = nil
+		e.mut.Unlock()
+
+		for _, o := range queue {
+			e.handleUpdates(o)
+		}
+		e.commit()
+
+		if e.needSync || e.needChannelSync() {
+			timerc = nil
+			e.signal()
+		} else if e.hasPending() {
+			if timerc == nil {
+				timerc = time.After(updatesGapTimeout)
+			}
+		} else {
+			timerc = nil
+		}
+	}
+}
+
+func (e *updatesEngine) send(o tl.Object) (tl.Object, error) {
+	r, err := e.fetch(e.ctx, o)
+	if e.ctx.Err() != nil {
+		return nil, errUpdatesStopped
+	}
+	return r, err
+}
+
+func (e *updatesEngine) sync() error {
+	if e.state.Pts == 0 {
+		r, err := e.send(&mtproto.TLUpdatesGetState{})
+		if err != nil {
+			return err
+		}
+		switch r := r.(type) {
+		case *mtproto.TLUpdatesState:
+			e.setState(r)
+			e.commit()
+			return nil
+		default:
+			return e.conn.HandleUnknownReply(r)
+		}
+	}
+
+	for {
+		r, err := e.send(&mtproto.TLUpdatesGetDifference{
+			Pts:  e.state.Pts,
+			Date: e.state.Date,
+			Qts:  e.state.Qts,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch r := r.(type) {
+		case *mtproto.TLUpdatesDifferenceEmpty:
+			e.state.Date = r.Date
+			e.state.Seq = r.Seq
+			e.dirty = true
+			e.commit()
+			return nil
+		case *mtproto.TLUpdatesDifference:
+			e.applyDifference(r.NewMessages, r.NewEncryptedMessages, r.OtherUpdates, r.Users, r.Chats)
+			e.setState(r.State)
+			e.commit()
+			return nil
+		case *mtproto.TLUpdatesDifferenceSlice:
+			e.applyDifference(r.NewMessages, r.NewEncryptedMessages, r.OtherUpdates, r.Users, r.Chats)
+			e.setState(r.IntermediateState)
+			e.commit()
+		case *mtproto.TLUpdatesDifferenceTooLong:
+			if e.conn.Verbose >= 1 {
+				log.Printf("Updates difference too long, skipping to pts %d", r.Pts)
+			}
+			e.state.Pts = r.Pts
+			e.dirty = true
+			e.commit()
+		default:
+			return e.conn.HandleUnknownReply(r)
+		}
+	}
+}
+
+func (e *updatesEngine) setState(st *mtproto.TLUpdatesState) {
+	e.state = UpdatesState{
+		Pts:  st.Pts,
+		Qts:  st.Qts,
+		Date: st.Date,
+		Seq:  st.Seq,
+	}
+	e.dirty = true
+}
+
+func (e *updatesEngine) applyDifference(messages []mtproto.TLMessageType, encrypted []mtproto.TLEncryptedMessageType, others []mtproto.TLUpdateType, users []mtproto.TLUserType, chats []mtproto.TLChatType) {
+	e.learnChats(chats)
+	for _, msg := range messages {
+		e.deliver(&Update{&mtproto.TLUpdateNewMessage{Message: msg}, users, chats})
+	}
+	for _, msg := range encrypted {
+		e.deliver(&Update{&mtproto.TLUpdateNewEncryptedMessage{Message: msg}, users, chats})
+	}
+	for _, upd := range others {
+		if isChannelUpdate(upd) {
+			// channels have their own pts, which the common difference does not advance
+			e.handleUpdate(&Update{upd, users, chats})
+		} else {
+			e.deliver(&Update{upd, users, chats})
+		}
+	}
+}
+
+func (e *updatesEngine) commit() {
+	if !e.dirty {
+		return
+	}
+	e.dirty = false
+
+	st := e.state
+	channels := make(map[int]*ChannelState, len(e.channels))
+	for id, ch := range e.channels {
+		cs := ch.ChannelState
+		channels[id] = &cs
+	}
+	e.conn.updateState(func(state *State) {
+		state.Updates = st
+		state.Channels = channels
+	})
+}
+
+func (e *updatesEngine) deliver(u *Update) {
+	c := e.conn
+	c.delegateQueue <- func() {
+		c.delegate.HandleUpdate(u)
+	}
+}
+
+func (e *updatesEngine) handleUpdates(o mtproto.TLUpdatesType) {
+	switch o := o.(type) {
+	case *mtproto.TLUpdatesTooLong:
+		e.needSync = true
+	case *mtproto.TLUpdateShort:
+		e.handleUpdate(&Update{Update: o.Update})
+	case *mtproto.TLUpdateShortMessage:
+		e.handleUpdate(&Update{Update: e.shortMessageUpdate(o)})
+	case *mtproto.TLUpdateShortChatMessage:
+		e.handleUpdate(&Update{Update: shortChatMessageUpdate(o)})
+	case *mtproto.TLUpdateShortSentMessage:
+		// the message itself is returned to whoever sent it, we only need to account for its pts
+		e.handlePts(o.Pts, o.PtsCount, nil)
+	case *mtproto.TLUpdates:
+		e.learnChats(o.Chats)
+		e.handleSeq(&pendingSeq{o.Seq, o.Seq, o.Date, o.Updates, o.Users, o.Chats})
+	case *mtproto.TLUpdatesCombined:
+		e.learnChats(o.Chats)
+		e.handleSeq(&pendingSeq{o.SeqStart, o.Seq, o.Date, o.Updates, o.Users, o.Chats})
+	default:
+		log.Printf("Unknown updates: %v", o)
+	}
+}
+
+func (e *updatesEngine) handleSeq(b *pendingSeq) {
+	if b.seqStart != 0 {
+		if e.state.Seq+1 > b.seqStart {
+			return // already applied
+		} else if e.state.Seq+1 < b.seqStart {
+			e.pendingSeq = append(e.pendingSeq, b)
+			return
+		}
+	}
+
+	e.applySeq(b)
+
+	for progress := true; progress; {
+		progress = false
+		for i, b := range e.pendingSeq {
+			if e.state.Seq+1 >= b.seqStart {
+				e.pendingSeq = append(e.pendingSeq[:i], e.pendingSeq[i+1:]...)
+				if e.state.Seq+1 == b.seqStart {
+					e.applySeq(b)
+				}
+				progress = true
+				break
+			}
+		}
+	}
+}
+
+func (e *updatesEngine) applySeq(b *pendingSeq) {
+	for _, upd := range b.updates {
+		e.handleUpdate(&Update{upd, b.users, b.chats})
+	}
+	if b.seq != 0 {
+		e.state.Seq = b.seq
+		e.state.Date = b.date
+		e.dirty = true
+	}
+}
+
+func (e *updatesEngine) handleUpdate(u *Update) {
+	if id, pts, ptsCount, ok := channelUpdatePts(u.Update); ok {
+		e.handleChannelPts(id, pts, ptsCount, u)
+	} else if o, ok := u.Update.(*mtproto.TLUpdateChannelTooLong); ok {
+		e.handleChannelTooLong(o, u)
+	} else if pts, ptsCount, ok := updatePts(u.Update); ok {
+		e.handlePts(pts, ptsCount, u)
+	} else if qts, ok := updateQts(u.Update); ok {
+		e.handleQts(qts, u)
+	} else {
+		e.deliver(u)
+	}
+}
+
+func (e *updatesEngine) handlePts(pts, ptsCount int, u *Update) {
+	e.pendingPts = e.applyPts(&e.state.Pts, e.pendingPts, &pendingUpdate{pts, ptsCount, u})
+}
+
+func (e *updatesEngine) handleQts(qts int, u *Update) {
+	e.pendingQts = e.applyPts(&e.state.Qts, e.pendingQts, &pendingUpdate{qts, 1, u})
+}
+
+// applyPts applies the given update if it directly follows the local pts,
+// buffers it if there is a gap, and drops it if it has already been applied.
+// Returns the new list of buffered updates.
+func (e *updatesEngine) applyPts(local *int, pending []*pendingUpdate, p *pendingUpdate) []*pendingUpdate {
+	switch comparePts(*local, p.pts, p.ptsCount) {
+	case ptsDuplicate:
+		return pending
+	case ptsGap:
+		return append(pending, p)
+	}
+
+	e.applyPending(local, p)
+
+	for progress := true; progress; {
+		progress = false
+		for i, p := range pending {
+			if r := comparePts(*local, p.pts, p.ptsCount); r != ptsGap {
+				pending = append(pending[:i], pending[i+1:]...)
+				if r == ptsApply {
+					e.applyPending(local, p)
+				}
+				progress = true
+				break
+			}
+		}
+	}
+	return pending
+}
+
+func (e *updatesEngine) applyPending(local *int, p *pendingUpdate) {
+	*local = p.pts
+	e.dirty = true
+	if p.update != nil {
+		e.deliver(p.update)
+	}
+}
+
+func (e *updatesEngine) hasPending() bool {
+	if len(e.pendingPts) > 0 || len(e.pendingQts) > 0 || len(e.pendingSeq) > 0 {
+		return true
+	}
+	for _, ch := range e.channels {
+		if len(ch.pending) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// expireGaps is called when buffered updates have waited for too long;
+// whatever pts sequences still have gaps get their difference fetched.
+func (e *updatesEngine) expireGaps() {
+	if len(e.pendingPts) > 0 || len(e.pendingQts) > 0 || len(e.pendingSeq) > 0 {
+		e.needSync = true
+	}
+	for _, ch := range e.channels {
+		if len(ch.pending) > 0 {
+			ch.needSync = true
+		}
+	}
+}
+
+func (e *updatesEngine) clearPending() {
+	e.pendingPts = nil
+	e.pendingQts = nil
+	e.pendingSeq = nil
+}
+
+// replayPending runs buffered updates through the checks again after the
+// state has been refreshed; the ones covered by the difference get dropped.
+func (e *updatesEngine) replayPending() {
+	pendingPts, pendingQts, pendingSeq := e.pendingPts, e.pendingQts, e.pendingSeq
+	e.clearPending()
+
+	for _, p := range pendingPts {
+		e.handlePts(p.pts, p.ptsCount, p.update)
+	}
+	for _, p := range pendingQts {
+		e.handleQts(p.pts, p.update)
+	}
+	for _, b := range pendingSeq {
+		e.handleSeq(b)
+	}
+	e.commit()
+}
+
+func (e *updatesEngine) shortMessageUpdate(o *mtproto.TLUpdateShortMessage) mtproto.TLUpdateType {
+	c := e.conn
+	c.stateMut.Lock()
+	selfID := c.state.UserID
+	c.stateMut.Unlock()
+
+	// flags of updateShortMessage use the same bits as the flags of message
+	msg := &mtproto.TLMessage{
+		Flags:        o.Flags,
+		ID:           o.ID,
+		FwdFrom:      o.FwdFrom,
+		ViaBotID:     o.ViaBotID,
+		ReplyToMsgID: o.ReplyToMsgID,
+		Date:         o.Date,
+		Message:      o.Message,
+		Entities:     o.Entities,
+	}
+	msg.SetHasFromID(true)
+	if o.Out() {
+		msg.FromID = selfID
+		msg.ToID = &mtproto.TLPeerUser{UserID: o.UserID}
+	} else {
+		msg.FromID = o.UserID
+		msg.ToID = &mtproto.TLPeerUser{UserID: selfID}
+	}
+
+	return &mtproto.TLUpdateNewMessage{Message: msg, Pts: o.Pts, PtsCount: o.PtsCount}
+}
+
+func shortChatMessageUpdate(o *mtproto.TLUpdateShortChatMessage) mtproto.TLUpdateType {
+	// flags of updateShortChatMessage use the same bits as the flags of message
+	msg := &mtproto.TLMessage{
+		Flags:        o.Flags,
+		ID:           o.ID,
+		FromID:       o.FromID,
+		ToID:         &mtproto.TLPeerChat{ChatID: o.ChatID},
+		FwdFrom:      o.FwdFrom,
+		ViaBotID:     o.ViaBotID,
+		ReplyToMsgID: o.ReplyToMsgID,
+		Date:         o.Date,
+		Message:      o.Message,
+		Entities:     o.Entities,
+	}
+	msg.SetHasFromID(true)
+
+	return &mtproto.TLUpdateNewMessage{Message: msg, Pts: o.Pts, PtsCount: o.PtsCount}
+}
+
+const (
+	ptsApply = iota
+	ptsDuplicate
+	ptsGap
+)
+
+func comparePts(local, pts, ptsCount int) int {
+	if local+ptsCount == pts {
+		return ptsApply
+	} else if local+ptsCount > pts {
+		return ptsDuplicate
+	} else {
+		return ptsGap
+	}
+}
+
+// updatePts returns the common (non-channel) pts carried by the update, if any.
+func updatePts(u mtproto.TLUpdateType) (int, int, bool) {
+	switch u := u.(type) {
+	case *mtproto.TLUpdateNewMessage:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateEditMessage:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateDeleteMessages:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateReadHistoryInbox:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateReadHistoryOutbox:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateReadMessagesContents:
+		return u.Pts, u.PtsCount, true
+	case *mtproto.TLUpdateWebPage:
+		return u.Pts, u.PtsCount, true
+	default:
+		return 0, 0, false
+	}
+}
+
+func updateQts(u mtproto.TLUpdateType) (int, bool) {
+	switch u := u.(type) {
+	case *mtproto.TLUpdateNewEncryptedMessage:
+		return u.Qts, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,413 @@
+package telegramapi
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/andreyvit/telegramapi/mtproto"
+	"github.com/andreyvit/telegramapi/tl"
+)
+
+type recordingDelegate struct {
+	updates []*Update
+}
+
+func (d *recordingDelegate) HandleConnectionReady()             {}
+func (d *recordingDelegate) HandleStateChanged(newState *State) {}
+func (d *recordingDelegate) HandleUpdate(update *Update) {
+	d.updates = append(d.updates, update)
+}
+
+// delivered returns a short description of every delivered update, like
+// "msg 5" or "status 7", and forgets them.
+func (d *recordingDelegate) delivered() []string {
+	var r []string
+	for _, u := range d.updates {
+		r = append(r, describeUpdate(u.Update))
+	}
+	d.updates = nil
+	return r
+}
+
+func describeUpdate(u mtproto.TLUpdateType) string {
+	switch u := u.(type) {
+	case *mtproto.TLUpdateNewMessage:
+		return "msg " + strconv.Itoa(u.Message.(*mtproto.TLMessage).ID)
+	case *mtproto.TLUpdateNewChannelMessage:
+		return "chmsg " + strconv.Itoa(u.Message.(*mtproto.TLMessage).ID)
+	case *mtproto.TLUpdateNewEncryptedMessage:
+		return "enc " + strconv.Itoa(u.Qts)
+	case *mtproto.TLUpdateUserStatus:
+		return "status " + strconv.Itoa(u.UserID)
+	case *mtproto.TLUpdateChannelTooLong:
+		return "toolong " + strconv.Itoa(u.ChannelID)
+	default:
+		return tl.Name(u)
+	}
+}
+
+// stubFetcher answers getState/getDifference calls with canned replies and
+// records the requests.
+type stubFetcher struct {
+	replies  []tl.Object
+	requests []tl.Object
+}
+
+func (f *stubFetcher) fetch(ctx context.Context, o tl.Object) (tl.Object, error) {
+	f.requests = append(f.requests, o)
+	if len(f.replies) == 0 {
+		return &mtproto.TLRPCError{ErrorCode: 500, ErrorMessage: "NO_STUB_REPLY"}, nil
+	}
+	r := f.replies[0]
+	f.replies = f.replies[1:]
+	return r, nil
+}
+
+// newTestEngine returns an updates engine that is driven directly by the
+// test rather than by its goroutine.
+func newTestEngine(pts int, replies ...tl.Object) (*updatesEngine, *recordingDelegate, *stubFetcher) {
+	d := new(recordingDelegate)
+	c := New(Options{SeedAddr: Addr{"127.0.0.1", 443}}, &State{}, d)
+	c.state.initialize()
+	c.delegateQueue = make(chan func(), 1000)
+
+	f := &stubFetcher{replies: replies}
+	e := c.updates
+	e.fetch = f.fetch
+	e.state.Pts = pts
+	e.synced = true
+	return e, d, f
+}
+
+// runDelegateCalls performs the delegate calls queued so far.
+func runDelegateCalls(e *updatesEngine) {
+	for {
+		select {
+		case f := <-e.conn.delegateQueue:
+			f()
+		default:
+			return
+		}
+	}
+}
+
+func newMsg(id, pts, ptsCount int) mtproto.TLUpdateType {
+	return &mtproto.TLUpdateNewMessage{Message: &mtproto.TLMessage{ID: id}, Pts: pts, PtsCount: ptsCount}
+}
+
+func TestComparePts(t *testing.T) {
+	tests := []struct {
+		local, pts, ptsCount int
+		expected             int
+	}{
+		{10, 11, 1, ptsApply},
+		{10, 13, 3, ptsApply},
+		{10, 10, 0, ptsApply},
+		{10, 10, 1, ptsDuplicate},
+		{10, 9, 1, ptsDuplicate},
+		{10, 12, 1, ptsGap},
+		{10, 14, 3, ptsGap},
+	}
+
+	for _, tt := range tests {
+		actual := comparePts(tt.local, tt.pts, tt.ptsCount)
+		if actual != tt.expected {
+			t.Errorf("comparePts(%d, %d, %d) == %d, expected %d", tt.local, tt.pts, tt.ptsCount, actual, tt.expected)
+		}
+	}
+}
+
+func TestUpdatesPtsOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		updates   []mtproto.TLUpdateType
+		delivered []string
+		pts       int
+		pending   int
+	}{
+		{
+			"in order",
+			[]mtproto.TLUpdateType{newMsg(1, 11, 1), newMsg(2, 12, 1), newMsg(3, 14, 2)},
+			[]string{"msg 1", "msg 2", "msg 3"},
+			14, 0,
+		},
+		{
+			"out of order",
+			[]mtproto.TLUpdateType{newMsg(3, 13, 1), newMsg(2, 12, 1), newMsg(1, 11, 1)},
+			[]string{"msg 1", "msg 2", "msg 3"},
+			13, 0,
+		},
+		{
+			"duplicates",
+			[]mtproto.TLUpdateType{newMsg(1, 11, 1), newMsg(1, 11, 1), newMsg(0, 9, 1), newMsg(2, 12, 1)},
+			[]string{"msg 1", "msg 2"},
+			12, 0,
+		},
+		{
+			"gap",
+			[]mtproto.TLUpdateType{newMsg(1, 11, 1), newMsg(3, 13, 1), newMsg(4, 14, 1)},
+			[]string{"msg 1"},
+			11, 2,
+		},
+		{
+			"no pts",
+			[]mtproto.TLUpdateType{newMsg(2, 12, 1), &mtproto.TLUpdateUserStatus{UserID: 7}, newMsg(1, 11, 1)},
+			[]string{"status 7", "msg 1", "msg 2"},
+			12, 0,
+		},
+	}
+
+	for _, tt := range tests {
+		e, d, _ := newTestEngine(10)
+		for _, u := range tt.updates {
+			e.handleUpdates(&mtproto.TLUpdateShort{Update: u})
+		}
+		runDelegateCalls(e)
+
+		if actual := d.delivered(); !reflect.DeepEqual(actual, tt.delivered) {
+			t.Errorf("%s: delivered %v, expected %v", tt.name, actual, tt.delivered)
+		}
+		if e.state.Pts != tt.pts {
+			t.Errorf("%s: pts == %d, expected %d", tt.name, e.state.Pts, tt.pts)
+		}
+		if len(e.pendingPts) != tt.pending {
+			t.Errorf("%s: %d pending, expected %d", tt.name, len(e.pendingPts), tt.pending)
+		}
+	}
+}
+
+func TestUpdatesQtsOrder(t *testing.T) {
+	enc := func(qts int) mtproto.TLUpdateType {
+		return &mtproto.TLUpdateNewEncryptedMessage{Message: &mtproto.TLEncryptedMessage{}, Qts: qts}
+	}
+
+	e, d, _ := newTestEngine(10)
+	e.state.Qts = 5
+	for _, u := range []mtproto.TLUpdateType{enc(7), enc(6), enc(6), enc(9)} {
+		e.handleUpdates(&mtproto.TLUpdateShort{Update: u})
+	}
+	runDelegateCalls(e)
+
+	expected := []string{"enc 6", "enc 7"}
+	if actual := d.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("delivered %v, expected %v", actual, expected)
+	}
+	if e.state.Qts != 7 || len(e.pendingQts) != 1 {
+		t.Errorf("qts == %d with %d pending, expected 7 with 1 pending", e.state.Qts, len(e.pendingQts))
+	}
+}
+
+func TestUpdatesSeqOrder(t *testing.T) {
+	status := func(id int) []mtproto.TLUpdateType {
+		return []mtproto.TLUpdateType{&mtproto.TLUpdateUserStatus{UserID: id}}
+	}
+
+	tests := []struct {
+		name      string
+		updates   []mtproto.TLUpdatesType
+		delivered []string
+		seq       int
+		pending   int
+	}{
+		{
+			"in order",
+			[]mtproto.TLUpdatesType{
+				&mtproto.TLUpdates{Updates: status(1), Seq: 6},
+				&mtproto.TLUpdatesCombined{Updates: status(2), SeqStart: 7, Seq: 8},
+			},
+			[]string{"status 1", "status 2"},
+			8, 0,
+		},
+		{
+			"out of order",
+			[]mtproto.TLUpdatesType{
+				&mtproto.TLUpdates{Updates: status(3), Seq: 8},
+				&mtproto.TLUpdates{Updates: status(2), Seq: 7},
+				&mtproto.TLUpdates{Updates: status(1), Seq: 6},
+			},
+			[]string{"status 1", "status 2", "status 3"},
+			8, 0,
+		},
+		{
+			"already applied",
+			[]mtproto.TLUpdatesType{
+				&mtproto.TLUpdates{Updates: status(1), Seq: 5},
+				&mtproto.TLUpdates{Updates: status(2), Seq: 6},
+			},
+			[]string{"status 2"},
+			6, 0,
+		},
+		{
+			"gap",
+			[]mtproto.TLUpdatesType{
+				&mtproto.TLUpdates{Updates: status(2), Seq: 7},
+			},
+			nil,
+			5, 1,
+		},
+		{
+			"unsequenced",
+			[]mtproto.TLUpdatesType{
+				&mtproto.TLUpdates{Updates: status(2), Seq: 7},
+				&mtproto.TLUpdates{Updates: status(9), Seq: 0},
+			},
+			[]string{"status 9"},
+			5, 1,
+		},
+	}
+
+	for _, tt := range tests {
+		e, d, _ := newTestEngine(10)
+		e.state.Seq = 5
+		for _, o := range tt.updates {
+			e.handleUpdates(o)
+		}
+		runDelegateCalls(e)
+
+		if actual := d.delivered(); !reflect.DeepEqual(actual, tt.delivered) {
+			t.Errorf("%s: delivered %v, expected %v", tt.name, actual, tt.delivered)
+		}
+		if e.state.Seq != tt.seq {
+			t.Errorf("%s: seq == %d, expected %d", tt.name, e.state.Seq, tt.seq)
+		}
+		if len(e.pendingSeq) != tt.pending {
+			t.Errorf("%s: %d pending, expected %d", tt.name, len(e.pendingSeq), tt.pending)
+		}
+	}
+}
+
+func TestUpdatesDifference(t *testing.T) {
+	msgs := func(ids ...int) []mtproto.TLMessageType {
+		var r []mtproto.TLMessageType
+		for _, id := range ids {
+			r = append(r, &mtproto.TLMessage{ID: id})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		replies   []tl.Object
+		delivered []string
+		requested []int
+		pts       int
+	}{
+		{
+			"empty",
+			[]tl.Object{&mtproto.TLUpdatesDifferenceEmpty{Date: 100, Seq: 3}},
+			nil,
+			[]int{10},
+			10,
+		},
+		{
+			"single",
+			[]tl.Object{
+				&mtproto.TLUpdatesDifference{NewMessages: msgs(1, 2), State: &mtproto.TLUpdatesState{Pts: 12}},
+			},
+			[]string{"msg 1", "msg 2"},
+			[]int{10},
+			12,
+		},
+		{
+			"slices",
+			[]tl.Object{
+				&mtproto.TLUpdatesDifferenceSlice{NewMessages: msgs(1, 2), IntermediateState: &mtproto.TLUpdatesState{Pts: 12}},
+				&mtproto.TLUpdatesDifferenceSlice{NewMessages: msgs(3), IntermediateState: &mtproto.TLUpdatesState{Pts: 13}},
+				&mtproto.TLUpdatesDifference{NewMessages: msgs(4), State: &mtproto.TLUpdatesState{Pts: 14}},
+			},
+			[]string{"msg 1", "msg 2", "msg 3", "msg 4"},
+			[]int{10, 12, 13},
+			14,
+		},
+		{
+			"too long",
+			[]tl.Object{
+				&mtproto.TLUpdatesDifferenceTooLong{Pts: 50},
+				&mtproto.TLUpdatesDifference{NewMessages: msgs(51), State: &mtproto.TLUpdatesState{Pts: 51}},
+			},
+			[]string{"msg 51"},
+			[]int{10, 50},
+			51,
+		},
+	}
+
+	for _, tt := range tests {
+		e, d, f := newTestEngine(10, tt.replies...)
+		if err := e.sync(); err != nil {
+			t.Errorf("%s: sync failed: %v", tt.name, err)
+			continue
+		}
+		runDelegateCalls(e)
+
+		if actual := d.delivered(); !reflect.DeepEqual(actual, tt.delivered) {
+			t.Errorf("%s: delivered %v, expected %v", tt.name, actual, tt.delivered)
+		}
+		var requested []int
+		for _, o := range f.requests {
+			requested = append(requested, o.(*mtproto.TLUpdatesGetDifference).Pts)
+		}
+		if !reflect.DeepEqual(requested, tt.requested) {
+			t.Errorf("%s: requested differences from pts %v, expected %v", tt.name, requested, tt.requested)
+		}
+		if e.state.Pts != tt.pts {
+			t.Errorf("%s: pts == %d, expected %d", tt.name, e.state.Pts, tt.pts)
+		}
+	}
+}
+
+func TestUpdatesGetState(t *testing.T) {
+	e, _, f := newTestEngine(0, &mtproto.TLUpdatesState{Pts: 100, Qts: 3, Seq: 7, Date: 1000})
+	if err := e.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.requests) != 1 {
+		t.Fatalf("%d requests, expected 1", len(f.requests))
+	}
+	if _, ok := f.requests[0].(*mtproto.TLUpdatesGetState); !ok {
+		t.Errorf("requested %s, expected updates.getState", tl.Name(f.requests[0]))
+	}
+	expected := UpdatesState{Pts: 100, Qts: 3, Seq: 7, Date: 1000}
+	if e.state != expected {
+		t.Errorf("state == %+v, expected %+v", e.state, expected)
+	}
+}
+
+// A gap that does not fill up in time is recovered with getDifference, and
+// the buffered updates it covers are not delivered twice.
+func TestUpdatesGapRecovery(t *testing.T) {
+	e, d, f := newTestEngine(10,
+		&mtproto.TLUpdatesDifference{
+			NewMessages: []mtproto.TLMessageType{&mtproto.TLMessage{ID: 1}, &mtproto.TLMessage{ID: 2}},
+			State:       &mtproto.TLUpdatesState{Pts: 12},
+		},
+	)
+
+	e.handleUpdates(&mtproto.TLUpdateShort{Update: newMsg(2, 12, 1)})
+	e.handleUpdates(&mtproto.TLUpdateShort{Update: newMsg(3, 13, 1)})
+	if !e.hasPending() {
+		t.Fatalf("no pending updates after a gap")
+	}
+
+	e.expireGaps()
+	if !e.needSync {
+		t.Fatalf("expireGaps did not request a sync")
+	}
+	if err := e.sync(); err != nil {
+		t.Fatal(err)
+	}
+	e.replayPending()
+	runDelegateCalls(e)
+
+	expected := []string{"msg 1", "msg 2", "msg 3"}
+	if actual := d.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("delivered %v, expected %v", actual, expected)
+	}
+	if e.state.Pts != 13 || e.hasPending() {
+		t.Errorf("pts == %d, pending %v, expected 13 with nothing pending", e.state.Pts, e.hasPending())
+	}
+	if len(f.requests) != 1 {
+		t.Errorf("%d requests, expected 1", len(f.requests))
+	}
+}